@@ -0,0 +1,211 @@
+//go:build rlnc_cgo
+
+package rlnc
+
+//go:generate sh -c "cargo build --release && cp ../target/release/librlnc_poc.a rust-lib/release/librlnc_poc.a"
+
+/*
+#cgo LDFLAGS: ${SRCDIR}/rust-lib/release/librlnc_poc.a -lm -ldl -lpthread
+#cgo darwin LDFLAGS: -framework Security -framework CoreFoundation
+
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdint.h>
+
+uint32_t get_version(void);
+void *gen_committer(uint32_t chunk_size_in_scalars);
+void serialize_committer(void *committer, uint8_t **out_ptr, size_t *out_len);
+void *deserialize_committer(uint8_t *serialized, size_t serialized_len);
+void free_committer(void *committer);
+uint32_t committer_len(void *committer);
+void *new_node(void *committer, uint32_t num_chunks);
+void *new_source_node(void *committer, uint8_t *block, size_t block_len, uint32_t num_chunks);
+int32_t commit_block(void *committer, uint8_t *block, size_t block_len, uint32_t num_chunks, uint8_t **out_ptr, size_t *out_len);
+void *new_source_node_with_commitments(void *committer, uint8_t *block, size_t block_len, uint32_t num_chunks, uint8_t *commitments, size_t commitments_len);
+int32_t send_chunk_for_needs(void *node, uint8_t *pivots, size_t pivots_len, uint8_t **out_data, size_t *out_len);
+int32_t pivot_columns(void *node, uint8_t **out_data, size_t *out_len);
+int32_t last_error(uint8_t **out_ptr, size_t *out_len);
+int32_t expect_commitments(void *node, uint8_t *commitments, size_t commitments_len);
+void *clone_node(void *node);
+void free_node(void *node);
+int32_t serialize_node(void *node, uint8_t **out_ptr, size_t *out_len);
+void *deserialize_node(void *committer, uint32_t num_chunks, uint8_t *serialized, size_t serialized_len);
+int32_t send_chunk(void *node, uint8_t **out_data, size_t *out_len);
+int32_t send_chunks(void *node, uint32_t count, uint8_t **out_data, size_t *out_len);
+int32_t send_systematic_chunk(void *node, uint32_t index, uint8_t **out_data, size_t *out_len);
+void set_seed(void *node, uint64_t seed);
+void reset_node(void *node);
+int32_t receive_chunk(void *node, uint8_t *chunk, size_t chunk_len);
+int32_t receive_chunks(void *node, uint8_t *chunks, uint64_t *lens, size_t count, int32_t *codes);
+int32_t receive_source_chunk(void *node, uint32_t index, uint8_t *data, size_t data_len, uint8_t *commitments, size_t commitments_len);
+int32_t verify_chunk(void *committer, uint8_t *chunk, size_t chunk_len);
+int32_t decode(void *node, uint8_t **out_data, size_t *out_len);
+int32_t decode_verified(void *node, uint8_t **out_data, size_t *out_len);
+int32_t decoded_chunks(void *node, uint8_t **out_data, size_t *out_len);
+void free_buffer(uint8_t *ptr, size_t len);
+int32_t is_full(void *node);
+uint32_t rank(void *node);
+uint64_t memory_usage(void *node);
+uint64_t live_handles(void);
+int32_t coefficients(void *node, uint8_t **out_data, size_t *out_len);
+int32_t node_commitments_hash(void *node, uint8_t **out_ptr, size_t *out_len);
+int32_t commitments_hash(uint8_t *message_data, size_t message_len, uint8_t **out_ptr, size_t *out_len);
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// FlavorStatic is the library linked into the binary by the rlnc_cgo backend.
+const FlavorStatic BuildFlavor = "static"
+
+// allocFreeCalls is set as cgo calls allocate nothing, so neither do
+// DataInto and ChunkToSendInto.
+const allocFreeCalls = true
+
+func cBytes(b []byte) *C.uint8_t {
+	return (*C.uint8_t)(unsafe.Pointer(unsafe.SliceData(b)))
+}
+
+func cOutPtr(p *unsafe.Pointer) **C.uint8_t {
+	return (**C.uint8_t)(unsafe.Pointer(p))
+}
+
+func cOutLen(l *uint64) *C.size_t {
+	return (*C.size_t)(unsafe.Pointer(l))
+}
+
+// load fills r's function table with calls into the statically linked
+// library, so nothing is extracted or dlopened at runtime.
+func (r *RLNC) load(o options) error {
+	if o.libPath != "" {
+		return errors.New("WithLibraryPath is not supported by the rlnc_cgo backend")
+	}
+
+	r.flavor = FlavorStatic
+	r.getVersion = func() uint32 {
+		return uint32(C.get_version())
+	}
+	r.genCommitter = func(chunkSizeInScalars uint32) unsafe.Pointer {
+		return C.gen_committer(C.uint32_t(chunkSizeInScalars))
+	}
+	r.serializeCommitter = func(commiter unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) {
+		C.serialize_committer(commiter, cOutPtr(outPtr), cOutLen(outLen))
+	}
+	r.deserializeCommitter = func(serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		return C.deserialize_committer((*C.uint8_t)(serializedPtr), C.size_t(serializedLen))
+	}
+	r.freeCommitter = func(commiter unsafe.Pointer) {
+		C.free_committer(commiter)
+	}
+	r.committerLen = func(commiter unsafe.Pointer) uint32 {
+		return uint32(C.committer_len(commiter))
+	}
+	r.newNode = func(commiter unsafe.Pointer, numChunks uint32) unsafe.Pointer {
+		return C.new_node(commiter, C.uint32_t(numChunks))
+	}
+	r.newSourceNode = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32) unsafe.Pointer {
+		return C.new_source_node(commiter, cBytes(block), C.size_t(blockLen), C.uint32_t(numChunks))
+	}
+	r.commitBlock = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		return int32(C.commit_block(commiter, cBytes(block), C.size_t(blockLen), C.uint32_t(numChunks), cOutPtr(outPtr), cOutLen(outLen)))
+	}
+	r.newSourceNodeWith = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, commitments []byte, commitmentsLen uint64) unsafe.Pointer {
+		return C.new_source_node_with_commitments(commiter, cBytes(block), C.size_t(blockLen), C.uint32_t(numChunks), cBytes(commitments), C.size_t(commitmentsLen))
+	}
+	r.cloneNode = func(node unsafe.Pointer) unsafe.Pointer {
+		return C.clone_node(node)
+	}
+	r.freeNode = func(node unsafe.Pointer) {
+		C.free_node(node)
+	}
+	r.serializeNode = func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		return int32(C.serialize_node(node, cOutPtr(outPtr), cOutLen(outLen)))
+	}
+	r.deserializeNode = func(commiter unsafe.Pointer, numChunks uint32, serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		return C.deserialize_node(commiter, C.uint32_t(numChunks), (*C.uint8_t)(serializedPtr), C.size_t(serializedLen))
+	}
+	r.sendChunk = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.send_chunk(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.sendChunks = func(node unsafe.Pointer, count uint32, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.send_chunks(node, C.uint32_t(count), cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.sendSystematicChunk = func(node unsafe.Pointer, index uint32, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.send_systematic_chunk(node, C.uint32_t(index), cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.sendChunkForNeeds = func(node unsafe.Pointer, pivots []byte, pivotsLen uint64, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.send_chunk_for_needs(node, cBytes(pivots), C.size_t(pivotsLen), cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.pivotColumns = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.pivot_columns(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.setSeed = func(node unsafe.Pointer, seed uint64) {
+		C.set_seed(node, C.uint64_t(seed))
+	}
+	r.resetNode = func(node unsafe.Pointer) {
+		C.reset_node(node)
+	}
+	r.receiveChunk = func(node unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		return int32(C.receive_chunk(node, cBytes(chunk), C.size_t(chunkLen)))
+	}
+	r.receiveChunks = func(node unsafe.Pointer, chunks []byte, lens []uint64, count uint64, codes []int32) int32 {
+		return int32(C.receive_chunks(node, cBytes(chunks), (*C.uint64_t)(unsafe.SliceData(lens)), C.size_t(count), (*C.int32_t)(unsafe.SliceData(codes))))
+	}
+	r.receiveSourceChunk = func(node unsafe.Pointer, index uint32, data []byte, dataLen uint64, commitments []byte, commitmentsLen uint64) int32 {
+		return int32(C.receive_source_chunk(node, C.uint32_t(index), cBytes(data), C.size_t(dataLen), cBytes(commitments), C.size_t(commitmentsLen)))
+	}
+	r.lastError = func(outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		return int32(C.last_error(cOutPtr(outPtr), cOutLen(outLen)))
+	}
+	r.expectCommitments = func(node unsafe.Pointer, commitments []byte, commitmentsLen uint64) int32 {
+		return int32(C.expect_commitments(node, cBytes(commitments), C.size_t(commitmentsLen)))
+	}
+	r.verifyChunk = func(commiter unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		return int32(C.verify_chunk(commiter, cBytes(chunk), C.size_t(chunkLen)))
+	}
+	r.decode = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.decode(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.decodeVerified = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.decode_verified(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.decodedChunks = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.decoded_chunks(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.freeBuffer = func(buffer unsafe.Pointer, len uint64) {
+		C.free_buffer((*C.uint8_t)(buffer), C.size_t(len))
+	}
+	r.isFull = func(node unsafe.Pointer) bool {
+		return C.is_full(node) != 0
+	}
+	r.rank = func(node unsafe.Pointer) uint32 {
+		return uint32(C.rank(node))
+	}
+	r.memoryUsage = func(node unsafe.Pointer) uint64 {
+		return uint64(C.memory_usage(node))
+	}
+	r.liveHandles = func() uint64 {
+		return uint64(C.live_handles())
+	}
+	r.coefficients = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(C.coefficients(node, cOutPtr(outData), cOutLen(outDataLen)))
+	}
+	r.nodeCommitmentsHash = func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		return int32(C.node_commitments_hash(node, cOutPtr(outPtr), cOutLen(outLen)))
+	}
+	r.commitmentsHash = func(messageData unsafe.Pointer, messageLen uint64, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		return int32(C.commitments_hash((*C.uint8_t)(messageData), C.size_t(messageLen), cOutPtr(outPtr), cOutLen(outLen)))
+	}
+	r.pinBuffers()
+	return nil
+}
+
+// unloadLib is a no-op since the library is linked into the binary.
+func (r *RLNC) unloadLib() {}
+
+func cleanupLibs() error {
+	return nil
+}
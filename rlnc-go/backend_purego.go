@@ -0,0 +1,129 @@
+//go:build !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ebitengine/purego"
+)
+
+// libPathEnv names a library to load instead of the embedded one, so a fresh
+// build of the Rust side can be tested without regenerating the embed.
+// WithLibraryPath takes precedence over it.
+const libPathEnv = "RLNC_LIB_PATH"
+
+// load extracts the library if needed, dlopens it and registers its functions
+// on r.
+func (r *RLNC) load(o options) error {
+	libPath, hint := o.libPath, ""
+	if libPath == "" {
+		if libPath = os.Getenv(libPathEnv); libPath != "" {
+			// Never fall back to the embedded library if this fails, it
+			// would hide that stale code is being tested.
+			hint = " (from " + libPathEnv + ")"
+		}
+	}
+
+	flavor := FlavorRelease
+	if o.debug {
+		flavor = FlavorDebug
+	}
+	if libPath != "" || !libEmbedded {
+		flavor = FlavorExternal
+	}
+
+	if libPath == "" {
+		if err := checkEmbeddedLib(flavor); err != nil {
+			return err
+		}
+		var err error
+		libPath, err = getLibPath(flavor)
+		if err != nil {
+			return err
+		}
+		hint = loadErrHint(libPath)
+	} else if _, err := os.Stat(libPath); err != nil {
+		return fmt.Errorf("failed to find library%s: %w", hint, err)
+	}
+
+	lib, err := openLib(libPath, o.dlopenFlags)
+	if err != nil {
+		releaseLibPath(libPath)
+		return fmt.Errorf("failed to load library at %s%s: %w", libPath, hint, err)
+	}
+
+	var missing []string
+	for _, sym := range []struct {
+		fptr any
+		name string
+	}{
+		{&r.getVersion, "get_version"},
+		{&r.genCommitter, "gen_committer"},
+		{&r.serializeCommitter, "serialize_committer"},
+		{&r.deserializeCommitter, "deserialize_committer"},
+		{&r.freeCommitter, "free_committer"},
+		{&r.committerLen, "committer_len"},
+		{&r.newNode, "new_node"},
+		{&r.newSourceNode, "new_source_node"},
+		{&r.commitBlock, "commit_block"},
+		{&r.newSourceNodeWith, "new_source_node_with_commitments"},
+		{&r.expectCommitments, "expect_commitments"},
+		{&r.lastError, "last_error"},
+		{&r.sendChunkForNeeds, "send_chunk_for_needs"},
+		{&r.pivotColumns, "pivot_columns"},
+		{&r.cloneNode, "clone_node"},
+		{&r.freeNode, "free_node"},
+		{&r.serializeNode, "serialize_node"},
+		{&r.deserializeNode, "deserialize_node"},
+		{&r.sendChunk, "send_chunk"},
+		{&r.sendChunks, "send_chunks"},
+		{&r.sendSystematicChunk, "send_systematic_chunk"},
+		{&r.setSeed, "set_seed"},
+		{&r.resetNode, "reset_node"},
+		{&r.receiveChunk, "receive_chunk"},
+		{&r.receiveChunks, "receive_chunks"},
+		{&r.receiveSourceChunk, "receive_source_chunk"},
+		{&r.verifyChunk, "verify_chunk"},
+		{&r.decode, "decode"},
+		{&r.decodeVerified, "decode_verified"},
+		{&r.decodedChunks, "decoded_chunks"},
+		{&r.freeBuffer, "free_buffer"},
+		{&r.isFull, "is_full"},
+		{&r.rank, "rank"},
+		{&r.memoryUsage, "memory_usage"},
+		{&r.liveHandles, "live_handles"},
+		{&r.coefficients, "coefficients"},
+		{&r.nodeCommitmentsHash, "node_commitments_hash"},
+		{&r.commitmentsHash, "commitments_hash"},
+	} {
+		addr, err := libSymbol(lib, sym.name)
+		if err != nil {
+			missing = append(missing, sym.name)
+			continue
+		}
+		purego.RegisterFunc(sym.fptr, addr)
+	}
+	if len(missing) > 0 {
+		closeLib(lib)
+		releaseLibPath(libPath)
+		return &MissingSymbolsError{Path: libPath, Symbols: missing}
+	}
+	if err := r.bindDirect(lib); err != nil {
+		closeLib(lib)
+		releaseLibPath(libPath)
+		return fmt.Errorf("failed to load library at %s%s: %w", libPath, hint, err)
+	}
+
+	r.pinBuffers()
+	r.lib = lib
+	r.libPath = libPath
+	r.flavor = flavor
+	return nil
+}
+
+func (r *RLNC) unloadLib() {
+	closeLib(r.lib)
+	releaseLibPath(r.libPath)
+}
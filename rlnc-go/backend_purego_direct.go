@@ -0,0 +1,92 @@
+//go:build !rlnc_cgo && !rlnc_wasm && (darwin || freebsd || linux) && (amd64 || arm64)
+
+package rlnc
+
+import (
+	"sync"
+	"unsafe"
+
+	_ "github.com/ebitengine/purego"
+)
+
+// allocFreeCalls reports whether DataInto and ChunkToSendInto call into the
+// library without allocating, which the direct calls below make possible.
+const allocFreeCalls = true
+
+// directArgs has the layout of purego's syscall15Args, the block its
+// trampoline reads a C function and its arguments from and writes the
+// results back to.
+type directArgs struct {
+	fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15 uintptr
+	f1, f2, f3, f4, f5, f6, f7, f8                                       uintptr
+	arm64R8                                                              uintptr
+}
+
+// directArgsPool holds the argument blocks of direct calls. The trampoline
+// gets a pointer to the block, so it escapes, and a block on the stack
+// would be allocated on every call.
+var directArgsPool = sync.Pool{New: func() any { return new(directArgs) }}
+
+//go:linkname runtimeCgocall runtime.cgocall
+func runtimeCgocall(fn uintptr, arg unsafe.Pointer) int32
+
+//go:linkname syscall15XABI0 github.com/ebitengine/purego.syscall15XABI0
+var syscall15XABI0 uintptr
+
+// directCall calls the C function at fn with up to three integer or pointer
+// arguments and returns its result, the way purego's registered functions do
+// but without reflection, so it allocates nothing.
+func directCall(fn, a1, a2, a3 uintptr) uintptr {
+	args := directArgsPool.Get().(*directArgs)
+	*args = directArgs{fn: fn, a1: a1, a2: a2, a3: a3}
+	runtimeCgocall(syscall15XABI0, unsafe.Pointer(args))
+	res := args.a1
+	directArgsPool.Put(args)
+	return res
+}
+
+// bindDirect replaces the functions of r's table on the DataInto and
+// ChunkToSendInto paths with direct calls, which purego's RegisterFunc
+// can't make without allocating. The out pointers the library writes
+// through point into outBuffers, which the callers keep alive.
+func (r *RLNC) bindDirect(lib uintptr) error {
+	var rank, isFull, sendChunk, decode, decodeVerified, freeBuffer uintptr
+	for _, sym := range []struct {
+		addr *uintptr
+		name string
+	}{
+		{&rank, "rank"},
+		{&isFull, "is_full"},
+		{&sendChunk, "send_chunk"},
+		{&decode, "decode"},
+		{&decodeVerified, "decode_verified"},
+		{&freeBuffer, "free_buffer"},
+	} {
+		addr, err := libSymbol(lib, sym.name)
+		if err != nil {
+			return err
+		}
+		*sym.addr = addr
+	}
+	r.rank = func(node unsafe.Pointer) uint32 {
+		return uint32(directCall(rank, uintptr(node), 0, 0))
+	}
+	r.isFull = func(node unsafe.Pointer) bool {
+		return uint8(directCall(isFull, uintptr(node), 0, 0)) != 0
+	}
+	r.sendChunk = outCall(sendChunk)
+	r.decode = outCall(decode)
+	r.decodeVerified = outCall(decodeVerified)
+	r.freeBuffer = func(buffer unsafe.Pointer, len uint64) {
+		directCall(freeBuffer, uintptr(buffer), uintptr(len), 0)
+	}
+	return nil
+}
+
+// outCall returns a direct call to a library function that takes a node and
+// returns a buffer through two out pointers.
+func outCall(fn uintptr) func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+	return func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		return int32(directCall(fn, uintptr(node), uintptr(unsafe.Pointer(outData)), uintptr(unsafe.Pointer(outDataLen))))
+	}
+}
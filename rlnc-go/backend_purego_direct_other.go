@@ -0,0 +1,12 @@
+//go:build !rlnc_cgo && !rlnc_wasm && !((darwin || freebsd || linux) && (amd64 || arm64))
+
+package rlnc
+
+// allocFreeCalls is unset, as RegisterFunc allocates on every call.
+const allocFreeCalls = false
+
+// bindDirect leaves r's table to purego's RegisterFunc, as there is no
+// direct call on this platform.
+func (r *RLNC) bindDirect(lib uintptr) error {
+	return nil
+}
@@ -0,0 +1,118 @@
+//go:build !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithLibraryPath(t *testing.T) {
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	if !filepath.IsAbs(libPath) {
+		t.Skip("library is resolved by the system loader")
+	}
+
+	rlnc, err := NewRLNCWithOptions(WithLibraryPath(libPath))
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	committer, err := rlnc.GenCommitterForMessage(31*512*8, 8)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	committer.Close()
+}
+
+func TestWithLibraryPathMissing(t *testing.T) {
+	_, err := NewRLNCWithOptions(WithLibraryPath(filepath.Join(t.TempDir(), "does-not-exist")))
+	if err == nil {
+		t.Fatalf("Expected error for missing library")
+	}
+}
+
+func TestBuildFlavor(t *testing.T) {
+	want := FlavorRelease
+	if !libEmbedded {
+		want = FlavorExternal
+	}
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+	if rlnc.BuildFlavor() != want {
+		t.Fatalf("Expected %s flavor, got %s", want, rlnc.BuildFlavor())
+	}
+
+	if libEmbedded {
+		want = FlavorDebug
+	}
+	debug, err := NewRLNCWithOptions(WithDebugLibrary())
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer debug.Close()
+	if debug.BuildFlavor() != want {
+		t.Fatalf("Expected %s flavor, got %s", want, debug.BuildFlavor())
+	}
+}
+
+func TestMissingSymbols(t *testing.T) {
+	var libPath string
+	for _, pattern := range []string{"/lib/*/libm.so.6", "/usr/lib/*/libm.so.6", "/lib64/libm.so.6", "/usr/lib/libm.so.6"} {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			libPath = matches[0]
+			break
+		}
+	}
+	if libPath == "" {
+		t.Skip("no library without the rlnc exports found")
+	}
+
+	_, err := NewRLNCWithOptions(WithLibraryPath(libPath))
+	var missing *MissingSymbolsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected MissingSymbolsError, got %v", err)
+	}
+	if !slices.Contains(missing.Symbols, "gen_committer") || !slices.Contains(missing.Symbols, "commitments_hash") {
+		t.Fatalf("Expected every symbol to be reported, got %v", missing.Symbols)
+	}
+}
+
+func TestLibPathEnv(t *testing.T) {
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	if !filepath.IsAbs(libPath) {
+		t.Skip("library is resolved by the system loader")
+	}
+	t.Setenv(libPathEnv, libPath)
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+	if rlnc.BuildFlavor() != FlavorExternal {
+		t.Fatalf("Expected %s flavor, got %s", FlavorExternal, rlnc.BuildFlavor())
+	}
+}
+
+func TestLibPathEnvMissing(t *testing.T) {
+	t.Setenv(libPathEnv, filepath.Join(t.TempDir(), "does-not-exist"))
+
+	_, err := NewRLNC()
+	if err == nil || !strings.Contains(err.Error(), libPathEnv) {
+		t.Fatalf("Expected an error naming %s, got %v", libPathEnv, err)
+	}
+}
@@ -0,0 +1,441 @@
+//go:build rlnc_wasm
+
+package rlnc
+
+//go:generate sh -c "cargo build --release --target wasm32-wasip1 && cp ../target/wasm32-wasip1/release/rlnc_poc.wasm rust-lib/release/rlnc_poc.wasm"
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+//go:embed rust-lib/release/rlnc_poc.wasm
+var wasmLib []byte
+
+// FlavorWASM is the wasm32 build of the library run by the rlnc_wasm backend.
+const FlavorWASM BuildFlavor = "wasm"
+
+// wasmInstance is one instantiation of the library. Every call goes through
+// the same linear memory, so calls are serialized.
+type wasmInstance struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	runtime wazero.Runtime
+	mod     api.Module
+	fns     map[string]api.Function
+}
+
+// wasmHandle boxes a pointer into the module's memory so it can travel
+// through the function table as an unsafe.Pointer.
+type wasmHandle struct {
+	off uint32
+}
+
+func toHandle(off uint32) unsafe.Pointer {
+	if off == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&wasmHandle{off: off})
+}
+
+func fromHandle(p unsafe.Pointer) uint32 {
+	if p == nil {
+		return 0
+	}
+	return (*wasmHandle)(p).off
+}
+
+// call must be made with w.mu held. Traps are the equivalent of a Rust panic
+// aborting the process in the native backends, so they panic too.
+func (w *wasmInstance) call(name string, params ...uint64) uint64 {
+	res, err := w.fns[name].Call(w.ctx, params...)
+	if err != nil {
+		panic(fmt.Errorf("rlnc: %s trapped: %w", name, err))
+	}
+	if len(res) == 0 {
+		return 0
+	}
+	return res[0]
+}
+
+// copyIn must be made with w.mu held. The buffer must be released with
+// free_buffer.
+func (w *wasmInstance) copyIn(b []byte) uint32 {
+	// Lengths are 32 bits on wasm32, so a larger one would be truncated.
+	if uint64(len(b)) > math.MaxUint32 {
+		panic(fmt.Sprintf("rlnc: %d byte buffer doesn't fit in wasm32 memory", len(b)))
+	}
+	off := uint32(w.call("alloc_buffer", uint64(len(b))))
+	if !w.mod.Memory().Write(off, b) {
+		panic("rlnc: alloc_buffer returned a buffer outside of memory")
+	}
+	return off
+}
+
+func (w *wasmInstance) free(off uint32, n int) {
+	w.call("free_buffer", uint64(off), uint64(n))
+}
+
+// callOut runs a function that returns a buffer through a pointer and length
+// out-parameter pair, passed after params. The buffer is copied into Go
+// memory and freed in the module, so freeBuffer has nothing left to do.
+func (w *wasmInstance) callOut(name string, outData *unsafe.Pointer, outLen *uint64, params ...uint64) uint64 {
+	// Pointers and usize are both 4 bytes on wasm32.
+	outs := w.copyIn(make([]byte, 8))
+	defer w.free(outs, 8)
+
+	res := w.call(name, append(params, uint64(outs), uint64(outs+4))...)
+
+	mem := w.mod.Memory()
+	ptr, _ := mem.ReadUint32Le(outs)
+	n, _ := mem.ReadUint32Le(outs + 4)
+	if ptr == 0 {
+		return res
+	}
+	b, ok := mem.Read(ptr, n)
+	if !ok {
+		panic(fmt.Sprintf("rlnc: %s returned a buffer outside of memory", name))
+	}
+	out := make([]byte, n)
+	copy(out, b)
+	w.free(ptr, int(n))
+	*outData = unsafe.Pointer(unsafe.SliceData(out))
+	*outLen = uint64(n)
+	return res
+}
+
+// allocFreeCalls is unset, as buffers are copied out of the module's memory.
+const allocFreeCalls = false
+
+// load instantiates the wasm build of the library and fills r's function
+// table with calls into it.
+func (r *RLNC) load(o options) error {
+	code, path := wasmLib, "embedded rlnc_poc.wasm"
+	r.flavor = FlavorWASM
+	if o.libPath != "" {
+		var err error
+		if code, err = os.ReadFile(o.libPath); err != nil {
+			return fmt.Errorf("failed to read library: %w", err)
+		}
+		path = o.libPath
+		r.flavor = FlavorExternal
+	}
+	if len(code) == 0 {
+		return errors.New("embedded wasm library is empty; run go generate to build it")
+	}
+
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	mod, err := rt.InstantiateWithConfig(ctx, code, wazero.NewModuleConfig().
+		WithRandSource(rand.Reader).
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		rt.Close(ctx)
+		return fmt.Errorf("failed to instantiate library at %s: %w", path, err)
+	}
+
+	w := &wasmInstance{ctx: ctx, runtime: rt, mod: mod, fns: map[string]api.Function{}}
+	var missing []string
+	for _, name := range []string{
+		"get_version",
+		"gen_committer",
+		"serialize_committer",
+		"deserialize_committer",
+		"free_committer",
+		"committer_len",
+		"new_node",
+		"new_source_node",
+		"commit_block",
+		"new_source_node_with_commitments",
+		"expect_commitments",
+		"last_error",
+		"send_chunk_for_needs",
+		"pivot_columns",
+		"clone_node",
+		"free_node",
+		"serialize_node",
+		"deserialize_node",
+		"send_chunk",
+		"send_chunks",
+		"send_systematic_chunk",
+		"set_seed",
+		"reset_node",
+		"receive_chunk",
+		"receive_chunks",
+		"receive_source_chunk",
+		"verify_chunk",
+		"decode",
+		"decode_verified",
+		"decoded_chunks",
+		"alloc_buffer",
+		"free_buffer",
+		"is_full",
+		"rank",
+		"memory_usage",
+		"live_handles",
+		"coefficients",
+		"node_commitments_hash",
+		"commitments_hash",
+	} {
+		fn := mod.ExportedFunction(name)
+		if fn == nil {
+			missing = append(missing, name)
+			continue
+		}
+		w.fns[name] = fn
+	}
+	if len(missing) > 0 {
+		rt.Close(ctx)
+		return &MissingSymbolsError{Path: path, Symbols: missing}
+	}
+	r.wasm = w
+	r.libPath = path
+
+	r.getVersion = func() uint32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return uint32(w.call("get_version"))
+	}
+	r.genCommitter = func(chunkSizeInScalars uint32) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return toHandle(uint32(w.call("gen_committer", uint64(chunkSizeInScalars))))
+	}
+	r.serializeCommitter = func(commiter unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.callOut("serialize_committer", outPtr, outLen, uint64(fromHandle(commiter)))
+	}
+	r.deserializeCommitter = func(serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(unsafe.Slice((*byte)(serializedPtr), serializedLen))
+		defer w.free(in, int(serializedLen))
+		return toHandle(uint32(w.call("deserialize_committer", uint64(in), serializedLen)))
+	}
+	r.freeCommitter = func(commiter unsafe.Pointer) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.call("free_committer", uint64(fromHandle(commiter)))
+	}
+	r.committerLen = func(commiter unsafe.Pointer) uint32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return uint32(w.call("committer_len", uint64(fromHandle(commiter))))
+	}
+	r.newNode = func(commiter unsafe.Pointer, numChunks uint32) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return toHandle(uint32(w.call("new_node", uint64(fromHandle(commiter)), uint64(numChunks))))
+	}
+	r.newSourceNode = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(block[:blockLen])
+		defer w.free(in, int(blockLen))
+		return toHandle(uint32(w.call("new_source_node", uint64(fromHandle(commiter)), uint64(in), blockLen, uint64(numChunks))))
+	}
+	r.commitBlock = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(block[:blockLen])
+		defer w.free(in, int(blockLen))
+		return int32(w.callOut("commit_block", outPtr, outLen, uint64(fromHandle(commiter)), uint64(in), blockLen, uint64(numChunks)))
+	}
+	r.newSourceNodeWith = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, commitments []byte, commitmentsLen uint64) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(block[:blockLen])
+		defer w.free(in, int(blockLen))
+		commitmentsIn := w.copyIn(commitments[:commitmentsLen])
+		defer w.free(commitmentsIn, int(commitmentsLen))
+		return toHandle(uint32(w.call("new_source_node_with_commitments", uint64(fromHandle(commiter)), uint64(in), blockLen, uint64(numChunks), uint64(commitmentsIn), commitmentsLen)))
+	}
+	r.cloneNode = func(node unsafe.Pointer) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return toHandle(uint32(w.call("clone_node", uint64(fromHandle(node)))))
+	}
+	r.freeNode = func(node unsafe.Pointer) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.call("free_node", uint64(fromHandle(node)))
+	}
+	r.serializeNode = func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("serialize_node", outPtr, outLen, uint64(fromHandle(node))))
+	}
+	r.deserializeNode = func(commiter unsafe.Pointer, numChunks uint32, serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(unsafe.Slice((*byte)(serializedPtr), serializedLen))
+		defer w.free(in, int(serializedLen))
+		return toHandle(uint32(w.call("deserialize_node", uint64(fromHandle(commiter)), uint64(numChunks), uint64(in), serializedLen)))
+	}
+	r.sendChunk = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("send_chunk", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	r.sendChunks = func(node unsafe.Pointer, count uint32, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("send_chunks", outData, outDataLen, uint64(fromHandle(node)), uint64(count)))
+	}
+	r.sendSystematicChunk = func(node unsafe.Pointer, index uint32, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("send_systematic_chunk", outData, outDataLen, uint64(fromHandle(node)), uint64(index)))
+	}
+	r.sendChunkForNeeds = func(node unsafe.Pointer, pivots []byte, pivotsLen uint64, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(pivots[:pivotsLen])
+		defer w.free(in, int(pivotsLen))
+		return int32(w.callOut("send_chunk_for_needs", outData, outDataLen, uint64(fromHandle(node)), uint64(in), pivotsLen))
+	}
+	r.pivotColumns = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("pivot_columns", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	r.setSeed = func(node unsafe.Pointer, seed uint64) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.call("set_seed", uint64(fromHandle(node)), seed)
+	}
+	r.resetNode = func(node unsafe.Pointer) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.call("reset_node", uint64(fromHandle(node)))
+	}
+	r.receiveChunk = func(node unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(chunk[:chunkLen])
+		defer w.free(in, int(chunkLen))
+		return int32(w.call("receive_chunk", uint64(fromHandle(node)), uint64(in), chunkLen))
+	}
+	r.receiveChunks = func(node unsafe.Pointer, chunks []byte, lens []uint64, count uint64, codes []int32) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(chunks)
+		defer w.free(in, len(chunks))
+		lensIn := w.copyIn(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(lens))), 8*len(lens)))
+		defer w.free(lensIn, 8*len(lens))
+		codesOut := w.copyIn(make([]byte, 4*len(codes)))
+		defer w.free(codesOut, 4*len(codes))
+		res := int32(w.call("receive_chunks", uint64(fromHandle(node)), uint64(in), uint64(lensIn), count, uint64(codesOut)))
+		for i := range codes {
+			code, _ := w.mod.Memory().ReadUint32Le(codesOut + uint32(4*i))
+			codes[i] = int32(code)
+		}
+		return res
+	}
+	r.receiveSourceChunk = func(node unsafe.Pointer, index uint32, data []byte, dataLen uint64, commitments []byte, commitmentsLen uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(data[:dataLen])
+		defer w.free(in, int(dataLen))
+		commitmentsIn := w.copyIn(commitments[:commitmentsLen])
+		defer w.free(commitmentsIn, int(commitmentsLen))
+		return int32(w.call("receive_source_chunk", uint64(fromHandle(node)), uint64(index), uint64(in), dataLen, uint64(commitmentsIn), commitmentsLen))
+	}
+	r.lastError = func(outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("last_error", outPtr, outLen))
+	}
+	r.expectCommitments = func(node unsafe.Pointer, commitments []byte, commitmentsLen uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(commitments[:commitmentsLen])
+		defer w.free(in, int(commitmentsLen))
+		return int32(w.call("expect_commitments", uint64(fromHandle(node)), uint64(in), commitmentsLen))
+	}
+	r.verifyChunk = func(commiter unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(chunk[:chunkLen])
+		defer w.free(in, int(chunkLen))
+		return int32(w.call("verify_chunk", uint64(fromHandle(commiter)), uint64(in), chunkLen))
+	}
+	r.decode = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("decode", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	r.decodeVerified = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("decode_verified", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	r.decodedChunks = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("decoded_chunks", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	// Buffers returned by the module are copied into Go memory and freed
+	// there right away.
+	r.freeBuffer = func(buffer unsafe.Pointer, len uint64) {}
+	r.isFull = func(node unsafe.Pointer) bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.call("is_full", uint64(fromHandle(node)))) != 0
+	}
+	r.rank = func(node unsafe.Pointer) uint32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return uint32(w.call("rank", uint64(fromHandle(node))))
+	}
+	r.memoryUsage = func(node unsafe.Pointer) uint64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.call("memory_usage", uint64(fromHandle(node)))
+	}
+	r.liveHandles = func() uint64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.call("live_handles")
+	}
+	r.coefficients = func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("coefficients", outData, outDataLen, uint64(fromHandle(node))))
+	}
+	r.nodeCommitmentsHash = func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return int32(w.callOut("node_commitments_hash", outPtr, outLen, uint64(fromHandle(node))))
+	}
+	r.commitmentsHash = func(messageData unsafe.Pointer, messageLen uint64, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		in := w.copyIn(unsafe.Slice((*byte)(messageData), messageLen))
+		defer w.free(in, int(messageLen))
+		return int32(w.callOut("commitments_hash", outPtr, outLen, uint64(in), messageLen))
+	}
+	return nil
+}
+
+// unloadLib closes the wazero runtime, releasing the module's memory.
+func (r *RLNC) unloadLib() {
+	r.wasm.runtime.Close(r.wasm.ctx)
+}
+
+func cleanupLibs() error {
+	return nil
+}
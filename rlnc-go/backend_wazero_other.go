@@ -0,0 +1,6 @@
+//go:build !rlnc_wasm
+
+package rlnc
+
+// wasmInstance is only used by the rlnc_wasm backend.
+type wasmInstance struct{}
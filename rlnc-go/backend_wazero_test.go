@@ -0,0 +1,16 @@
+//go:build rlnc_wasm
+
+package rlnc
+
+import "testing"
+
+func TestWASMLibrary(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+	if got := rlnc.BuildFlavor(); got != FlavorWASM {
+		t.Fatalf("Expected the %s flavor, got %s", FlavorWASM, got)
+	}
+}
@@ -0,0 +1,103 @@
+package rlnc
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Block bundles a block's data, already split and committed to, with the hash
+// of its commitments, so the two can't get mixed up across blocks. It pads
+// the data like NewSourceNodePadded, so any size fits, and its chunks decode
+// with a Decoder or a node from NewNodePadded.
+type Block struct {
+	node *Node
+	hash []byte
+	id   BlockID
+	size int
+}
+
+// BlockRef describes a block to a receiver: the hash of its commitments and
+// how it is split, so its chunks can be checked before they are decoded.
+type BlockRef struct {
+	Hash      []byte
+	NumChunks int
+	// ChunkSize is the size of a chunk once the data is padded, in bytes.
+	ChunkSize int
+}
+
+// chunkLen returns the length of every chunk of the block.
+func (ref BlockRef) chunkLen() int {
+	return chunkWireSize(ref.ChunkSize, ref.NumChunks)
+}
+
+// NewBlock splits data into numChunks chunks and commits to them. The
+// Committer must support PaddedSize(len(data), numChunks)/numChunks byte
+// chunks, and outlive the Block.
+func (c *Committer) NewBlock(data []byte, numChunks int) (*Block, error) {
+	node, err := c.NewSourceNodePadded(data, numChunks)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := node.CommitmentsHash()
+	if err != nil {
+		node.Close()
+		return nil, err
+	}
+	b := &Block{node: node, hash: hash, size: len(data)}
+	copy(b.id[:], hash)
+	node.SetBlockID(b.id)
+	return b, nil
+}
+
+// ID returns the block's ID, the first bytes of its commitments hash.
+// WrappedChunk wraps chunks with it.
+func (b *Block) ID() BlockID {
+	return b.id
+}
+
+// Hash returns the hash of the commitments every chunk of the block carries.
+func (b *Block) Hash() []byte {
+	return bytes.Clone(b.hash)
+}
+
+// Len returns the size of the block's data, without padding.
+func (b *Block) Len() int {
+	return b.size
+}
+
+// Ref returns the BlockRef receivers need to check the block's chunks.
+func (b *Block) Ref() BlockRef {
+	return BlockRef{Hash: b.Hash(), NumChunks: b.node.NumChunks(), ChunkSize: b.node.ChunkSize()}
+}
+
+// NextChunk returns a new random combination of the block's chunks.
+func (b *Block) NextChunk() ([]byte, error) {
+	return b.node.ChunkToSend()
+}
+
+// WrappedChunk is NextChunk with the chunk wrapped in an envelope carrying
+// the block's ID.
+func (b *Block) WrappedChunk() ([]byte, error) {
+	return b.node.WrappedChunkToSend()
+}
+
+// Close frees the block's node. Calling it again does nothing.
+func (b *Block) Close() {
+	b.node.Close()
+}
+
+// NewDecoderForBlock is NewDecoder for the chunks of the block ref describes.
+// Chunks of another block or of the wrong size are refused from the first
+// one, instead of pinning the commitments of whichever chunk comes first.
+func NewDecoderForBlock(r *RLNC, committer []byte, ref BlockRef) (*Decoder, error) {
+	if len(ref.Hash) == 0 {
+		return nil, fmt.Errorf("block ref has no hash")
+	}
+	d, err := NewDecoder(r, committer, ref.NumChunks)
+	if err != nil {
+		return nil, err
+	}
+	d.hash = bytes.Clone(ref.Hash)
+	d.chunkLen = ref.chunkLen()
+	return d, nil
+}
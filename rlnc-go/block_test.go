@@ -0,0 +1,108 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestBlock(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 5000)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(PaddedSize(len(data), numChunks), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	serialized, err := committer.Serialize()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+
+	block, err := committer.NewBlock(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating block: %v", err)
+	}
+	defer block.Close()
+	otherData := make([]byte, len(data))
+	rand.Read(otherData)
+	other, err := committer.NewBlock(otherData, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating block: %v", err)
+	}
+	defer other.Close()
+
+	if block.Len() != len(data) {
+		t.Fatalf("Expected block length %d, got %d", len(data), block.Len())
+	}
+	if id := block.ID(); !bytes.Equal(id[:], block.Hash()[:len(id)]) {
+		t.Fatalf("Expected the block ID to prefix its hash")
+	}
+	if block.ID() == other.ID() {
+		t.Fatalf("Expected blocks of different data to have different IDs")
+	}
+	chunk, err := block.NextChunk()
+	if err != nil {
+		t.Fatalf("Error getting chunk: %v", err)
+	}
+	hash, err := rlnc.CommitmentsHash(chunk)
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+	if !bytes.Equal(hash, block.Hash()) {
+		t.Fatalf("Expected the block hash to match its chunks' commitments")
+	}
+	wrapped, err := block.WrappedChunk()
+	if err != nil {
+		t.Fatalf("Error getting wrapped chunk: %v", err)
+	}
+	if id, _, err := UnwrapChunk(wrapped); err != nil || id != block.ID() {
+		t.Fatalf("Expected a chunk wrapped with the block ID, got %x, %v", id, err)
+	}
+
+	decoder, err := NewDecoderForBlock(rlnc, serialized, block.Ref())
+	if err != nil {
+		t.Fatalf("Error creating decoder: %v", err)
+	}
+	defer decoder.Close()
+	otherChunk, err := other.NextChunk()
+	if err != nil {
+		t.Fatalf("Error getting chunk: %v", err)
+	}
+	if _, err := decoder.Add(otherChunk); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Fatalf("Expected ErrCommitmentMismatch for another block's first chunk, got %v", err)
+	}
+	if _, err := decoder.Add(chunk[:len(chunk)-32]); !errors.Is(err, ErrChunkMismatch) {
+		t.Fatalf("Expected ErrChunkMismatch for a short chunk, got %v", err)
+	}
+	for done := false; !done; {
+		chunk, err := block.NextChunk()
+		if err != nil {
+			t.Fatalf("Error getting chunk: %v", err)
+		}
+		if done, err = decoder.Add(chunk); err != nil {
+			t.Fatalf("Error adding chunk: %v", err)
+		}
+	}
+	got, err := decoder.Bytes()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+
+	block.Close()
+	block.Close()
+	if _, err := block.NextChunk(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from a closed block, got %v", err)
+	}
+}
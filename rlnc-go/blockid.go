@@ -0,0 +1,126 @@
+package rlnc
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// BlockID is a sender chosen identifier for a block, carried in the envelope
+// WrapChunk puts around a chunk. Receivers can route chunks to the right Node
+// by ID and only fall back to CommitmentsHash for IDs they don't know.
+type BlockID [8]byte
+
+// blockEnvelopeVersion is the first byte of a wrapped chunk.
+const blockEnvelopeVersion = 1
+
+// blockEnvelopeSize is the number of bytes WrapChunk adds to a chunk.
+const blockEnvelopeSize = 1 + len(BlockID{})
+
+// WrapChunk returns chunk prefixed with an envelope carrying id.
+func WrapChunk(id BlockID, chunk []byte) []byte {
+	b := make([]byte, 0, blockEnvelopeSize+len(chunk))
+	b = append(b, blockEnvelopeVersion)
+	b = append(b, id[:]...)
+	return append(b, chunk...)
+}
+
+// UnwrapChunk splits a chunk wrapped by WrapChunk into its block ID and the
+// chunk itself. The returned chunk aliases b.
+func UnwrapChunk(b []byte) (BlockID, []byte, error) {
+	var id BlockID
+	if len(b) < blockEnvelopeSize {
+		return id, nil, errors.New("wrapped chunk is too short")
+	}
+	if b[0] != blockEnvelopeVersion {
+		return id, nil, fmt.Errorf("unknown chunk envelope version %d", b[0])
+	}
+	copy(id[:], b[1:])
+	return id, b[blockEnvelopeSize:], nil
+}
+
+// SetBlockID sets the ID of the block n holds. Senders set it on the source
+// node right after creating it so WrappedChunkToSend can wrap chunks with it.
+// Receivers that already know the ID can set it so ReceiveWrappedChunk
+// rejects chunks of other blocks from the first one.
+func (n *Node) SetBlockID(id BlockID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.id = id
+	n.hasID = true
+}
+
+// BlockID returns the ID of the block n holds, and false if it isn't known
+// yet.
+func (n *Node) BlockID() (BlockID, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.id, n.hasID
+}
+
+// WrappedChunkToSend is ChunkToSend with the chunk wrapped in an envelope
+// carrying n's block ID.
+func (n *Node) WrappedChunkToSend() ([]byte, error) {
+	id, ok := n.BlockID()
+	if !ok {
+		return nil, errors.New("node has no block ID")
+	}
+	chunk, err := n.ChunkToSend()
+	if err != nil {
+		return nil, err
+	}
+	return WrapChunk(id, chunk), nil
+}
+
+// ReceiveWrappedChunk is ReceiveChunk for a chunk wrapped by WrapChunk. A
+// chunk with a different block ID than n's is rejected with a
+// *BlockMismatchError, which matches ErrCommitmentMismatch too, without
+// calling into the library. A node without an ID adopts the ID of the first
+// chunk it accepts.
+func (n *Node) ReceiveWrappedChunk(b []byte) (bool, error) {
+	id, chunk, err := UnwrapChunk(b)
+	if err != nil {
+		return false, err
+	}
+	if held, ok := n.BlockID(); ok && id != held {
+		return false, n.wrappedMismatch(id, held, chunk)
+	}
+	ok, err := n.ReceiveChunk(chunk)
+	if ok {
+		n.adoptBlockID(id)
+	}
+	return ok, err
+}
+
+// wrappedMismatch returns the error for a wrapped chunk of block id received
+// by n, which holds block held. It names the blocks by the commitments hashes
+// of n and of chunk, or by their IDs if n holds no chunk yet or chunk is too
+// short to have commitments.
+func (n *Node) wrappedMismatch(id, held BlockID, chunk []byte) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	e := &BlockMismatchError{
+		Node:  held[:blockMismatchPrefix],
+		Chunk: id[:blockMismatchPrefix],
+		Err:   fmt.Errorf("%w: chunk is for block %x, node holds block %x", ErrCommitmentMismatch, id, held),
+	}
+	if hash, err := n.commitmentsHash(); err == nil {
+		e.Node = hash[:blockMismatchPrefix]
+	}
+	if len(chunk) >= 8+32*n.numChunks {
+		hash := sha256.Sum256(chunkCommitments(chunk, n.numChunks))
+		e.Chunk = hash[:blockMismatchPrefix]
+	}
+	return e
+}
+
+// adoptBlockID sets n's block ID to id unless it has one already.
+func (n *Node) adoptBlockID(id BlockID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.hasID {
+		n.id, n.hasID = id, true
+	}
+}
@@ -0,0 +1,83 @@
+package rlnc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWrapChunk(t *testing.T) {
+	id := BlockID{1, 2, 3, 4, 5, 6, 7, 8}
+	chunk := []byte("chunk")
+	gotID, got, err := UnwrapChunk(WrapChunk(id, chunk))
+	if err != nil {
+		t.Fatalf("Error unwrapping chunk: %v", err)
+	}
+	if gotID != id || !bytes.Equal(got, chunk) {
+		t.Fatalf("Unwrapped %x, %q, expected %x, %q", gotID, got, id, chunk)
+	}
+
+	if _, _, err := UnwrapChunk(WrapChunk(id, nil)[:blockEnvelopeSize-1]); err == nil {
+		t.Fatalf("Expected error unwrapping a truncated envelope")
+	}
+	wrapped := WrapChunk(id, chunk)
+	wrapped[0] = 0xff
+	if _, _, err := UnwrapChunk(wrapped); err == nil {
+		t.Fatalf("Expected error unwrapping an unknown envelope version")
+	}
+}
+
+func TestReceiveWrappedChunk(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 2)
+	id := BlockID{1, 2, 3, 4, 5, 6, 7, 8}
+
+	if _, err := sourceNode.WrappedChunkToSend(); err == nil {
+		t.Fatalf("Expected error wrapping a chunk without a block ID")
+	}
+	sourceNode.SetBlockID(id)
+
+	if _, ok := destinationNode.BlockID(); ok {
+		t.Fatalf("Expected a new node to have no block ID")
+	}
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.WrappedChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveWrappedChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	if got, ok := destinationNode.BlockID(); !ok || got != id {
+		t.Fatalf("Expected the destination node to adopt block ID %x, got %x", id, got)
+	}
+	destData, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	if _, err := destinationNode.ReceiveWrappedChunk(WrapChunk(BlockID{9}, chunk)); !errors.Is(err, ErrCommitmentMismatch) || !errors.Is(err, ErrBlockMismatch) {
+		t.Fatalf("Expected ErrBlockMismatch for another block's ID, got %v", err)
+	}
+
+	// A node holding no chunk yet names the blocks by their IDs.
+	empty, err := sourceNode.committer.NewNode(2)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer empty.Close()
+	empty.SetBlockID(id)
+	other := BlockID{9, 8, 7, 6}
+	_, err = empty.ReceiveWrappedChunk(WrapChunk(other, []byte{1}))
+	var mismatch *BlockMismatchError
+	if !errors.As(err, &mismatch) || !bytes.Equal(mismatch.Node, id[:4]) || !bytes.Equal(mismatch.Chunk, other[:4]) {
+		t.Fatalf("Expected a *BlockMismatchError naming blocks %x and %x, got %v", id[:4], other[:4], err)
+	}
+}
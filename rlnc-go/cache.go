@@ -0,0 +1,125 @@
+package rlnc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CommitterCache hands out Committers by chunk size, generating each one only
+// once. Generating a Committer derives a full set of generators, so blocks
+// that all use the same chunk size should share one through a cache rather
+// than each generating their own.
+//
+// The Committers it returns share their native committer, but each must be
+// closed as usual: the native committer is freed once the cache and every
+// Committer handed out for it are closed.
+type CommitterCache struct {
+	r *RLNC
+
+	mu sync.Mutex
+	// entries is keyed by the chunk size in scalars, since committers only
+	// depend on that.
+	entries map[int]*sharedCommitter
+	hits    uint64
+	misses  uint64
+	closed  bool
+}
+
+// CacheStats reports how a CommitterCache has been used.
+type CacheStats struct {
+	// Hits and Misses count the requests served from the cache and those
+	// that generated a Committer.
+	Hits   uint64
+	Misses uint64
+	// Entries is the number of Committers the cache holds.
+	Entries int
+}
+
+// sharedCommitter is a Committer referenced by a CommitterCache and the
+// Committers it handed out.
+type sharedCommitter struct {
+	mu   sync.Mutex
+	refs int
+	c    *Committer
+}
+
+func (s *sharedCommitter) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs--
+	if s.refs == 0 {
+		s.c.Close()
+	}
+}
+
+// NewCommitterCache returns an empty cache generating Committers with r.
+func NewCommitterCache(r *RLNC) *CommitterCache {
+	return &CommitterCache{r: r, entries: make(map[int]*sharedCommitter)}
+}
+
+// GenCommitterForChunkSize returns a Committer for chunks of chunkSize bytes,
+// generating it only if the cache doesn't hold one for the same number of
+// scalars yet.
+func (cc *CommitterCache) GenCommitterForChunkSize(chunkSize int) (*Committer, error) {
+	if err := checkChunkSize(int64(chunkSize)); err != nil {
+		return nil, err
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.closed {
+		return nil, ErrClosed
+	}
+
+	scalars := int(chunkSizeInScalars(int64(chunkSize)))
+	s, ok := cc.entries[scalars]
+	if ok {
+		cc.hits++
+	} else {
+		c, err := cc.r.GenCommitterForChunkSize(chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		cc.misses++
+		s = &sharedCommitter{refs: 1, c: c}
+		cc.entries[scalars] = s
+	}
+
+	s.mu.Lock()
+	s.refs++
+	s.mu.Unlock()
+	return (&Committer{r: s.c.r, p: s.c.p, chunkSize: chunkSize, shared: s}).track(), nil
+}
+
+// GenCommitterForMessage returns a Committer for messages of messageSize
+// bytes split into numChunks chunks, like GenCommitterForChunkSize.
+func (cc *CommitterCache) GenCommitterForMessage(messageSize int, numChunks int) (*Committer, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	if messageSize%numChunks != 0 {
+		return nil, fmt.Errorf("message size must be a multiple of num chunks")
+	}
+	return cc.GenCommitterForChunkSize(messageSize / numChunks)
+}
+
+// Stats returns the cache's statistics.
+func (cc *CommitterCache) Stats() CacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return CacheStats{Hits: cc.hits, Misses: cc.misses, Entries: len(cc.entries)}
+}
+
+// Close drops the cache's references to its Committers. Committers it handed
+// out keep working until they are closed. Calling Close again does nothing.
+func (cc *CommitterCache) Close() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.closed {
+		return
+	}
+	cc.closed = true
+	for _, s := range cc.entries {
+		s.release()
+	}
+	cc.entries = nil
+}
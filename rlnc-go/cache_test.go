@@ -0,0 +1,112 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestCommitterCache(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	cache := NewCommitterCache(rlnc)
+	numChunks := 8
+	blockSize := 128 * 1024
+	first, err := cache.GenCommitterForMessage(blockSize, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	second, err := cache.GenCommitterForMessage(blockSize, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	other, err := cache.GenCommitterForChunkSize(32 * 64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer other.Close()
+	if stats := cache.Stats(); stats != (CacheStats{Hits: 1, Misses: 2, Entries: 2}) {
+		t.Fatalf("Unexpected cache stats %+v", stats)
+	}
+	if equal, err := first.Equal(second); err != nil || !equal {
+		t.Fatalf("Expected cached committers to be equal, got %v, %v", equal, err)
+	}
+
+	// Closing one user's Committer, and then the cache, leaves the other
+	// usable.
+	first.Close()
+	cache.Close()
+	if _, err := cache.GenCommitterForChunkSize(32 * 64); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from a closed cache, got %v", err)
+	}
+
+	data := make([]byte, blockSize)
+	rand.Read(data)
+	sourceNode, err := second.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := second.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+	second.Close()
+	second.Close()
+}
+
+// BenchmarkCommitterSetup measures getting the committer for a 128KB block of
+// 8 chunks, with and without a CommitterCache.
+func BenchmarkCommitterSetup(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	blockSize, numChunks := 128*1024, 8
+	setup := func(b *testing.B, gen func() (*Committer, error)) {
+		for range b.N {
+			committer, err := gen()
+			if err != nil {
+				b.Fatalf("Error creating committer: %v", err)
+			}
+			committer.Close()
+		}
+	}
+
+	b.Run("Uncached", func(b *testing.B) {
+		setup(b, func() (*Committer, error) {
+			return rlnc.GenCommitterForMessage(blockSize, numChunks)
+		})
+	})
+	b.Run("Cached", func(b *testing.B) {
+		cache := NewCommitterCache(rlnc)
+		defer cache.Close()
+		setup(b, func() (*Committer, error) {
+			return cache.GenCommitterForMessage(blockSize, numChunks)
+		})
+	})
+}
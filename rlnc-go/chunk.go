@@ -0,0 +1,268 @@
+package rlnc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Scalar is the 32 byte little-endian encoding of a scalar.
+type Scalar [32]byte
+
+// Point is a compressed Ristretto point.
+type Point [32]byte
+
+// MarshalText encodes s as hex, which is how it appears in JSON.
+func (s Scalar) MarshalText() ([]byte, error) {
+	return hexText(s[:]), nil
+}
+
+// UnmarshalText decodes s from the hex MarshalText returns.
+func (s *Scalar) UnmarshalText(text []byte) error {
+	return unhexText(s[:], text)
+}
+
+// MarshalText encodes p as hex, which is how it appears in JSON.
+func (p Point) MarshalText() ([]byte, error) {
+	return hexText(p[:]), nil
+}
+
+// UnmarshalText decodes p from the hex MarshalText returns.
+func (p *Point) UnmarshalText(text []byte) error {
+	return unhexText(p[:], text)
+}
+
+func hexText(b []byte) []byte {
+	return hex.AppendEncode(nil, b)
+}
+
+func unhexText(dst, text []byte) error {
+	if hex.DecodedLen(len(text)) != len(dst) {
+		return fmt.Errorf("expected %d hex digits, got %d", 2*len(dst), len(text))
+	}
+	_, err := hex.Decode(dst, text)
+	return err
+}
+
+// Chunk is a coded chunk parsed from the bytes ChunkToSend returns. The
+// library only receives chunks in the wire format, so hand a Node the bytes
+// a Chunk was parsed from rather than marshaling it again.
+type Chunk struct {
+	// Payload is the combination of the original chunks' scalars.
+	Payload []Scalar
+	// Coefficients holds the weight of each original chunk in Payload.
+	Coefficients []Scalar
+	// Commitments holds a commitment to each original chunk. They are the
+	// same for every chunk of a block.
+	Commitments []Point
+}
+
+// chunkWireSize returns the length of a chunk of a block of numChunks chunks
+// of chunkSize bytes: three length prefixes, the payload scalars and a
+// coefficient and a commitment per chunk.
+func chunkWireSize(chunkSize, numChunks int) int {
+	return 3*8 + 32*(int(chunkSizeInScalars(int64(chunkSize)))+2*numChunks)
+}
+
+// ParseChunk parses a chunk in the library's wire format: the payload,
+// coefficients and commitments, each a little-endian uint64 count followed
+// by that many 32 byte values.
+func ParseChunk(b []byte) (*Chunk, error) {
+	var c Chunk
+	var err error
+	if c.Payload, b, err = parseVector[Scalar](b, "payload"); err != nil {
+		return nil, err
+	}
+	if c.Coefficients, b, err = parseVector[Scalar](b, "coefficients"); err != nil {
+		return nil, err
+	}
+	if c.Commitments, b, err = parseVector[Point](b, "commitments"); err != nil {
+		return nil, err
+	}
+	if len(b) != 0 {
+		return nil, fmt.Errorf("chunk has %d trailing bytes", len(b))
+	}
+	return &c, nil
+}
+
+func parseVector[T ~[32]byte](b []byte, name string) ([]T, []byte, error) {
+	rest, err := skipVector(b, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := make([]T, (len(b)-8-len(rest))/32)
+	for i := range v {
+		copy(v[i][:], b[8+32*i:])
+	}
+	return v, rest, nil
+}
+
+// CommitmentsOf returns the commitments segment of a chunk in the wire
+// format: the little-endian uint64 count followed by the compressed points.
+// These are the bytes CommitmentsHash hashes, so its SHA-256 digest matches
+// CommitmentsHash. The returned slice aliases chunk.
+func CommitmentsOf(chunk []byte) ([]byte, error) {
+	b := chunk
+	for _, name := range []string{"payload", "coefficients"} {
+		var err error
+		if b, err = skipVector(b, name); err != nil {
+			return nil, err
+		}
+	}
+	rest, err := skipVector(b, "commitments")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("chunk has %d trailing bytes", len(rest))
+	}
+	return b, nil
+}
+
+func skipVector(b []byte, name string) ([]byte, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("chunk is truncated before the %s length", name)
+	}
+	n := binary.LittleEndian.Uint64(b)
+	b = b[8:]
+	if n > uint64(len(b)/32) {
+		return nil, fmt.Errorf("chunk is truncated in the %s", name)
+	}
+	return b[32*n:], nil
+}
+
+// checkChunkFrame checks that chunk is laid out like a chunk of a block of
+// numChunks chunks of at most maxScalars scalars each, with every segment the
+// length its count says and nothing after them. It doesn't look at the values
+// themselves, which only the library can check.
+func checkChunkFrame(chunk []byte, numChunks, maxScalars int) error {
+	b := chunk
+	for _, segment := range []struct {
+		name     string
+		min, max uint64
+	}{
+		{"payload", 1, uint64(maxScalars)},
+		{"coefficients", uint64(numChunks), uint64(numChunks)},
+		{"commitments", uint64(numChunks), uint64(numChunks)},
+	} {
+		if len(b) >= 8 {
+			count := binary.LittleEndian.Uint64(b)
+			if count < segment.min || count > segment.max {
+				if segment.min == segment.max {
+					return fmt.Errorf("%w: %d values in the %s, want %d", ErrMalformedChunk, count, segment.name, segment.min)
+				}
+				return fmt.Errorf("%w: %d values in the %s, want %d to %d", ErrMalformedChunk, count, segment.name, segment.min, segment.max)
+			}
+		}
+		var err error
+		if b, err = skipVector(b, segment.name); err != nil {
+			return fmt.Errorf("%w: %w", ErrMalformedChunk, err)
+		}
+	}
+	if len(b) != 0 {
+		return fmt.Errorf("%w: chunk has %d trailing bytes", ErrMalformedChunk, len(b))
+	}
+	return nil
+}
+
+// chunkCommitments returns the commitments of a chunk of numChunks chunks
+// that passed checkChunkFrame, serialized like in the chunk, which is what
+// its commitments hash is taken over.
+func chunkCommitments(chunk []byte, numChunks int) []byte {
+	return chunk[len(chunk)-(8+32*numChunks):]
+}
+
+// coefficientCount returns the number of coefficients of a chunk in the wire
+// format, which is the number of chunks of its block, or false if the chunk
+// is too short to tell.
+func coefficientCount(chunk []byte) (uint64, bool) {
+	b, err := skipVector(chunk, "payload")
+	if err != nil || len(b) < 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(b), true
+}
+
+// Marshal encodes c in the library's wire format.
+func (c *Chunk) Marshal() []byte {
+	b := make([]byte, 0, 24+32*(len(c.Payload)+len(c.Coefficients)+len(c.Commitments)))
+	b = appendVector(b, c.Payload)
+	b = appendVector(b, c.Coefficients)
+	return appendVector(b, c.Commitments)
+}
+
+func appendVector[T ~[32]byte](b []byte, v []T) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(v)))
+	for _, x := range v {
+		b = append(b, x[:]...)
+	}
+	return b
+}
+
+// chunkJSON is the JSON form of a Chunk. A truncated chunk has the length
+// and digest of its payload instead of the payload.
+type chunkJSON struct {
+	Payload       []Scalar `json:"payload,omitempty"`
+	PayloadLen    int      `json:"payload_len,omitempty"`
+	PayloadDigest string   `json:"payload_digest,omitempty"`
+	Coefficients  []Scalar `json:"coefficients"`
+	Commitments   []Point  `json:"commitments"`
+}
+
+// MarshalJSON renders c with its scalars and points as hex strings, for
+// debugging tools and logs. It keeps the whole payload, so UnmarshalJSON
+// gives c back; marshal a TruncatedChunk to leave it out.
+func (c *Chunk) MarshalJSON() ([]byte, error) {
+	return json.Marshal(chunkJSON{Payload: c.Payload, Coefficients: c.Coefficients, Commitments: c.Commitments})
+}
+
+// UnmarshalJSON parses the JSON MarshalJSON returns. The JSON of a
+// TruncatedChunk is rejected, since its payload is gone.
+func (c *Chunk) UnmarshalJSON(b []byte) error {
+	var j chunkJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if j.PayloadDigest != "" {
+		return errors.New("chunk payload was truncated")
+	}
+	*c = Chunk{Payload: j.Payload, Coefficients: j.Coefficients, Commitments: j.Commitments}
+	return nil
+}
+
+// TruncatedChunk marshals a Chunk to JSON like Chunk.MarshalJSON, but with
+// the payload replaced by its length in scalars and its SHA-256 digest, which
+// keeps log lines short when the data itself isn't of interest.
+type TruncatedChunk struct {
+	*Chunk
+}
+
+// MarshalJSON renders the chunk with a digest of its payload.
+func (t TruncatedChunk) MarshalJSON() ([]byte, error) {
+	digest := sha256.Sum256(appendVector(nil, t.Payload))
+	return json.Marshal(chunkJSON{
+		PayloadLen:    len(t.Payload),
+		PayloadDigest: hex.EncodeToString(digest[:]),
+		Coefficients:  t.Coefficients,
+		Commitments:   t.Commitments,
+	})
+}
+
+// Summary returns a one-line description of c for log lines: the first bytes
+// of its commitments hash, which identifies its block, how many of its
+// coefficients are non-zero out of how many chunks, and its size on the
+// wire.
+func (c *Chunk) Summary() string {
+	hash := sha256.Sum256(appendVector(nil, c.Commitments))
+	degree := 0
+	for _, x := range c.Coefficients {
+		if x != (Scalar{}) {
+			degree++
+		}
+	}
+	size := 24 + 32*(len(c.Payload)+len(c.Coefficients)+len(c.Commitments))
+	return fmt.Sprintf("block=%x degree=%d/%d size=%d", hash[:4], degree, len(c.Coefficients), size)
+}
@@ -0,0 +1,197 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseChunk(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	for !destinationNode.IsFull() {
+		raw, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		chunk, err := ParseChunk(raw)
+		if err != nil {
+			t.Fatalf("Error parsing chunk: %v", err)
+		}
+		if len(chunk.Payload) != int(chunkSizeInScalars(int64(len(data)/numChunks))) || len(chunk.Coefficients) != numChunks || len(chunk.Commitments) != numChunks {
+			t.Fatalf("Unexpected chunk shape: %d payload scalars, %d coefficients, %d commitments", len(chunk.Payload), len(chunk.Coefficients), len(chunk.Commitments))
+		}
+		if !bytes.Equal(chunk.Marshal(), raw) {
+			t.Fatalf("Marshaled chunk doesn't match the original")
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk.Marshal()); err != nil {
+			t.Fatalf("Error receiving marshaled chunk: %v", err)
+		}
+
+		for _, n := range []int{0, 7, 8, len(raw) / 2, len(raw) - 1} {
+			if _, err := ParseChunk(raw[:n]); err == nil {
+				t.Fatalf("Expected error parsing a chunk truncated to %d bytes", n)
+			}
+		}
+		if _, err := ParseChunk(append(raw, 0)); err == nil {
+			t.Fatalf("Expected error parsing a chunk with trailing bytes")
+		}
+	}
+}
+
+func TestCommitmentsOf(t *testing.T) {
+	sourceNode, _, _ := newStreamNodes(t, 3)
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	commitments, err := CommitmentsOf(chunk)
+	if err != nil {
+		t.Fatalf("Error extracting commitments: %v", err)
+	}
+	if len(commitments) != 8+32*3 {
+		t.Fatalf("Expected %d bytes of commitments, got %d", 8+32*3, len(commitments))
+	}
+	want, err := sourceNode.r.CommitmentsHash(chunk)
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+	if got := sha256.Sum256(commitments); !bytes.Equal(got[:], want) {
+		t.Fatalf("Hash of extracted commitments %x doesn't match CommitmentsHash %x", got, want)
+	}
+
+	other, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	otherCommitments, err := CommitmentsOf(other)
+	if err != nil {
+		t.Fatalf("Error extracting commitments: %v", err)
+	}
+	if !bytes.Equal(commitments, otherCommitments) {
+		t.Fatalf("Chunks of the same block have different commitments")
+	}
+
+	if _, err := CommitmentsOf(chunk[:len(chunk)-1]); err == nil {
+		t.Fatalf("Expected error extracting commitments from a truncated chunk")
+	}
+}
+
+func TestChunkJSON(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	raw, err := sourceNode.SystematicChunk(1)
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	chunk, err := ParseChunk(raw)
+	if err != nil {
+		t.Fatalf("Error parsing chunk: %v", err)
+	}
+
+	full, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatalf("Error marshaling chunk: %v", err)
+	}
+	if want := fmt.Sprintf(`"commitments":["%x"`, chunk.Commitments[0]); !strings.Contains(string(full), want) {
+		t.Fatalf("Expected hex commitments in %s", full)
+	}
+	var decoded Chunk
+	if err := json.Unmarshal(full, &decoded); err != nil {
+		t.Fatalf("Error unmarshaling chunk: %v", err)
+	}
+	if !bytes.Equal(decoded.Marshal(), raw) {
+		t.Fatalf("Expected the chunk to round-trip through JSON")
+	}
+
+	truncated, err := json.Marshal(TruncatedChunk{chunk})
+	if err != nil {
+		t.Fatalf("Error marshaling truncated chunk: %v", err)
+	}
+	if strings.Contains(string(truncated), `"payload"`) || !strings.Contains(string(truncated), `"payload_digest"`) {
+		t.Fatalf("Expected a payload digest instead of the payload in %s", truncated)
+	}
+	if err := json.Unmarshal(truncated, &decoded); err == nil {
+		t.Fatalf("Expected an error unmarshaling a truncated chunk")
+	}
+	if err := json.Unmarshal([]byte(`{"coefficients":["00"]}`), &decoded); err == nil {
+		t.Fatalf("Expected an error for a short scalar")
+	}
+
+	hash, err := sourceNode.CommitmentsHash()
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+	want := fmt.Sprintf("block=%x degree=1/%d size=%d", hash[:4], numChunks, len(raw))
+	if summary := chunk.Summary(); summary != want {
+		t.Fatalf("Expected summary %q, got %q", want, summary)
+	}
+}
+
+func FuzzParseChunk(f *testing.F) {
+	f.Add((&Chunk{}).Marshal())
+	f.Add((&Chunk{
+		Payload:      make([]Scalar, 2),
+		Coefficients: make([]Scalar, 1),
+		Commitments:  make([]Point, 1),
+	}).Marshal())
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		chunk, err := ParseChunk(b)
+		_, commitmentsErr := CommitmentsOf(b)
+		if (err == nil) != (commitmentsErr == nil) {
+			t.Fatalf("ParseChunk and CommitmentsOf disagree: %v, %v", err, commitmentsErr)
+		}
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(chunk.Marshal(), b) {
+			t.Fatalf("Marshaled chunk doesn't match the parsed bytes")
+		}
+	})
+}
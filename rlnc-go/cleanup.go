@@ -0,0 +1,20 @@
+package rlnc
+
+// Cleanup removes every library file this process extracted, including ones
+// still in use by an open RLNC. Files are otherwise removed when the last RLNC
+// using them is closed, which never happens for Default.
+func Cleanup() error {
+	return cleanupLibs()
+}
+
+// RunAndCleanup runs m and then calls Cleanup, returning m's exit code. It is
+// meant for TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(rlnc.RunAndCleanup(m))
+//	}
+func RunAndCleanup(m interface{ Run() int }) int {
+	code := m.Run()
+	Cleanup()
+	return code
+}
@@ -0,0 +1,57 @@
+package rlnc
+
+// ChunkSource produces the coded chunks of a block. *Node implements it, so
+// code sending chunks can take a ChunkSource and be tested with a fake one.
+type ChunkSource interface {
+	ChunkToSend() ([]byte, error)
+	Close()
+}
+
+// ChunkSink decodes a block from coded chunks, with the semantics of
+// Node.ReceiveChunk: a chunk is innovative, dropped as dependent without an
+// error, or rejected, and the caller may reuse it as soon as ReceiveChunk
+// returns. *Node implements it.
+type ChunkSink interface {
+	ReceiveChunk(chunk []byte) (bool, error)
+	IsFull() bool
+	Rank() int
+	Data() ([]byte, error)
+	Close()
+}
+
+// CommitterAPI creates the sources and sinks of blocks and tells which block
+// a chunk belongs to. *Committer implements it, and the Manager takes one.
+type CommitterAPI interface {
+	NewSource(block []byte, numChunks int) (ChunkSource, error)
+	NewSink(numChunks int) (ChunkSink, error)
+	// ChunkHash returns a hash identifying the block of chunk.
+	ChunkHash(chunk []byte) ([]byte, error)
+}
+
+// NewSource is NewSourceNode returning a ChunkSource.
+func (c *Committer) NewSource(block []byte, numChunks int) (ChunkSource, error) {
+	n, err := c.NewSourceNode(block, numChunks)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// NewSink is NewNode returning a ChunkSink.
+func (c *Committer) NewSink(numChunks int) (ChunkSink, error) {
+	n, err := c.NewNode(numChunks)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// ChunkHash returns the hash of the commitments chunk carries, the same as
+// RLNC.CommitmentsHash, which identifies its block.
+func (c *Committer) ChunkHash(chunk []byte) ([]byte, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.r.CommitmentsHash(chunk)
+}
@@ -0,0 +1,64 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+var (
+	_ ChunkSource  = (*Node)(nil)
+	_ ChunkSink    = (*Node)(nil)
+	_ CommitterAPI = (*Committer)(nil)
+)
+
+func TestEncoderDecoderFromInterfaces(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	block := make([]byte, numChunks*64)
+	rand.Read(block)
+	committer, err := rlnc.GenCommitterForMessage(len(block), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	var api CommitterAPI = committer
+	source, err := api.NewSource(block, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source: %v", err)
+	}
+	encoder := NewEncoderFromSource(source, numChunks)
+	defer encoder.Close()
+	if _, err := encoder.Committer(); err == nil {
+		t.Fatalf("Expected an error for the committer of an encoder without one")
+	}
+	sink, err := api.NewSink(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating sink: %v", err)
+	}
+	decoder := NewDecoderForSink(sink)
+	defer decoder.Close()
+
+	for done := false; !done; {
+		chunk, err := encoder.NextChunk()
+		if err != nil {
+			t.Fatalf("Error getting chunk: %v", err)
+		}
+		if done, err = decoder.Add(chunk); err != nil {
+			t.Fatalf("Error adding chunk: %v", err)
+		}
+	}
+	data, err := decoder.Bytes()
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if !bytes.Equal(data, block) {
+		t.Fatalf("Decoded data doesn't match the block")
+	}
+}
@@ -0,0 +1,124 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// Commitments are the commitments to the chunks of a block, which every chunk
+// of it carries. Committing to a block once with Committer.Commit lets them
+// be advertised, hashed and reused without a source node.
+type Commitments struct {
+	// serialized is the wire form, as returned by CommitmentsOf.
+	serialized []byte
+	numChunks  int
+}
+
+// ParseCommitments parses commitments serialized by Serialize or returned by
+// CommitmentsOf.
+func ParseCommitments(b []byte) (*Commitments, error) {
+	points, rest, err := parseVector[Point](b, "commitments")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("commitments have %d trailing bytes", len(rest))
+	}
+	if len(points) == 0 {
+		return nil, errors.New("no commitments")
+	}
+	return &Commitments{serialized: bytes.Clone(b), numChunks: len(points)}, nil
+}
+
+// Serialize returns the commitments in the wire format of a chunk's
+// commitments segment.
+func (c *Commitments) Serialize() []byte {
+	return bytes.Clone(c.serialized)
+}
+
+// Hash returns the hash of the commitments, the same as CommitmentsHash of
+// the chunks that carry them.
+func (c *Commitments) Hash() []byte {
+	sum := sha256.Sum256(c.serialized)
+	return sum[:]
+}
+
+// NumChunks returns the number of chunks committed to.
+func (c *Commitments) NumChunks() int {
+	return c.numChunks
+}
+
+// Points returns a commitment to each chunk.
+func (c *Commitments) Points() []Point {
+	points, _, _ := parseVector[Point](c.serialized, "commitments")
+	return points
+}
+
+// Commit computes the commitments to the chunks of block split into numChunks
+// chunks, without creating a source node. Block must be split the way
+// NewSourceNode requires.
+func (c *Committer) Commit(block []byte, numChunks int) (*Commitments, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
+	if _, err := c.checkBlock(block, numChunks); err != nil {
+		return nil, err
+	}
+	var outPtr unsafe.Pointer
+	var outLen uint64
+	if res := c.r.commitBlock(c.p, block, uint64(len(block)), uint32(numChunks), &outPtr, &outLen); res != 0 {
+		return nil, errors.New("failed to commit to block")
+	}
+	defer c.r.freeBuffer(outPtr, outLen)
+	serialized := bytes.Clone(unsafe.Slice((*byte)(outPtr), int(outLen)))
+	return &Commitments{serialized: serialized, numChunks: numChunks}, nil
+}
+
+// NewSourceNodeWithCommitments is NewSourceNode reusing commitments from
+// Commit instead of computing them again. They aren't checked against block,
+// so commitments to another block give a node whose chunks don't verify.
+func (c *Committer) NewSourceNodeWithCommitments(block []byte, numChunks int, commitments *Commitments) (*Node, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
+	chunkSize, err := c.checkBlock(block, numChunks)
+	if err != nil {
+		return nil, err
+	}
+	if commitments.NumChunks() != numChunks {
+		return nil, fmt.Errorf("%d commitments for %d chunks", commitments.NumChunks(), numChunks)
+	}
+	p, err := c.r.newHandle("create source node", func() unsafe.Pointer {
+		return c.r.newSourceNodeWith(c.p, block, uint64(len(block)), uint32(numChunks), commitments.serialized, uint64(len(commitments.serialized)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.r.acquire()
+	c.nodes.Add(1)
+	return (&Node{r: c.r, p: p, committer: c, numChunks: numChunks, chunkSize: chunkSize}).track(), nil
+}
+
+// ExpectCommitments tells n the commitments of the block it decodes ahead of
+// its first chunk, so chunks of any other block are rejected with an error
+// wrapping ErrCommitmentMismatch rather than the first one received deciding
+// the block. It returns such an error if n already holds other commitments,
+// and one wrapping ErrInvalidMessage if they aren't one per chunk.
+func (n *Node) ExpectCommitments(commitments *Commitments) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	if commitments.NumChunks() != n.numChunks {
+		return fmt.Errorf("%d commitments for %d chunks: %w", commitments.NumChunks(), n.numChunks, ErrInvalidMessage)
+	}
+	return receiveError(n.r.expectCommitments(n.p, commitments.serialized, uint64(len(commitments.serialized))))
+}
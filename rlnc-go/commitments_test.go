@@ -0,0 +1,125 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestCommit(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	block := make([]byte, numChunks*64)
+	rand.Read(block)
+	committer, err := rlnc.GenCommitterForMessage(len(block), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	commitments, err := committer.Commit(block, numChunks)
+	if err != nil {
+		t.Fatalf("Error committing to block: %v", err)
+	}
+	if commitments.NumChunks() != numChunks || len(commitments.Points()) != numChunks {
+		t.Fatalf("Expected %d commitments, got %d", numChunks, commitments.NumChunks())
+	}
+	parsed, err := ParseCommitments(commitments.Serialize())
+	if err != nil {
+		t.Fatalf("Error parsing commitments: %v", err)
+	}
+	if !bytes.Equal(parsed.Hash(), commitments.Hash()) {
+		t.Fatalf("Expected parsed commitments to hash the same")
+	}
+
+	sourceNode, err := committer.NewSourceNodeWithCommitments(block, numChunks, commitments)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	hash, err := sourceNode.CommitmentsHash()
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+	if !bytes.Equal(hash, commitments.Hash()) {
+		t.Fatalf("Expected the source node to carry the commitments")
+	}
+	if hash, _ := committer.CommitmentsHashForBlock(block, numChunks); !bytes.Equal(hash, commitments.Hash()) {
+		t.Fatalf("Expected Commit to match the source node's commitments")
+	}
+
+	impostorBlock := make([]byte, len(block))
+	rand.Read(impostorBlock)
+	impostor, err := committer.NewSourceNode(impostorBlock, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer impostor.Close()
+
+	node, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer node.Close()
+	if err := node.ExpectCommitments(commitments); err != nil {
+		t.Fatalf("Error setting commitments: %v", err)
+	}
+	chunk, err := impostor.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error sending chunk: %v", err)
+	}
+	if _, err := node.ReceiveChunk(chunk); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Fatalf("Expected ErrCommitmentMismatch for the impostor's chunk, got %v", err)
+	}
+	impostorCommitments, err := ParseCommitments(mustCommitmentsOf(t, chunk))
+	if err != nil {
+		t.Fatalf("Error parsing commitments: %v", err)
+	}
+	if err := node.ExpectCommitments(impostorCommitments); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Fatalf("Expected ErrCommitmentMismatch for other commitments, got %v", err)
+	}
+
+	if _, err := node.ReceiveSourceChunk(0, block[:64]); err != nil {
+		t.Fatalf("Error receiving source chunk: %v", err)
+	}
+	for !node.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error sending chunk: %v", err)
+		}
+		if _, err := node.ReceiveChunk(chunk); err != nil && !errors.Is(err, ErrLinearlyDependent) {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	data, err := node.Data()
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if !bytes.Equal(data, block) {
+		t.Fatalf("Decoded data doesn't match the block")
+	}
+
+	other, err := committer.NewNode(numChunks + 1)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer other.Close()
+	if err := other.ExpectCommitments(commitments); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for the wrong number of commitments, got %v", err)
+	}
+}
+
+func mustCommitmentsOf(t *testing.T, chunk []byte) []byte {
+	t.Helper()
+	commitments, err := CommitmentsOf(chunk)
+	if err != nil {
+		t.Fatalf("Error getting commitments: %v", err)
+	}
+	return commitments
+}
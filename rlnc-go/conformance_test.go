@@ -0,0 +1,28 @@
+package rlnc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/marcopolo/rlnc_poc/rlnc-go"
+	"github.com/marcopolo/rlnc_poc/rlnc-go/rlnctest"
+)
+
+func TestCommitterConformance(t *testing.T) {
+	r, err := rlnc.NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer r.Close()
+	committer, err := r.GenCommitterForChunkSize(64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	// Off-by-one bugs hide in the smallest blocks.
+	for _, numChunks := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("%dChunks", numChunks), func(t *testing.T) {
+			rlnctest.TestCoder(t, committer, numChunks, 64)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package rlnc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// receiveStats counts the chunks a node received by outcome. The counters are
+// atomic since ReceiveChunksContext can leave a batch receiving in the
+// background.
+type receiveStats struct {
+	accepted  atomic.Uint64
+	dependent atomic.Uint64
+	invalid   atomic.Uint64
+}
+
+// record counts a chunk received with err.
+func (s *receiveStats) record(err error) {
+	switch {
+	case err == nil:
+		s.accepted.Add(1)
+	case errors.Is(err, ErrLinearlyDependent):
+		s.dependent.Add(1)
+	default:
+		s.invalid.Add(1)
+	}
+}
+
+// DebugState is a snapshot of a node's progress for diagnostics.
+type DebugState struct {
+	// Closed is set once the node is closed. Only NumChunks and the
+	// counters are kept then.
+	Closed    bool
+	Rank      int
+	NumChunks int
+	// CommitmentsLocked is set once the node knows the commitments of its
+	// block, and rejects chunks with others.
+	CommitmentsLocked bool
+	// CommitmentsHashPrefix is the first 4 bytes of the commitments hash, if
+	// they are locked.
+	CommitmentsHashPrefix []byte
+	// NativeMemory is the bytes of memory the library holds for the node.
+	NativeMemory uint64
+	// Accepted, Dependent and Invalid count the chunks received since the
+	// node was created that were innovative, linearly dependent and
+	// rejected.
+	Accepted  uint64
+	Dependent uint64
+	Invalid   uint64
+}
+
+// DebugState returns a snapshot of n's progress, to find out why it isn't
+// full yet. It can be called at any time, including after Close.
+func (n *Node) DebugState() DebugState {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s := DebugState{
+		Closed:    n.p == nil,
+		NumChunks: n.numChunks,
+		Accepted:  n.stats.accepted.Load(),
+		Dependent: n.stats.dependent.Load(),
+		Invalid:   n.stats.invalid.Load(),
+	}
+	if s.Closed {
+		return s
+	}
+	s.Rank = int(n.r.rank(n.p))
+	s.NativeMemory = n.r.memoryUsage(n.p)
+	if hash, err := n.commitmentsHash(); err == nil {
+		s.CommitmentsLocked = true
+		s.CommitmentsHashPrefix = hash[:4]
+	}
+	return s
+}
+
+// String renders s on one line.
+func (s DebugState) String() string {
+	var b strings.Builder
+	if s.Closed {
+		b.WriteString("closed ")
+	}
+	fmt.Fprintf(&b, "rank=%d/%d", s.Rank, s.NumChunks)
+	if s.CommitmentsLocked {
+		fmt.Fprintf(&b, " commitments=%x", s.CommitmentsHashPrefix)
+	} else {
+		b.WriteString(" commitments=none")
+	}
+	fmt.Fprintf(&b, " memory=%dB accepted=%d dependent=%d invalid=%d", s.NativeMemory, s.Accepted, s.Dependent, s.Invalid)
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package rlnc
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestDebugState(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	block := make([]byte, numChunks*64)
+	rand.Read(block)
+	committer, err := rlnc.GenCommitterForMessage(len(block), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(block, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	node, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	state := node.DebugState()
+	if state.Closed || state.Rank != 0 || state.NumChunks != numChunks || state.CommitmentsLocked {
+		t.Fatalf("Unexpected state of a new node: %v", state)
+	}
+	if !strings.Contains(state.String(), "commitments=none") {
+		t.Fatalf("Expected no commitments in %q", state)
+	}
+
+	// Systematic chunks are always innovative, so the counters are exact.
+	chunk, err := sourceNode.SystematicChunk(0)
+	if err != nil {
+		t.Fatalf("Error sending chunk: %v", err)
+	}
+	if _, err := node.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	corrupted := append([]byte(nil), chunk...)
+	corrupted[8] ^= 1
+	if _, err := node.ReceiveChunk(corrupted); err == nil {
+		t.Fatalf("Expected an error for a corrupted chunk")
+	}
+	for i := 1; i < numChunks; i++ {
+		chunk, err := sourceNode.SystematicChunk(i)
+		if err != nil {
+			t.Fatalf("Error sending chunk: %v", err)
+		}
+		node.ReceiveChunks([][]byte{chunk, chunk})
+	}
+
+	state = node.DebugState()
+	hash, err := sourceNode.CommitmentsHash()
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+	if state.Rank != numChunks || !state.CommitmentsLocked || string(state.CommitmentsHashPrefix) != string(hash[:4]) {
+		t.Fatalf("Unexpected state of a full node: %v", state)
+	}
+	if state.Accepted != uint64(numChunks) || state.Dependent != uint64(numChunks-1) || state.Invalid != 1 {
+		t.Fatalf("Expected %d accepted, %d dependent and 1 invalid chunks, got %v", numChunks, numChunks-1, state)
+	}
+	if state.NativeMemory == 0 {
+		t.Fatalf("Expected the node to use native memory")
+	}
+
+	node.Close()
+	state = node.DebugState()
+	if !state.Closed || state.Rank != 0 || state.NativeMemory != 0 || state.Accepted != uint64(numChunks) {
+		t.Fatalf("Unexpected state of a closed node: %v", state)
+	}
+	if !strings.HasPrefix(state.String(), "closed ") {
+		t.Fatalf("Expected a closed marker in %q", state)
+	}
+}
@@ -0,0 +1,22 @@
+package rlnc
+
+import "sync"
+
+var (
+	defaultOnce sync.Once
+	defaultRLNC *RLNC
+	defaultErr  error
+)
+
+// Default returns a process-wide RLNC, loading the library on first use. Every
+// call returns the same RLNC, or the same error if loading failed. Since it's
+// shared by every caller, Close on the default RLNC is a no-op.
+func Default() (*RLNC, error) {
+	defaultOnce.Do(func() {
+		defaultRLNC, defaultErr = NewRLNC()
+		if defaultErr == nil {
+			defaultRLNC.shared = true
+		}
+	})
+	return defaultRLNC, defaultErr
+}
@@ -0,0 +1,37 @@
+package rlnc
+
+import "testing"
+
+func TestDefault(t *testing.T) {
+	r, err := Default()
+	if err != nil {
+		t.Fatalf("Error getting default RLNC: %v", err)
+	}
+	again, err := Default()
+	if err != nil {
+		t.Fatalf("Error getting default RLNC: %v", err)
+	}
+	if r != again {
+		t.Fatalf("Default returned different instances")
+	}
+
+	// Closing the default instance must not unload the library for others.
+	r.Close()
+
+	committer, err := again.GenCommitterForMessage(31*512*8, 8)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	data := make([]byte, 31*512*8)
+	sourceNode, err := committer.NewSourceNode(data, 8)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	if _, err := sourceNode.ChunkToSend(); err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+}
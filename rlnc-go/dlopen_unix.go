@@ -0,0 +1,23 @@
+//go:build !windows && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import "github.com/ebitengine/purego"
+
+// defaultDlopenFlags is used unless WithDlopenFlags says otherwise.
+const defaultDlopenFlags = purego.RTLD_NOW | purego.RTLD_GLOBAL
+
+func openLib(path string, flags int) (uintptr, error) {
+	if flags == 0 {
+		flags = defaultDlopenFlags
+	}
+	return purego.Dlopen(path, flags)
+}
+
+func closeLib(lib uintptr) {
+	purego.Dlclose(lib)
+}
+
+func libSymbol(lib uintptr, name string) (uintptr, error) {
+	return purego.Dlsym(lib, name)
+}
@@ -0,0 +1,85 @@
+//go:build !windows && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ebitengine/purego"
+)
+
+func TestDlopenLocal(t *testing.T) {
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	defer releaseLibPath(libPath)
+	if !filepath.IsAbs(libPath) {
+		t.Skip("library is resolved by the system loader")
+	}
+
+	// A copy of the library is another Rust dylib exporting the same std
+	// symbols, and all of ours too.
+	libData, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Error reading library: %v", err)
+	}
+	otherPath := filepath.Join(t.TempDir(), "libother.so")
+	if err := os.WriteFile(otherPath, libData, 0755); err != nil {
+		t.Fatalf("Error writing library: %v", err)
+	}
+	other, err := purego.Dlopen(otherPath, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Error loading other library: %v", err)
+	}
+	defer purego.Dlclose(other)
+
+	for i := 0; i < 2; i++ {
+		rlnc, err := NewRLNCWithOptions(WithDlopenFlags(purego.RTLD_NOW | purego.RTLD_LOCAL))
+		if err != nil {
+			t.Fatalf("Error creating RLNC: %v", err)
+		}
+		defer rlnc.Close()
+
+		numChunks := 4
+		committer, err := rlnc.GenCommitterForMessage(32*64*numChunks, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating committer: %v", err)
+		}
+		defer committer.Close()
+
+		data := make([]byte, 32*64*numChunks)
+		rand.Read(data)
+		source, err := committer.NewSourceNode(data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer source.Close()
+		dest, err := committer.NewNode(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer dest.Close()
+
+		for !dest.IsFull() {
+			chunk, err := source.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			if _, err := dest.ReceiveChunk(chunk); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+		got, err := dest.Data()
+		if err != nil {
+			t.Fatalf("Error getting data: %v", err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("Decoded data doesn't match")
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import "syscall"
+
+// purego has no Dlopen on Windows; the handle returned by LoadLibrary can be
+// passed to purego.RegisterLibFunc directly. There are no dlopen flags to
+// apply.
+func openLib(path string, flags int) (uintptr, error) {
+	handle, err := syscall.LoadLibrary(path)
+	return uintptr(handle), err
+}
+
+func closeLib(lib uintptr) {
+	syscall.FreeLibrary(syscall.Handle(lib))
+}
+
+func libSymbol(lib uintptr, name string) (uintptr, error) {
+	return syscall.GetProcAddress(syscall.Handle(lib), name)
+}
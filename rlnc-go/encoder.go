@@ -0,0 +1,171 @@
+package rlnc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Encoder produces coded chunks of a block of any size. It creates the
+// Committer, pads the data and frees everything on Close.
+type Encoder struct {
+	committer *Committer
+	source    ChunkSource
+	numChunks int
+	hash      []byte
+	closed    bool
+}
+
+// NewEncoder creates an Encoder splitting data into numChunks chunks.
+func NewEncoder(r *RLNC, data []byte, numChunks int) (*Encoder, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	committer, err := r.GenCommitterForMessage(PaddedSize(len(data), numChunks), numChunks)
+	if err != nil {
+		return nil, err
+	}
+	node, err := committer.NewSourceNodePadded(data, numChunks)
+	if err != nil {
+		committer.Close()
+		return nil, err
+	}
+	e := &Encoder{committer: committer, source: node, numChunks: numChunks}
+
+	chunk, err := node.ChunkToSend()
+	if err == nil {
+		e.hash, err = r.CommitmentsHash(chunk)
+	}
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEncoderFromSource returns an Encoder sending the chunks of source, a
+// block of numChunks chunks, such as a fake ChunkSource in tests. The Encoder
+// closes source. It has no Committer and its CommitmentsHash is nil.
+func NewEncoderFromSource(source ChunkSource, numChunks int) *Encoder {
+	return &Encoder{source: source, numChunks: numChunks}
+}
+
+// Committer returns the serialized Committer a Decoder needs to verify the
+// Encoder's chunks.
+func (e *Encoder) Committer() ([]byte, error) {
+	if e.committer == nil {
+		return nil, errors.New("encoder has no committer")
+	}
+	return e.committer.Serialize()
+}
+
+// NumChunks returns the number of chunks the data is split into.
+func (e *Encoder) NumChunks() int {
+	return e.numChunks
+}
+
+// NextChunk returns a new random combination of the chunks.
+func (e *Encoder) NextChunk() ([]byte, error) {
+	return e.source.ChunkToSend()
+}
+
+// CommitmentsHash returns the hash of the commitments every chunk of the
+// Encoder carries.
+func (e *Encoder) CommitmentsHash() []byte {
+	return bytes.Clone(e.hash)
+}
+
+// Close frees the Encoder. Calling it again does nothing.
+func (e *Encoder) Close() {
+	if e.closed {
+		return
+	}
+	e.closed = true
+	e.source.Close()
+	if e.committer != nil {
+		e.committer.Close()
+	}
+}
+
+// Decoder reassembles the data of an Encoder from its chunks.
+type Decoder struct {
+	r         *RLNC
+	committer *Committer
+	sink      ChunkSink
+	hash      []byte
+	// chunkLen is the length of the chunks expected by a Decoder from
+	// NewDecoderForBlock, or 0.
+	chunkLen int
+	closed   bool
+}
+
+// NewDecoder creates a Decoder for the chunks of an Encoder, given its
+// serialized Committer and number of chunks.
+func NewDecoder(r *RLNC, committer []byte, numChunks int) (*Decoder, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	c, err := DeserializeCommitter(r, committer)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := c.NewNodePadded(numChunks)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &Decoder{r: r, committer: c, sink: sink}, nil
+}
+
+// NewDecoderForSink returns a Decoder adding chunks to sink, such as a fake
+// ChunkSink in tests. The Decoder closes sink. It leaves checking that chunks
+// belong to the same block to sink.
+func NewDecoderForSink(sink ChunkSink) *Decoder {
+	return &Decoder{sink: sink}
+}
+
+// Add adds a chunk and reports whether the Decoder now has all the data.
+// Chunks that add no new information are ignored. A chunk carrying other
+// commitments than the first one added is refused with a *BlockMismatchError,
+// which matches ErrCommitmentMismatch too.
+func (d *Decoder) Add(chunk []byte) (done bool, err error) {
+	if len(chunk) == 0 {
+		return false, ErrEmptyChunk
+	}
+	if d.chunkLen != 0 && len(chunk) != d.chunkLen {
+		return false, fmt.Errorf("%w: chunk is %d bytes, expected %d", ErrChunkMismatch, len(chunk), d.chunkLen)
+	}
+	var hash []byte
+	if d.r != nil {
+		var err error
+		if hash, err = d.r.CommitmentsHash(chunk); err != nil {
+			return false, err
+		}
+		if d.hash != nil && !bytes.Equal(hash, d.hash) {
+			return false, &BlockMismatchError{Node: d.hash[:blockMismatchPrefix], Chunk: hash[:blockMismatchPrefix], Err: ErrCommitmentMismatch}
+		}
+	}
+	if _, err := d.sink.ReceiveChunk(chunk); err != nil {
+		return false, err
+	}
+	// Only a chunk that verified pins the commitments.
+	d.hash = hash
+	return d.sink.IsFull(), nil
+}
+
+// Bytes returns the decoded data once Add has reported done.
+func (d *Decoder) Bytes() ([]byte, error) {
+	return d.sink.Data()
+}
+
+// Close frees the Decoder. Calling it again does nothing.
+func (d *Decoder) Close() {
+	if d.closed {
+		return
+	}
+	d.closed = true
+	d.sink.Close()
+	if d.committer != nil {
+		d.committer.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	for _, size := range []int{0, 1, 31, 32*numChunks - 8, 32 * numChunks, 10000} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		encoder, err := NewEncoder(rlnc, data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating encoder: %v", err)
+		}
+		defer encoder.Close()
+		committer, err := encoder.Committer()
+		if err != nil {
+			t.Fatalf("Error serializing committer: %v", err)
+		}
+		decoder, err := NewDecoder(rlnc, committer, encoder.NumChunks())
+		if err != nil {
+			t.Fatalf("Error creating decoder: %v", err)
+		}
+		defer decoder.Close()
+
+		for done := false; !done; {
+			chunk, err := encoder.NextChunk()
+			if err != nil {
+				t.Fatalf("Error getting chunk: %v", err)
+			}
+			if done, err = decoder.Add(chunk); err != nil {
+				t.Fatalf("Error adding chunk: %v", err)
+			}
+		}
+		got, err := decoder.Bytes()
+		if err != nil {
+			t.Fatalf("Error decoding %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("Decoded %d bytes don't match", size)
+		}
+
+		encoder.Close()
+		decoder.Close()
+	}
+}
+
+func TestDecoderRefusesOtherCommitments(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	data := make([]byte, 1000)
+	rand.Read(data)
+	encoder, err := NewEncoder(rlnc, data, 4)
+	if err != nil {
+		t.Fatalf("Error creating encoder: %v", err)
+	}
+	defer encoder.Close()
+	// Committers of the same size are identical, so only other data gives
+	// other commitments.
+	otherData := make([]byte, len(data))
+	rand.Read(otherData)
+	other, err := NewEncoder(rlnc, otherData, 4)
+	if err != nil {
+		t.Fatalf("Error creating encoder: %v", err)
+	}
+	defer other.Close()
+
+	committer, err := encoder.Committer()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+	decoder, err := NewDecoder(rlnc, committer, 4)
+	if err != nil {
+		t.Fatalf("Error creating decoder: %v", err)
+	}
+	defer decoder.Close()
+
+	chunk, err := encoder.NextChunk()
+	if err != nil {
+		t.Fatalf("Error getting chunk: %v", err)
+	}
+	if _, err := decoder.Add(chunk); err != nil {
+		t.Fatalf("Error adding chunk: %v", err)
+	}
+	chunk, err = other.NextChunk()
+	if err != nil {
+		t.Fatalf("Error getting chunk: %v", err)
+	}
+	if _, err := decoder.Add(chunk); !errors.Is(err, ErrCommitmentMismatch) || !errors.Is(err, ErrBlockMismatch) {
+		t.Fatalf("Expected ErrBlockMismatch, got %v", err)
+	}
+}
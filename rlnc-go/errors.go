@@ -0,0 +1,186 @@
+package rlnc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Errors returned by Node methods. They are wrapped together with the code
+// the library returned, so match them with errors.Is.
+var (
+	ErrReceiveFailed      = errors.New("failed to receive chunk")
+	ErrCommitmentMismatch = errors.New("existing commitments mismatch")
+	ErrChunkMismatch      = errors.New("existing chunks mismatch")
+	ErrInvalidMessage     = errors.New("invalid message")
+	ErrLinearlyDependent  = errors.New("linearly dependent chunk")
+	ErrNoCommitments      = errors.New("node holds no commitments")
+	ErrUnknown            = errors.New("unknown error")
+	ErrSendFailed         = errors.New("failed to get chunk")
+	ErrNoChunksHeld       = errors.New("node holds no chunks to send")
+	ErrDecodeFailed       = errors.New("failed to get data")
+	ErrMalformedChunk     = errors.New("malformed chunk")
+	ErrEmptyChunk         = errors.New("empty chunk")
+)
+
+// ErrClosed is returned by methods called on an RLNC, Committer or Node
+// after its Close.
+var ErrClosed = errors.New("use of closed handle")
+
+// ErrInvalidCommitter is returned by DeserializeCommitter and
+// Committer.UnmarshalBinary for bytes that aren't a serialized Committer.
+var ErrInvalidCommitter = errors.New("invalid serialized committer")
+
+// ErrNotReady is matched by the NotReadyError that Node.Data and the other
+// decoding methods return before the node holds enough chunks, where
+// ErrDecodeFailed means decoding failed for good.
+var ErrNotReady = errors.New("node doesn't hold enough chunks to decode")
+
+// ErrDecodeVerificationFailed is returned by Node.VerifiedData when the
+// decoded block doesn't match the commitments it was decoded under.
+var ErrDecodeVerificationFailed = errors.New("decoded data doesn't match the commitments")
+
+// ErrVerificationFailed is returned for a chunk whose payload doesn't match
+// its coefficients and commitments, which means it was modified after it was
+// sent. Such errors match ErrInvalidMessage too, which the library reported
+// them as before.
+var ErrVerificationFailed = errors.New("chunk doesn't match its commitments")
+
+// ErrBlockMismatch is matched by the BlockMismatchError a Node returns for a
+// chunk of another block than the one it decodes.
+var ErrBlockMismatch = errors.New("chunk is for another block")
+
+// receiveError maps a receive_chunk result to an error.
+func receiveError(code int32) error {
+	var err error
+	switch code {
+	case 0:
+		return nil
+	case -1:
+		err = ErrReceiveFailed
+	case -2:
+		err = ErrCommitmentMismatch
+	case -3:
+		err = ErrChunkMismatch
+	case -4:
+		err = ErrInvalidMessage
+	case -5:
+		err = ErrLinearlyDependent
+	case -6:
+		err = ErrNoCommitments
+	case -7:
+		return fmt.Errorf("%w: %w (code %d)", ErrVerificationFailed, ErrInvalidMessage, code)
+	default:
+		err = ErrUnknown
+	}
+	return codeError(err, code)
+}
+
+// verifyError maps a verify_chunk result to an error.
+func verifyError(code int32) error {
+	switch code {
+	case 0:
+		return nil
+	case -1:
+		return codeError(ErrMalformedChunk, code)
+	default:
+		return receiveError(code)
+	}
+}
+
+func codeError(err error, code int32) error {
+	return fmt.Errorf("%w (code %d)", err, code)
+}
+
+// ShortBufferError is returned by methods writing into a caller's buffer when
+// it is too small. It matches io.ErrShortBuffer with errors.Is.
+type ShortBufferError struct {
+	// Need is the size the buffer needs to be.
+	Need int
+}
+
+func (e *ShortBufferError) Error() string {
+	return fmt.Sprintf("%v: need %d bytes", io.ErrShortBuffer, e.Need)
+}
+
+func (e *ShortBufferError) Is(target error) bool {
+	return target == io.ErrShortBuffer
+}
+
+// NumChunksError is returned when a Node receives a chunk of a block split
+// into a different number of chunks than the Node was created for. It matches
+// ErrChunkMismatch with errors.Is.
+type NumChunksError struct {
+	// Node is the number of chunks the Node decodes and Chunk the number the
+	// chunk has coefficients for.
+	Node  int
+	Chunk uint64
+}
+
+func (e *NumChunksError) Error() string {
+	return fmt.Sprintf("node configured for %d chunks, chunk encodes %d", e.Node, e.Chunk)
+}
+
+func (e *NumChunksError) Is(target error) bool {
+	return target == ErrChunkMismatch
+}
+
+// BlockMismatchError is returned when a Node or Decoder receives a chunk
+// whose commitments differ from the ones of the block it took from its first
+// chunk, or that is sized differently, which means the chunk was routed to the
+// wrong Node rather than corrupted. It matches ErrBlockMismatch with errors.Is, and
+// unwraps to the library's error.
+type BlockMismatchError struct {
+	// Node and Chunk are the first bytes of the commitments hash of the
+	// Node's block and of the chunk, as CommitmentsHash returns them.
+	Node  []byte
+	Chunk []byte
+	// Err is the library's error, which matches ErrCommitmentMismatch or
+	// ErrChunkMismatch.
+	Err error
+}
+
+// blockMismatchPrefix is the number of bytes of each hash a
+// BlockMismatchError carries.
+const blockMismatchPrefix = 4
+
+func (e *BlockMismatchError) Error() string {
+	return fmt.Sprintf("%v: node holds block %x, chunk is for block %x: %v", ErrBlockMismatch, e.Node, e.Chunk, e.Err)
+}
+
+func (e *BlockMismatchError) Is(target error) bool {
+	return target == ErrBlockMismatch
+}
+
+func (e *BlockMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// NotReadyError is returned when decoding a Node that isn't full yet, so it
+// needs more chunks. It matches ErrNotReady with errors.Is.
+type NotReadyError struct {
+	// Rank is the number of linearly independent chunks the Node holds and
+	// NumChunks the number it needs to decode.
+	Rank      int
+	NumChunks int
+}
+
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("%v: holds %d of %d chunks", ErrNotReady, e.Rank, e.NumChunks)
+}
+
+func (e *NotReadyError) Is(target error) bool {
+	return target == ErrNotReady
+}
+
+// MissingSymbolsError is returned by NewRLNC when the library doesn't export
+// every function the bindings need, usually because it is older than them.
+type MissingSymbolsError struct {
+	Path    string
+	Symbols []string
+}
+
+func (e *MissingSymbolsError) Error() string {
+	return fmt.Sprintf("library at %s is missing symbols: %s", e.Path, strings.Join(e.Symbols, ", "))
+}
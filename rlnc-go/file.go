@@ -0,0 +1,274 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ManifestName is the name of the manifest EncodeFile writes next to the
+// chunk files.
+const ManifestName = "manifest.json"
+
+// maxFileChunkSize is the largest chunk EncodeFile uses, 1024 scalars. Larger
+// files are split into generations of numChunks chunks coded separately, so
+// neither the committer nor a generation grows with the file.
+const maxFileChunkSize = 16 * 63 * 32
+
+// fileManifest describes the chunk files of an encoded file.
+type fileManifest struct {
+	Size      int64 `json:"size"`
+	NumChunks int   `json:"num_chunks"`
+	ChunkSize int   `json:"chunk_size"`
+	// Hashes holds the commitments hash of each generation.
+	Hashes [][]byte `json:"hashes"`
+}
+
+// check checks a manifest read from disk, which may have been tampered with,
+// before anything is derived from its fields. EncodeFile never writes chunks
+// larger than maxFileChunkSize, so the sizes derived from a manifest that
+// passes are small and can't overflow.
+func (m *fileManifest) check() error {
+	if err := checkNumChunks(m.NumChunks); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.ChunkSize <= 0 || m.ChunkSize > maxFileChunkSize || m.ChunkSize%32 != 0 {
+		return fmt.Errorf("invalid manifest: chunk size %d isn't a positive multiple of 32 up to %d", m.ChunkSize, maxFileChunkSize)
+	}
+	if m.Size < 0 {
+		return fmt.Errorf("invalid manifest: negative size %d", m.Size)
+	}
+	if want := max((m.Size+m.generationSize()-1)/m.generationSize(), 1); int64(len(m.Hashes)) != want {
+		return fmt.Errorf("invalid manifest: %d hashes for %d generations", len(m.Hashes), want)
+	}
+	return nil
+}
+
+func (m *fileManifest) generationSize() int64 {
+	return int64(m.NumChunks) * int64(m.ChunkSize)
+}
+
+func (m *fileManifest) wireChunkSize() int64 {
+	return int64(chunkWireSize(m.ChunkSize, m.NumChunks))
+}
+
+// EncodeFile codes the file at path into numChunks+extra chunk files in
+// outDir, any numChunks of which DecodeFile can reconstruct the file from,
+// and writes a manifest with the size of the file and the commitments hash of
+// each generation next to them. Files larger than numChunks chunks of 32KB
+// are coded a generation at a time, each chunk file holding one chunk of
+// every generation, so the file is never held in memory whole. It returns
+// the paths of the chunk files.
+func EncodeFile(path string, numChunks, extra int, outDir string) ([]string, error) {
+	if err := checkNumChunks(numChunks); err != nil {
+		return nil, err
+	}
+	if extra < 0 {
+		return nil, fmt.Errorf("extra chunks must not be negative, got %d", extra)
+	}
+	r, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &fileManifest{Size: info.Size(), NumChunks: numChunks}
+	perChunk := (m.Size + int64(numChunks) - 1) / int64(numChunks)
+	m.ChunkSize = int(max(min((perChunk+31)/32*32, maxFileChunkSize), 32))
+	committer, err := r.GenCommitterForChunkSize(m.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer committer.Close()
+
+	paths := make([]string, numChunks+extra)
+	outs := make([]*os.File, len(paths))
+	defer func() {
+		for _, out := range outs {
+			if out != nil {
+				out.Close()
+			}
+		}
+	}()
+	for i := range paths {
+		paths[i] = filepath.Join(outDir, fmt.Sprintf("chunk-%04d", i))
+		if outs[i], err = os.Create(paths[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	generation := make([]byte, m.generationSize())
+	for read := int64(0); read < m.Size || len(m.Hashes) == 0; read += int64(len(generation)) {
+		n, err := io.ReadFull(in, generation)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			clear(generation[n:])
+		} else if err != nil {
+			return nil, err
+		}
+		hash, err := encodeGeneration(committer, generation, numChunks, outs)
+		if err != nil {
+			return nil, fmt.Errorf("encoding generation %d: %w", len(m.Hashes), err)
+		}
+		m.Hashes = append(m.Hashes, hash)
+	}
+	for i, out := range outs {
+		outs[i] = nil
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, ManifestName), manifest, 0o644); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// encodeGeneration writes a coded chunk of generation to each of outs and
+// returns the generation's commitments hash.
+func encodeGeneration(committer *Committer, generation []byte, numChunks int, outs []*os.File) ([]byte, error) {
+	sourceNode, err := committer.NewSourceNode(generation, numChunks)
+	if err != nil {
+		return nil, err
+	}
+	defer sourceNode.Close()
+	for _, out := range outs {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := out.Write(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return sourceNode.CommitmentsHash()
+}
+
+// DecodeFile reconstructs a file encoded by EncodeFile into outPath from its
+// chunk files, reading the manifest next to the first of them. A chunk file
+// is rejected as soon as one of its chunks doesn't verify or belongs to
+// another file, and the rest of the file is decoded without it. DecodeFile
+// returns the paths of the rejected chunk files, and an error if too few
+// valid ones were left to decode every generation.
+func DecodeFile(chunkPaths []string, outPath string) (rejected []string, err error) {
+	if len(chunkPaths) == 0 {
+		return nil, errors.New("no chunk files given")
+	}
+	r, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := os.ReadFile(filepath.Join(filepath.Dir(chunkPaths[0]), ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m fileManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	committer, err := r.GenCommitterForChunkSize(m.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer committer.Close()
+
+	ins := make([]*os.File, len(chunkPaths))
+	defer func() {
+		for _, in := range ins {
+			if in != nil {
+				in.Close()
+			}
+		}
+	}()
+	for i, path := range chunkPaths {
+		if ins[i], err = os.Open(path); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(outPath)
+		}
+	}()
+
+	chunk := make([]byte, m.wireChunkSize())
+	for g, hash := range m.Hashes {
+		node, err := committer.NewNode(m.NumChunks)
+		if err != nil {
+			return rejected, err
+		}
+		for i := 0; i < len(ins) && !node.IsFull(); i++ {
+			if ins[i] == nil {
+				continue
+			}
+			_, err := ins[i].ReadAt(chunk, int64(g)*int64(len(chunk)))
+			if err == nil {
+				err = receiveFileChunk(node, chunk, hash)
+			}
+			if err != nil {
+				ins[i].Close()
+				ins[i] = nil
+				rejected = append(rejected, chunkPaths[i])
+			}
+		}
+		if !node.IsFull() {
+			rank := node.Rank()
+			node.Close()
+			return rejected, fmt.Errorf("generation %d: only %d of %d chunks are valid", g, rank, m.NumChunks)
+		}
+		data, err := node.Data()
+		node.Close()
+		if err != nil {
+			return rejected, fmt.Errorf("generation %d: %w", g, err)
+		}
+		if left := m.Size - int64(g)*m.generationSize(); left < int64(len(data)) {
+			data = data[:left]
+		}
+		if _, err := out.Write(data); err != nil {
+			return rejected, err
+		}
+	}
+	return rejected, nil
+}
+
+// receiveFileChunk receives chunk into node, unless it was committed to under
+// another hash than the generation's.
+func receiveFileChunk(node *Node, chunk, hash []byte) error {
+	commitments, err := CommitmentsOf(chunk)
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(commitments); !bytes.Equal(sum[:], hash) {
+		return ErrCommitmentMismatch
+	}
+	_, err = node.ReceiveChunk(chunk)
+	return err
+}
@@ -0,0 +1,153 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeFile(t *testing.T) {
+	numChunks, extra := 4, 3
+	for _, size := range []int{0, 1000, 3*numChunks*maxFileChunkSize + 12345} {
+		dir := t.TempDir()
+		data := make([]byte, size)
+		rand.Read(data)
+		path := filepath.Join(dir, "file")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Error writing file: %v", err)
+		}
+		outDir := filepath.Join(dir, "chunks")
+		if err := os.Mkdir(outDir, 0o755); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		chunkPaths, err := EncodeFile(path, numChunks, extra, outDir)
+		if err != nil {
+			t.Fatalf("Error encoding file: %v", err)
+		}
+		if len(chunkPaths) != numChunks+extra {
+			t.Fatalf("Expected %d chunk files, got %d", numChunks+extra, len(chunkPaths))
+		}
+
+		// Any numChunks of the chunk files will do.
+		for range 3 {
+			subset := slices.Clone(chunkPaths)
+			for len(subset) > numChunks {
+				var b [1]byte
+				rand.Read(b[:])
+				i := int(b[0]) % len(subset)
+				subset = slices.Delete(subset, i, i+1)
+			}
+			outPath := filepath.Join(dir, "decoded")
+			rejected, err := DecodeFile(subset, outPath)
+			if err != nil || len(rejected) != 0 {
+				t.Fatalf("Error decoding %d byte file: %v, rejected %v", size, err, rejected)
+			}
+			got, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("Error reading decoded file: %v", err)
+			}
+			if !bytes.Equal(data, got) {
+				t.Fatalf("Decoded %d byte file doesn't match", size)
+			}
+		}
+	}
+}
+
+func TestDecodeFileRejects(t *testing.T) {
+	dir := t.TempDir()
+	numChunks := 3
+	data := make([]byte, 2*numChunks*maxFileChunkSize)
+	rand.Read(data)
+	path := filepath.Join(dir, "file")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	chunkPaths, err := EncodeFile(path, numChunks, 2, dir)
+	if err != nil {
+		t.Fatalf("Error encoding file: %v", err)
+	}
+
+	// Corrupt the second generation of one chunk file and truncate another.
+	corrupted, err := os.ReadFile(chunkPaths[0])
+	if err != nil {
+		t.Fatalf("Error reading chunk file: %v", err)
+	}
+	corrupted[len(corrupted)/2+8] ^= 1
+	if err := os.WriteFile(chunkPaths[0], corrupted, 0o644); err != nil {
+		t.Fatalf("Error writing chunk file: %v", err)
+	}
+	if err := os.Truncate(chunkPaths[1], 100); err != nil {
+		t.Fatalf("Error truncating chunk file: %v", err)
+	}
+
+	outPath := filepath.Join(dir, "decoded")
+	rejected, err := DecodeFile(chunkPaths, outPath)
+	if err != nil {
+		t.Fatalf("Error decoding file: %v", err)
+	}
+	slices.Sort(rejected)
+	if !slices.Equal(rejected, chunkPaths[:2]) {
+		t.Fatalf("Expected %v to be rejected, got %v", chunkPaths[:2], rejected)
+	}
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("Error reading decoded file: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded file doesn't match")
+	}
+
+	// Without a spare chunk file nothing is written.
+	os.Remove(outPath)
+	_, err = DecodeFile(chunkPaths[:numChunks], outPath)
+	if err == nil {
+		t.Fatalf("Expected an error decoding from too few valid chunk files")
+	}
+	// The first generation of the corrupted file is fine, the truncated one
+	// is the only one short.
+	if want := fmt.Sprintf("only %d of %d chunks are valid", numChunks-1, numChunks); !strings.Contains(err.Error(), want) {
+		t.Fatalf("Expected %q in the error, got %v", want, err)
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected no decoded file to be left behind, got %v", err)
+	}
+}
+
+func TestDecodeFileMalformedManifest(t *testing.T) {
+	dir := t.TempDir()
+	chunkPath := filepath.Join(dir, "chunk-0000")
+	if err := os.WriteFile(chunkPath, make([]byte, 1024), 0o644); err != nil {
+		t.Fatalf("Error writing chunk file: %v", err)
+	}
+	hash := `"` + strings.Repeat("A", 43) + `="`
+	for name, manifest := range map[string]string{
+		"not JSON":              `{`,
+		"zero chunks":           `{"size": 64, "num_chunks": 0, "chunk_size": 32, "hashes": [` + hash + `]}`,
+		"too many chunks":       `{"size": 64, "num_chunks": 1000000, "chunk_size": 32, "hashes": [` + hash + `]}`,
+		"zero chunk size":       `{"size": 64, "num_chunks": 2, "chunk_size": 0, "hashes": [` + hash + `]}`,
+		"negative chunk size":   `{"size": 64, "num_chunks": 2, "chunk_size": -32, "hashes": [` + hash + `]}`,
+		"odd chunk size":        `{"size": 64, "num_chunks": 2, "chunk_size": 33, "hashes": [` + hash + `]}`,
+		"huge chunk size":       `{"size": 64, "num_chunks": 2, "chunk_size": 4611686018427387904, "hashes": [` + hash + `]}`,
+		"overflowing sizes":     `{"size": 64, "num_chunks": 65536, "chunk_size": 140737488355328, "hashes": [` + hash + `]}`,
+		"negative size":         `{"size": -1, "num_chunks": 2, "chunk_size": 32, "hashes": [` + hash + `]}`,
+		"missing hashes":        `{"size": 1000, "num_chunks": 2, "chunk_size": 32, "hashes": [` + hash + `]}`,
+		"no hashes for nothing": `{"size": 0, "num_chunks": 2, "chunk_size": 32, "hashes": []}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte(manifest), 0o644); err != nil {
+			t.Fatalf("Error writing manifest: %v", err)
+		}
+		outPath := filepath.Join(dir, "decoded")
+		_, err := DecodeFile([]string{chunkPath}, outPath)
+		if err == nil || !strings.Contains(err.Error(), "invalid manifest") {
+			t.Fatalf("Expected an invalid manifest error for %s, got %v", name, err)
+		}
+		if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+			t.Fatalf("Expected nothing to be written for %s, got %v", name, err)
+		}
+	}
+}
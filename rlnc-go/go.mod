@@ -3,3 +3,5 @@ module github.com/marcopolo/rlnc_poc/rlnc-go
 go 1.23.4
 
 require github.com/ebitengine/purego v0.8.2
+
+require github.com/tetratelabs/wazero v1.8.2
@@ -0,0 +1,199 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestChunkToSendInto(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 3)
+
+	chunkLen := sourceNode.ChunkLen()
+	var shortErr *ShortBufferError
+	if _, err := sourceNode.ChunkToSendInto(make([]byte, chunkLen-1)); !errors.As(err, &shortErr) || shortErr.Need != chunkLen {
+		t.Fatalf("Expected a ShortBufferError needing %d bytes, got %v", chunkLen, err)
+	}
+
+	buf := make([]byte, chunkLen)
+	for !destinationNode.IsFull() {
+		n, err := sourceNode.ChunkToSendInto(buf)
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if n != chunkLen {
+			t.Fatalf("Expected a %d byte chunk, got %d", chunkLen, n)
+		}
+		if _, err := destinationNode.ReceiveChunk(buf[:n]); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	if destinationNode.ChunkLen() != chunkLen {
+		t.Fatalf("Expected the destination node's chunks to be %d bytes, got %d", chunkLen, destinationNode.ChunkLen())
+	}
+	destData, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+}
+
+// BenchmarkChunkToSendInto sends chunks through buffers from a sync.Pool, as a
+// caller would, and fails if that allocates where allocFreeCalls is set and
+// the race detector is off.
+func BenchmarkChunkToSendInto(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 8
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		b.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		b.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	chunkLen := sourceNode.ChunkLen()
+	pool := sync.Pool{New: func() any {
+		buf := make([]byte, chunkLen)
+		return &buf
+	}}
+	send := func() {
+		buf := pool.Get().(*[]byte)
+		if _, err := sourceNode.ChunkToSendInto(*buf); err != nil {
+			b.Fatalf("Error getting chunk to send: %v", err)
+		}
+		pool.Put(buf)
+	}
+	if allocs := testing.AllocsPerRun(10, send); allocFreeCalls && !raceEnabled && allocs != 0 {
+		b.Fatalf("Expected no allocations, got %v", allocs)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		send()
+	}
+}
+
+func TestDataInto(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 2)
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	var shortErr *ShortBufferError
+	if _, err := destinationNode.DataInto(make([]byte, len(data)-1)); !errors.As(err, &shortErr) || shortErr.Need != len(data) || !errors.Is(err, io.ErrShortBuffer) {
+		t.Fatalf("Expected a ShortBufferError needing %d bytes, got %v", len(data), err)
+	}
+	buf := make([]byte, len(data)+10)
+	n, err := destinationNode.DataInto(buf)
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, buf[:n]) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+}
+
+// BenchmarkDataInto decodes into one buffer, allocating nothing where
+// allocFreeCalls is set.
+func BenchmarkDataInto(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 8
+	data := make([]byte, 32*256*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		b.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		b.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	buf := make([]byte, len(data))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := sourceNode.DataInto(buf); err != nil {
+			b.Fatalf("Error getting data: %v", err)
+		}
+	}
+}
+
+// intoAllocs returns the allocations of a call to into on a source node of
+// numChunks chunks of chunkSize bytes, with a buffer large enough for both
+// the block and a chunk.
+func intoAllocs(t *testing.T, chunkSize, numChunks int, into func(*Node, []byte) (int, error)) float64 {
+	t.Helper()
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+	data := make([]byte, chunkSize*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	buf := make([]byte, max(len(data), sourceNode.ChunkLen()))
+	return testing.AllocsPerRun(20, func() {
+		if _, err := into(sourceNode, buf); err != nil {
+			t.Fatalf("Error calling into the library: %v", err)
+		}
+	})
+}
+
+func TestIntoAllocs(t *testing.T) {
+	if !allocFreeCalls {
+		t.Skip("calls into the library allocate with this backend")
+	}
+	if raceEnabled {
+		t.Skip("allocations aren't counted reliably with the race detector")
+	}
+	for name, into := range map[string]func(*Node, []byte) (int, error){
+		"DataInto":        (*Node).DataInto,
+		"ChunkToSendInto": (*Node).ChunkToSendInto,
+	} {
+		for _, chunkSize := range []int{32 * 16, 32 * 1024} {
+			if allocs := intoAllocs(t, chunkSize, 8, into); allocs != 0 {
+				t.Fatalf("Expected no allocations from %s for %d byte chunks, got %v", name, chunkSize, allocs)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rlnc
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Committers and Nodes free their handle when they are garbage collected
+// without being closed, so a missed Close on some error path doesn't leak
+// the library's memory for good. Close disarms this, and stays the way to
+// free a handle promptly: the garbage collector doesn't see the library's
+// memory, so it is in no hurry to collect values holding on to lots of it.
+
+// WithLeakLog makes the RLNC report every Committer and Node garbage
+// collected without being closed to logf, such as log.Printf, with the stack
+// it was created from. Capturing the stack slows every constructor down, so
+// it's meant for tracking leaks down.
+func WithLeakLog(logf func(format string, args ...any)) Option {
+	return func(o *options) {
+		o.leakLog = logf
+	}
+}
+
+// LiveHandles returns the number of Committers and Nodes the library holds
+// that haven't been freed yet, across every RLNC sharing it, or 0 once r is
+// unloaded.
+func (r *RLNC) LiveHandles() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unloaded {
+		return 0
+	}
+	return r.liveHandles()
+}
+
+// allocSite returns the stack to report if a handle created now leaks, or ""
+// if r doesn't log leaks.
+func (r *RLNC) allocSite() string {
+	if r.leakLog == nil {
+		return ""
+	}
+	return string(debug.Stack())
+}
+
+// reportLeak logs a handle created at site that was never closed.
+func (r *RLNC) reportLeak(what, site string) {
+	if r.leakLog != nil {
+		r.leakLog("rlnc: %s garbage collected without Close, created at:\n%s", what, site)
+	}
+}
+
+// track arms the finalizer freeing c if it is dropped without Close.
+func (c *Committer) track() *Committer {
+	site := c.r.allocSite()
+	c.tracked = true
+	runtime.SetFinalizer(c, func(c *Committer) {
+		c.r.reportLeak("Committer", site)
+		c.Close()
+	})
+	return c
+}
+
+// untrack disarms the finalizer track armed, if any. The Committer
+// UnmarshalBinary fills may be part of another value, which can't have a
+// finalizer, so only tracked ones are passed to SetFinalizer.
+func (c *Committer) untrack() {
+	if c.tracked {
+		c.tracked = false
+		runtime.SetFinalizer(c, nil)
+	}
+}
+
+// track arms the finalizer freeing n if it is dropped without Close.
+func (n *Node) track() *Node {
+	site := n.r.allocSite()
+	n.tracked = true
+	runtime.SetFinalizer(n, func(n *Node) {
+		n.r.reportLeak("Node", site)
+		n.Close()
+	})
+	return n
+}
+
+// untrack disarms the finalizer track armed, if any.
+func (n *Node) untrack() {
+	if n.tracked {
+		n.tracked = false
+		runtime.SetFinalizer(n, nil)
+	}
+}
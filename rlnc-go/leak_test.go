@@ -0,0 +1,119 @@
+package rlnc
+
+import (
+	"crypto/rand"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// settleHandles collects garbage until the library's handle count stops
+// changing or reaches want, and returns it. Finalizers run in the background
+// after a collection, and a Node's Committer is only collectable once the
+// Node's finalizer ran, so it takes a few rounds.
+func settleHandles(r *RLNC, want uint64) uint64 {
+	last := r.LiveHandles()
+	deadline := time.Now().Add(5 * time.Second)
+	for stable := 0; stable < 5 && time.Now().Before(deadline); {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		got := r.LiveHandles()
+		if got == want {
+			return got
+		}
+		if got == last {
+			stable++
+		} else {
+			stable = 0
+		}
+		last = got
+	}
+	return last
+}
+
+func TestLeakedHandles(t *testing.T) {
+	var mu sync.Mutex
+	var logs []string
+	rlnc, err := NewRLNCWithOptions(WithLeakLog(func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}))
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	numChunks := 4
+	data := make([]byte, 32*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	// Leaks of earlier tests may still be waiting to be collected.
+	baseline := settleHandles(rlnc, 0)
+
+	leakedNodes, leakedCommitters := 50, 5
+	func() {
+		for range leakedNodes {
+			n, err := committer.NewSourceNode(data, numChunks)
+			if err != nil {
+				t.Fatalf("Error creating source node: %v", err)
+			}
+			if _, err := n.ChunkToSend(); err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			// Closed ones aren't freed twice or reported.
+			n, err = committer.NewNode(numChunks)
+			if err != nil {
+				t.Fatalf("Error creating node: %v", err)
+			}
+			n.Close()
+		}
+		for range leakedCommitters {
+			c, err := rlnc.GenCommitterForChunkSize(64)
+			if err != nil {
+				t.Fatalf("Error creating committer: %v", err)
+			}
+			if _, err := c.NewNode(numChunks); err != nil {
+				t.Fatalf("Error creating node: %v", err)
+			}
+		}
+	}()
+	if got, want := rlnc.LiveHandles(), baseline+uint64(2*leakedCommitters+leakedNodes); got < want {
+		t.Fatalf("Expected at least %d live handles before collecting, got %d", want, got)
+	}
+
+	if got := settleHandles(rlnc, baseline); got > baseline {
+		t.Fatalf("Expected leaked handles to be freed back to %d, got %d", baseline, got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var nodes, committers int
+	for _, l := range logs {
+		if !strings.Contains(l, "TestLeakedHandles") {
+			t.Fatalf("Expected the report to show where the handle was created, got %q", l)
+		}
+		switch {
+		case strings.HasPrefix(l, "rlnc: Node garbage collected without Close"):
+			nodes++
+		case strings.HasPrefix(l, "rlnc: Committer garbage collected without Close"):
+			committers++
+		default:
+			t.Fatalf("Unexpected leak report %q", l)
+		}
+	}
+	if nodes != leakedNodes+leakedCommitters || committers != leakedCommitters {
+		t.Fatalf("Expected %d nodes and %d committers reported, got %d and %d", leakedNodes+leakedCommitters, leakedCommitters, nodes, committers)
+	}
+
+	committer.Close()
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected freed handles not to hold the library")
+	}
+}
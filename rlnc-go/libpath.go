@@ -0,0 +1,200 @@
+//go:build !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	// extractMu serializes extraction, so concurrent NewRLNC calls never
+	// see each other's partially written files.
+	extractMu sync.Mutex
+	// extracted counts the RLNCs using each library file this process
+	// wrote, so the file can be removed when the last one is closed.
+	extracted = map[string]int{}
+	// noMemLib forces extraction to a file, for tests.
+	noMemLib bool
+)
+
+const libEmbedded = true
+
+// getLibPath returns a path the embedded library can be loaded from, loading
+// it straight from memory where the platform allows and extracting it to a
+// file otherwise. If every candidate directory fails, the error lists each
+// path tried and why. Each call must be paired with a releaseLibPath.
+func getLibPath(flavor BuildFlavor) (string, error) {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+
+	libData := embeddedLib(flavor)
+	sum := sha256.Sum256(libData)
+	name := contentAddressedName(libName, sum)
+
+	if !noMemLib {
+		if path, ok := memLibPath(name, libData); ok {
+			return path, nil
+		}
+	}
+
+	var errs []error
+	for _, dir := range extractDirs() {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// Anyone else able to write to dir could swap the library between
+		// the hash check below and dlopen.
+		if err := checkPrivateDir(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !dirAllowsExec(dir) {
+			errs = append(errs, fmt.Errorf("%s is mounted noexec", dir))
+			continue
+		}
+
+		// The directory may be shared with other users and earlier runs, so
+		// whatever is at tempPath is only reused if it hashes to the embedded
+		// library. This is checked on every call since the file may change
+		// after we wrote it.
+		tempPath := filepath.Join(dir, name)
+		written := false
+		if !fileHasHash(tempPath, sum) {
+			if err := writeFileAtomic(tempPath, libData); err != nil {
+				errs = append(errs, fmt.Errorf("writing %s: %w", tempPath, err))
+				continue
+			}
+			written = true
+		}
+
+		// Only files this process wrote are ours to remove later.
+		if users, ok := extracted[tempPath]; ok || written {
+			extracted[tempPath] = users + 1
+		}
+		return tempPath, nil
+	}
+	return "", fmt.Errorf("failed to extract library: %w", errors.Join(errs...))
+}
+
+// releaseLibPath removes the file at path once no RLNC uses it anymore, if
+// this process extracted it.
+func releaseLibPath(path string) {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+
+	users, ok := extracted[path]
+	if !ok {
+		return
+	}
+	if users > 1 {
+		extracted[path] = users - 1
+		return
+	}
+	os.Remove(path)
+	delete(extracted, path)
+}
+
+func cleanupLibs() error {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+
+	var errs []error
+	for path := range extracted {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		delete(extracted, path)
+	}
+	return errors.Join(errs...)
+}
+
+// extractDirs returns the directories to try extracting the library into, in
+// order of preference. The user cache dir comes first since tmp cleaners like
+// systemd-tmpfiles delete files from under long-running processes. The temp
+// dir is shared with other users, so the library goes into a directory of
+// this user's below it.
+func extractDirs() []string {
+	var dirs []string
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, filepath.Join(cacheDir, "rlnc-go", BindingsVersion))
+	}
+	return append(dirs, privateTempDir())
+}
+
+func embeddedLib(flavor BuildFlavor) []byte {
+	if flavor == FlavorDebug {
+		return debugLib
+	}
+	return releaseLib
+}
+
+// checkEmbeddedLib reports an error if the library for this platform wasn't
+// built before it was embedded, rather than letting dlopen fail cryptically.
+func checkEmbeddedLib(flavor BuildFlavor) error {
+	if err := checkLibc(flavor); err != nil {
+		return err
+	}
+	if len(embeddedLib(flavor)) == 0 {
+		return fmt.Errorf("embedded %s library for %s/%s is empty; run go generate to build it", flavor, runtime.GOOS, runtime.GOARCH)
+	}
+	return nil
+}
+
+// contentAddressedName inserts a prefix of sum into name, so binaries
+// embedding different builds of the library don't clobber each other's file.
+func contentAddressedName(name string, sum [sha256.Size]byte) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + hex.EncodeToString(sum[:8]) + ext
+}
+
+func fileHasHash(path string, sum [sha256.Size]byte) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return bytes.Equal(h.Sum(nil), sum[:])
+}
+
+// writeFileAtomic writes data next to path and renames it into place. The
+// library may already be mapped by this or another process, and truncating a
+// mapped file out from under it crashes that process.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), libFileMode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func loadErrHint(libPath string) string {
+	return ""
+}
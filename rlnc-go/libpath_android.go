@@ -0,0 +1,28 @@
+//go:build android && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import "syscall"
+
+// Android refuses to dlopen writable files, so the extracted library is
+// made read-only.
+const libFileMode = 0555
+
+const libName = "librlnc_poc.so"
+
+// stNoExec is ST_NOEXEC from statfs(2).
+const stNoExec = 0x8
+
+// dirAllowsExec reports whether dlopen can map files from dir. The app
+// sandbox mounts the generic temp dir noexec.
+func dirAllowsExec(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return true
+	}
+	return st.Flags&stNoExec == 0
+}
+
+func checkLibc(flavor BuildFlavor) error {
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build android && arm64 && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import _ "embed"
+
+//go:generate sh -c "cargo build --release --target aarch64-linux-android && cargo build --target aarch64-linux-android && cp ../target/aarch64-linux-android/release/librlnc_poc.so rust-lib/release/android_arm64/librlnc_poc.so && cp ../target/aarch64-linux-android/debug/librlnc_poc.so rust-lib/debug/android_arm64/librlnc_poc.so"
+
+//go:embed rust-lib/release/android_arm64/librlnc_poc.so
+var releaseLib []byte
+
+//go:embed rust-lib/debug/android_arm64/librlnc_poc.so
+var debugLib []byte
@@ -0,0 +1,13 @@
+//go:build darwin && amd64 && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import _ "embed"
+
+//go:generate sh -c "cargo build --release --target x86_64-apple-darwin && cargo build --target x86_64-apple-darwin && cp ../target/x86_64-apple-darwin/release/librlnc_poc.dylib rust-lib/release/darwin_amd64/librlnc_poc.dylib && cp ../target/x86_64-apple-darwin/debug/librlnc_poc.dylib rust-lib/debug/darwin_amd64/librlnc_poc.dylib"
+
+//go:embed rust-lib/release/darwin_amd64/librlnc_poc.dylib
+var releaseLib []byte
+
+//go:embed rust-lib/debug/darwin_amd64/librlnc_poc.dylib
+var debugLib []byte
@@ -0,0 +1,13 @@
+//go:build darwin && arm64 && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import _ "embed"
+
+//go:generate sh -c "cargo build --release --target aarch64-apple-darwin && cargo build --target aarch64-apple-darwin && cp ../target/aarch64-apple-darwin/release/librlnc_poc.dylib rust-lib/release/darwin_arm64/librlnc_poc.dylib && cp ../target/aarch64-apple-darwin/debug/librlnc_poc.dylib rust-lib/debug/darwin_arm64/librlnc_poc.dylib"
+
+//go:embed rust-lib/release/darwin_arm64/librlnc_poc.dylib
+var releaseLib []byte
+
+//go:embed rust-lib/debug/darwin_arm64/librlnc_poc.dylib
+var debugLib []byte
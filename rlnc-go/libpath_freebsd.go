@@ -0,0 +1,26 @@
+//go:build freebsd && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import "syscall"
+
+const libFileMode = 0755
+
+const libName = "librlnc_poc.so"
+
+// mntNoExec is MNT_NOEXEC from sys/mount.h.
+const mntNoExec = 0x4
+
+// dirAllowsExec reports whether dlopen can map files from dir, which it can't
+// on a noexec mount.
+func dirAllowsExec(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return true
+	}
+	return st.Flags&mntNoExec == 0
+}
+
+func checkLibc(flavor BuildFlavor) error {
+	return nil
+}
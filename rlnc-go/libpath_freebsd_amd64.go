@@ -0,0 +1,13 @@
+//go:build freebsd && amd64 && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import _ "embed"
+
+//go:generate sh -c "cargo build --release --target x86_64-unknown-freebsd && cargo build --target x86_64-unknown-freebsd && cp ../target/x86_64-unknown-freebsd/release/librlnc_poc.so rust-lib/release/freebsd_amd64/librlnc_poc.so && cp ../target/x86_64-unknown-freebsd/debug/librlnc_poc.so rust-lib/debug/freebsd_amd64/librlnc_poc.so"
+
+//go:embed rust-lib/release/freebsd_amd64/librlnc_poc.so
+var releaseLib []byte
+
+//go:embed rust-lib/debug/freebsd_amd64/librlnc_poc.so
+var debugLib []byte
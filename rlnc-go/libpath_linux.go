@@ -0,0 +1,57 @@
+//go:build linux && !android && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+//go:generate sh -c "cargo build --release && cargo build && cp ../target/release/librlnc_poc.so rust-lib/release/librlnc_poc.so && cp ../target/debug/librlnc_poc.so rust-lib/debug/librlnc_poc.so"
+
+//go:embed rust-lib/release/librlnc_poc.so
+var releaseLib []byte
+
+//go:embed rust-lib/debug/librlnc_poc.so
+var debugLib []byte
+
+// libc is the C library of the running system. The embedded library is
+// linked against glibc, and fails to dlopen on musl systems like Alpine with
+// a confusing "not found" error.
+var libc = detectLibc()
+
+func detectLibc() string {
+	if m, _ := filepath.Glob("/lib/ld-musl-*"); len(m) > 0 {
+		return "musl"
+	}
+	return "glibc"
+}
+
+// checkLibc reports an error on a musl system, which needs a musl build of
+// the library passed through WithLibraryPath or RLNC_LIB_PATH.
+func checkLibc(flavor BuildFlavor) error {
+	if libc == "musl" {
+		return fmt.Errorf("this system uses musl, but the embedded %s library for %s/%s is built for glibc; load a musl build with WithLibraryPath or %s", flavor, runtime.GOOS, runtime.GOARCH, libPathEnv)
+	}
+	return nil
+}
+
+const libFileMode = 0755
+
+const libName = "librlnc_poc.so"
+
+// stNoExec is ST_NOEXEC from statfs(2).
+const stNoExec = 0x8
+
+// dirAllowsExec reports whether dlopen can map files from dir, which it can't
+// on a noexec mount.
+func dirAllowsExec(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return true
+	}
+	return st.Flags&stNoExec == 0
+}
@@ -0,0 +1,17 @@
+//go:build linux && !android && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMuslEmbedded(t *testing.T) {
+	defer func(l string) { libc = l }(libc)
+	libc = "musl"
+	err := checkEmbeddedLib(FlavorRelease)
+	if err == nil || !strings.Contains(err.Error(), "musl") || !strings.Contains(err.Error(), libPathEnv) {
+		t.Fatalf("Expected an error naming musl and %s, got %v", libPathEnv, err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const libEmbedded = false
+
+// getLibPath returns the bare library name so the system loader resolves it
+// via LD_LIBRARY_PATH/DYLD_LIBRARY_PATH and the default search paths.
+func getLibPath(flavor BuildFlavor) (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "librlnc_poc.dylib", nil
+	case "windows":
+		return "rlnc_poc.dll", nil
+	default:
+		return "librlnc_poc.so", nil
+	}
+}
+
+func releaseLibPath(path string) {}
+
+func cleanupLibs() error {
+	return nil
+}
+
+func checkEmbeddedLib(flavor BuildFlavor) error {
+	return nil
+}
+
+func loadErrHint(libPath string) string {
+	return fmt.Sprintf(" (built with rlnc_nolib: %s must be installed where the system loader can find it, e.g. via LD_LIBRARY_PATH or DYLD_LIBRARY_PATH)", libPath)
+}
@@ -0,0 +1,226 @@
+//go:build !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestContentAddressedName(t *testing.T) {
+	a := contentAddressedName("librlnc_poc.so", sha256.Sum256([]byte("a")))
+	b := contentAddressedName("librlnc_poc.so", sha256.Sum256([]byte("b")))
+	if a == b {
+		t.Fatalf("Different contents produced the same name %s", a)
+	}
+	if !strings.HasPrefix(a, "librlnc_poc-") || !strings.HasSuffix(a, ".so") {
+		t.Fatalf("Unexpected name %s", a)
+	}
+}
+
+func TestCorruptedLibraryIsReextracted(t *testing.T) {
+	forceFileExtraction(t)
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	// Replace rather than overwrite the file, since it may be mapped already.
+	if err := writeFileAtomic(libPath, []byte("corrupted")); err != nil {
+		t.Fatalf("Error corrupting library: %v", err)
+	}
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	if !fileHasHash(libPath, sha256.Sum256(releaseLib)) {
+		t.Fatalf("Library at %s was not re-extracted", libPath)
+	}
+}
+
+func TestEmptyEmbeddedLibrary(t *testing.T) {
+	saved := releaseLib
+	releaseLib = nil
+	defer func() { releaseLib = saved }()
+
+	_, err := NewRLNC()
+	if err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Fatalf("Expected empty library error, got %v", err)
+	}
+}
+
+func TestExtractToCacheDir(t *testing.T) {
+	forceFileExtraction(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("cache dir is not configured through the environment on windows")
+	}
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("HOME", cacheDir)
+
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	if !strings.HasPrefix(libPath, cacheDir) {
+		t.Fatalf("Expected library under %s, got %s", cacheDir, libPath)
+	}
+}
+
+func TestExtractFallsBackToTempDir(t *testing.T) {
+	forceFileExtraction(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("cache dir is not configured through the environment on windows")
+	}
+	// A cache dir below a regular file can't be created, even by root.
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatalf("Error creating file: %v", err)
+	}
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(notADir, "cache"))
+	t.Setenv("HOME", notADir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	libPath, err := getLibPath(FlavorRelease)
+	if err != nil {
+		t.Fatalf("Error extracting library: %v", err)
+	}
+	if filepath.Dir(libPath) != privateTempDir() {
+		t.Fatalf("Expected library in %s, got %s", privateTempDir(), libPath)
+	}
+	fi, err := os.Stat(privateTempDir())
+	if err != nil {
+		t.Fatalf("Error checking %s: %v", privateTempDir(), err)
+	}
+	if fi.Mode().Perm() != 0700 {
+		t.Fatalf("Expected %s to be private, got mode %v", privateTempDir(), fi.Mode())
+	}
+
+	rlnc, err := NewRLNCWithOptions(WithLibraryPath(libPath))
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	rlnc.Close()
+}
+
+func TestExtractRejectsSharedTempDir(t *testing.T) {
+	forceFileExtraction(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("the temp dir is private to the user on windows")
+	}
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatalf("Error creating file: %v", err)
+	}
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(notADir, "cache"))
+	t.Setenv("HOME", notADir)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := privateTempDir()
+
+	// Whoever else can write to the directory could replace the library
+	// after it is checked, so it isn't extracted there.
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatalf("Error making directory writable: %v", err)
+	}
+	if _, err := getLibPath(FlavorRelease); err == nil || !strings.Contains(err.Error(), "writable by other users") {
+		t.Fatalf("Expected a directory writable by others to be rejected, got %v", err)
+	}
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatalf("Error removing directory: %v", err)
+	}
+	if err := os.Symlink(t.TempDir(), dir); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+	if _, err := getLibPath(FlavorRelease); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Fatalf("Expected a symlink to be rejected, got %v", err)
+	}
+
+	if os.Getuid() == 0 {
+		if err := os.Remove(dir); err != nil {
+			t.Fatalf("Error removing symlink: %v", err)
+		}
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		if err := os.Chown(dir, 12345, 12345); err != nil {
+			t.Fatalf("Error changing owner: %v", err)
+		}
+		if _, err := getLibPath(FlavorRelease); err == nil || !strings.Contains(err.Error(), "owned by another user") {
+			t.Fatalf("Expected a directory of another user to be rejected, got %v", err)
+		}
+	}
+}
+
+func TestExtractUnwritable(t *testing.T) {
+	forceFileExtraction(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("cache dir is not configured through the environment on windows")
+	}
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatalf("Error creating file: %v", err)
+	}
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(notADir, "cache"))
+	t.Setenv("HOME", notADir)
+	t.Setenv("TMPDIR", filepath.Join(notADir, "tmp"))
+
+	_, err := NewRLNC()
+	if err == nil {
+		t.Fatalf("Expected an error extracting to an unwritable directory")
+	}
+	if !strings.Contains(err.Error(), notADir) {
+		t.Fatalf("Expected the error to name the path tried, got %v", err)
+	}
+}
+
+func forceFileExtraction(t *testing.T) {
+	noMemLib = true
+	t.Cleanup(func() { noMemLib = false })
+}
+
+func TestCloseRemovesExtractedLibrary(t *testing.T) {
+	forceFileExtraction(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	libPath := rlnc.libPath
+	if _, err := os.Stat(libPath); err != nil {
+		t.Fatalf("Library was not extracted: %v", err)
+	}
+
+	rlnc.Close()
+	if _, err := os.Stat(libPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected %s to be removed, got %v", libPath, err)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	forceFileExtraction(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	if err := Cleanup(); err != nil {
+		t.Fatalf("Error cleaning up: %v", err)
+	}
+	if _, err := os.Stat(rlnc.libPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected %s to be removed, got %v", rlnc.libPath, err)
+	}
+}
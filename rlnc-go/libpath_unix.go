@@ -0,0 +1,36 @@
+//go:build !windows && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// privateTempDir returns the directory below os.TempDir to extract the
+// library into, named after the user so each gets their own.
+func privateTempDir() string {
+	return filepath.Join(os.TempDir(), "rlnc-go-"+strconv.Itoa(os.Getuid()))
+}
+
+// checkPrivateDir returns an error unless dir is a directory, rather than a
+// symlink to one, owned by the user and writable by no one else.
+func checkPrivateDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	switch {
+	case !fi.IsDir():
+		return fmt.Errorf("%s is not a directory", dir)
+	case !ok || int(st.Uid) != os.Getuid():
+		return fmt.Errorf("%s is owned by another user", dir)
+	case fi.Mode().Perm()&0022 != 0:
+		return fmt.Errorf("%s is writable by other users", dir)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build windows && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+)
+
+//go:generate sh -c "cargo build --release && cargo build && cp ../target/release/rlnc_poc.dll rust-lib/release/rlnc_poc.dll && cp ../target/debug/rlnc_poc.dll rust-lib/debug/rlnc_poc.dll"
+
+//go:embed rust-lib/release/rlnc_poc.dll
+var releaseLib []byte
+
+//go:embed rust-lib/debug/rlnc_poc.dll
+var debugLib []byte
+
+const libFileMode = 0755
+
+const libName = "rlnc_poc.dll"
+
+func dirAllowsExec(dir string) bool {
+	return true
+}
+
+// privateTempDir returns the directory below os.TempDir to extract the
+// library into. The temp dir is already private to the user on Windows.
+func privateTempDir() string {
+	return filepath.Join(os.TempDir(), "rlnc-go")
+}
+
+func checkPrivateDir(dir string) error {
+	return nil
+}
+
+func checkLibc(flavor BuildFlavor) error {
+	return nil
+}
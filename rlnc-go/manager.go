@@ -0,0 +1,173 @@
+package rlnc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrTooManySessions is returned by Manager.Handle for a chunk of a new block
+// when the Manager already has as many blocks in progress as it allows.
+var ErrTooManySessions = errors.New("too many sessions in progress")
+
+// Manager decodes several blocks at once, routing each chunk to a Node for
+// its block by the hash of its commitments. Nodes are created when the first
+// valid chunk of a block arrives and freed as soon as the block is decoded.
+type Manager struct {
+	committer   CommitterAPI
+	numChunks   int
+	maxSessions int
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	// completed remembers the hashes of the last maxSessions decoded blocks,
+	// oldest first, so their late chunks don't start new sessions.
+	completed [][]byte
+	closed    bool
+}
+
+type session struct {
+	hash []byte
+	node ChunkSink
+}
+
+// Result is what Manager.Handle reports about a chunk.
+type Result struct {
+	// Hash is the hash of the commitments of the chunk's block.
+	Hash []byte
+	// Innovative is set if the chunk added information to its session.
+	Innovative bool
+	// Complete is set on the chunk that completed its block, and Data then
+	// holds the decoded block.
+	Complete bool
+	Data     []byte
+	// Stale is set if the chunk belongs to a block that was already decoded.
+	Stale bool
+}
+
+// SessionInfo describes a block in progress.
+type SessionInfo struct {
+	Hash      []byte
+	Rank      int
+	NumChunks int
+}
+
+// NewManager returns a Manager decoding blocks of numChunks chunks committed
+// to with committer, with at most maxSessions blocks in progress at a time.
+// The committer must outlive the Manager. It is usually a *Committer, but
+// any CommitterAPI will do, like a fake one in tests.
+func NewManager(committer CommitterAPI, numChunks, maxSessions int) (*Manager, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	if maxSessions <= 0 {
+		return nil, fmt.Errorf("max sessions must be positive, got %d", maxSessions)
+	}
+	return &Manager{
+		committer:   committer,
+		numChunks:   numChunks,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]*session),
+	}, nil
+}
+
+// Handle feeds chunk to the session of its block, creating it if needed.
+// Chunks that fail to verify don't create sessions, so junk can't use up
+// native memory.
+func (m *Manager) Handle(chunk []byte) (*Result, error) {
+	hash, err := m.committer.ChunkHash(chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errors.New("manager is closed")
+	}
+	res := &Result{Hash: hash}
+	if slices.ContainsFunc(m.completed, func(h []byte) bool { return bytes.Equal(h, hash) }) {
+		res.Stale = true
+		return res, nil
+	}
+
+	s, ok := m.sessions[string(hash)]
+	if !ok {
+		if len(m.sessions) >= m.maxSessions {
+			return nil, ErrTooManySessions
+		}
+		node, err := m.committer.NewSink(m.numChunks)
+		if err != nil {
+			return nil, err
+		}
+		s = &session{hash: hash, node: node}
+	}
+	res.Innovative, err = s.node.ReceiveChunk(chunk)
+	if err != nil {
+		if !ok {
+			s.node.Close()
+		}
+		return nil, err
+	}
+	if !ok {
+		m.sessions[string(hash)] = s
+	}
+	if !s.node.IsFull() {
+		return res, nil
+	}
+
+	res.Complete = true
+	res.Data, err = s.node.Data()
+	m.remove(s)
+	if len(m.completed) == m.maxSessions {
+		m.completed = m.completed[1:]
+	}
+	m.completed = append(m.completed, hash)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Sessions returns the blocks in progress.
+func (m *Manager) Sessions() []SessionInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	infos := make([]SessionInfo, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		infos = append(infos, SessionInfo{Hash: bytes.Clone(s.hash), Rank: s.node.Rank(), NumChunks: m.numChunks})
+	}
+	return infos
+}
+
+// Drop abandons the block with the given hash, freeing its Node. It reports
+// whether the block was in progress.
+func (m *Manager) Drop(hash []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[string(hash)]
+	if ok {
+		m.remove(s)
+	}
+	return ok
+}
+
+func (m *Manager) remove(s *session) {
+	delete(m.sessions, string(s.hash))
+	s.node.Close()
+}
+
+// Close frees every session in progress. Calling it again does nothing.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	for _, s := range m.sessions {
+		m.remove(s)
+	}
+}
@@ -0,0 +1,106 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	chunkSize := 32 * 64
+	committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	manager, err := NewManager(committer, numChunks, 2)
+	if err != nil {
+		t.Fatalf("Error creating manager: %v", err)
+	}
+	defer manager.Close()
+
+	var blocks [][]byte
+	var sources []*Node
+	for range 3 {
+		data := make([]byte, chunkSize*numChunks)
+		rand.Read(data)
+		sourceNode, err := committer.NewSourceNode(data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer sourceNode.Close()
+		blocks = append(blocks, data)
+		sources = append(sources, sourceNode)
+	}
+	chunkOf := func(i int) []byte {
+		chunk, err := sources[i].ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		return chunk
+	}
+
+	// Junk doesn't start a session.
+	tampered := chunkOf(0)
+	tampered[8] ^= 1
+	if _, err := manager.Handle(tampered); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for a tampered chunk, got %v", err)
+	}
+	if sessions := manager.Sessions(); len(sessions) != 0 {
+		t.Fatalf("Expected no sessions after a tampered chunk, got %+v", sessions)
+	}
+
+	// Start two blocks, filling the manager.
+	for i := range 2 {
+		res, err := manager.Handle(chunkOf(i))
+		if err != nil {
+			t.Fatalf("Error handling chunk: %v", err)
+		}
+		if !res.Innovative || res.Complete {
+			t.Fatalf("Expected an innovative chunk of an incomplete block, got %+v", res)
+		}
+	}
+	if sessions := manager.Sessions(); len(sessions) != 2 || sessions[0].Rank != 1 {
+		t.Fatalf("Expected 2 sessions of rank 1, got %+v", sessions)
+	}
+	if _, err := manager.Handle(chunkOf(2)); !errors.Is(err, ErrTooManySessions) {
+		t.Fatalf("Expected ErrTooManySessions, got %v", err)
+	}
+
+	// Completing the first block frees its session.
+	var res *Result
+	for res == nil || !res.Complete {
+		if res, err = manager.Handle(chunkOf(0)); err != nil {
+			t.Fatalf("Error handling chunk: %v", err)
+		}
+	}
+	if !bytes.Equal(res.Data, blocks[0]) {
+		t.Fatalf("Decoded block doesn't match")
+	}
+	if sessions := manager.Sessions(); len(sessions) != 1 {
+		t.Fatalf("Expected 1 session after completing a block, got %+v", sessions)
+	}
+	if res, err := manager.Handle(chunkOf(0)); err != nil || !res.Stale {
+		t.Fatalf("Expected a late chunk to be stale, got %+v, %v", res, err)
+	}
+
+	// Now there is room for the third block.
+	res, err = manager.Handle(chunkOf(2))
+	if err != nil {
+		t.Fatalf("Error handling chunk: %v", err)
+	}
+	if !manager.Drop(res.Hash) {
+		t.Fatalf("Expected to drop the third block")
+	}
+	if sessions := manager.Sessions(); len(sessions) != 1 {
+		t.Fatalf("Expected 1 session after dropping a block, got %+v", sessions)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build linux && !android && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	mfdCloexec = 0x1
+	mfdExec    = 0x10
+)
+
+// memfds keeps the memfd each library was loaded into open, by name.
+var memfds = map[string]*os.File{}
+
+// memLibPath copies libData into an anonymous memfd and returns a path dlopen
+// can load it from, so nothing is written to disk. It reports false where
+// memfd_create isn't available (before Linux 3.17), in which case the library
+// has to be extracted to a file instead.
+func memLibPath(name string, libData []byte) (string, bool) {
+	if f, ok := memfds[name]; ok {
+		return procFdPath(f), true
+	}
+
+	trap, ok := sysMemfdCreate()
+	if !ok {
+		return "", false
+	}
+	namePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return "", false
+	}
+	fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(namePtr)), mfdCloexec|mfdExec, 0)
+	if errno == syscall.EINVAL {
+		// MFD_EXEC is new in Linux 6.3; before that memfds are always
+		// executable.
+		fd, _, errno = syscall.Syscall(trap, uintptr(unsafe.Pointer(namePtr)), mfdCloexec, 0)
+	}
+	if errno != 0 {
+		return "", false
+	}
+
+	f := os.NewFile(fd, name)
+	if _, err := f.Write(libData); err != nil {
+		f.Close()
+		return "", false
+	}
+	// dlopen needs /proc to reach the memfd.
+	if _, err := os.Stat(procFdPath(f)); err != nil {
+		f.Close()
+		return "", false
+	}
+
+	memfds[name] = f
+	return procFdPath(f), true
+}
+
+func procFdPath(f *os.File) string {
+	return fmt.Sprintf("/proc/self/fd/%d", f.Fd())
+}
+
+// sysMemfdCreate returns the memfd_create syscall number, which package
+// syscall doesn't define on every architecture.
+func sysMemfdCreate() (uintptr, bool) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return 319, true
+	case "386":
+		return 356, true
+	case "arm":
+		return 385, true
+	case "arm64", "riscv64", "loong64":
+		return 279, true
+	}
+	return 0, false
+}
@@ -0,0 +1,38 @@
+//go:build linux && !android && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMemfdLoadWritesNoFiles(t *testing.T) {
+	if _, ok := memLibPath("probe", nil); !ok {
+		t.Skip("memfd_create is not available")
+	}
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	if !strings.HasPrefix(rlnc.libPath, "/proc/self/fd/") {
+		t.Fatalf("Expected the library to be loaded from a memfd, got %s", rlnc.libPath)
+	}
+	for _, dir := range []string{tmpDir, cacheDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", dir, err)
+		}
+		if len(entries) != 0 {
+			t.Fatalf("Expected no files in %s, found %v", dir, entries)
+		}
+	}
+}
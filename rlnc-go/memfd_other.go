@@ -0,0 +1,7 @@
+//go:build (!linux || android) && !rlnc_nolib && !rlnc_cgo && !rlnc_wasm
+
+package rlnc
+
+func memLibPath(name string, libData []byte) (string, bool) {
+	return "", false
+}
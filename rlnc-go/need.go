@@ -0,0 +1,104 @@
+package rlnc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// needSummaryVersion is the first byte of a need summary.
+const needSummaryVersion = 1
+
+// needSummaryHeaderSize is the size of a need summary before its pivot
+// bitmap: the version, the number of chunks and the rank.
+const needSummaryHeaderSize = 1 + 4 + 4
+
+// NeedSummary describes what n still needs, for a sender to target with
+// ChunkForNeeds: a version byte, the number of chunks and n's rank as
+// little-endian uint32s, and a bitmap with bit j set (least significant bit
+// first) if column j is a pivot of n's coefficients in echelon form. A chunk
+// that is zero on every pivot column is innovative for n, so the bitmap is
+// all a sender needs of n's basis.
+func (n *Node) NeedSummary() ([]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	var outData unsafe.Pointer
+	var outDataLen uint64
+	if res := n.r.pivotColumns(n.p, &outData, &outDataLen); res != 0 {
+		return nil, codeError(ErrUnknown, res)
+	}
+	defer n.r.freeBuffer(outData, outDataLen)
+	pivots := unsafe.Slice((*byte)(outData), int(outDataLen))
+
+	b := make([]byte, needSummaryHeaderSize+(len(pivots)+7)/8)
+	b[0] = needSummaryVersion
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(pivots)))
+	rank := 0
+	for j, p := range pivots {
+		if p != 0 {
+			b[needSummaryHeaderSize+j/8] |= 1 << (j % 8)
+			rank++
+		}
+	}
+	binary.LittleEndian.PutUint32(b[5:], uint32(rank))
+	return b, nil
+}
+
+// parseNeedSummary returns a byte per column of the summary, 1 if it is a
+// pivot, as the library takes them.
+func parseNeedSummary(b []byte) ([]byte, error) {
+	if len(b) < needSummaryHeaderSize {
+		return nil, errors.New("need summary is too short")
+	}
+	if b[0] != needSummaryVersion {
+		return nil, fmt.Errorf("unknown need summary version %d", b[0])
+	}
+	numChunks := binary.LittleEndian.Uint32(b[1:])
+	rank := binary.LittleEndian.Uint32(b[5:])
+	bitmap := b[needSummaryHeaderSize:]
+	if uint64(len(bitmap)) != (uint64(numChunks)+7)/8 {
+		return nil, fmt.Errorf("need summary has a %d byte bitmap for %d chunks", len(bitmap), numChunks)
+	}
+	pivots := make([]byte, numChunks)
+	count := 0
+	for j := range pivots {
+		pivots[j] = bitmap[j/8] >> (j % 8) & 1
+		count += int(pivots[j])
+	}
+	if uint32(count) != rank {
+		return nil, fmt.Errorf("need summary has rank %d but %d pivots", rank, count)
+	}
+	return pivots, nil
+}
+
+// ChunkForNeeds returns a chunk that is innovative for the receiver that
+// produced summary with NeedSummary: a random combination of the original
+// chunks at the receiver's non-pivot columns. Combinations for the same
+// summary are independent with overwhelming probability, so a receiver at
+// rank k decodes from the NumChunks-k chunks sent for one summary. Only a
+// source node holds the original chunks; other nodes, and receivers that
+// need nothing, get an error wrapping ErrSendFailed.
+func (n *Node) ChunkForNeeds(summary []byte) ([]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	pivots, err := parseNeedSummary(summary)
+	if err != nil {
+		return nil, err
+	}
+	if len(pivots) != n.numChunks {
+		return nil, fmt.Errorf("need summary is for %d chunks, node has %d: %w", len(pivots), n.numChunks, ErrChunkMismatch)
+	}
+	out := getOutBuffer()
+	defer outBuffers.Put(out)
+	if res := n.r.sendChunkForNeeds(n.p, pivots, uint64(len(pivots)), &out.ptr, &out.len); res != 0 {
+		return nil, codeError(ErrSendFailed, res)
+	}
+	defer n.r.freeBuffer(out.ptr, out.len)
+	return bytes.Clone(unsafe.Slice((*byte)(out.ptr), int(out.len))), nil
+}
@@ -0,0 +1,109 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestChunkForNeeds(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 8
+	block := make([]byte, numChunks*64)
+	rand.Read(block)
+	committer, err := rlnc.GenCommitterForMessage(len(block), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(block, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	for _, rank := range []int{0, 3, numChunks - 1} {
+		node, err := committer.NewNode(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer node.Close()
+		for node.Rank() < rank {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error sending chunk: %v", err)
+			}
+			if _, err := node.ReceiveChunk(chunk); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+
+		summary, err := node.NeedSummary()
+		if err != nil {
+			t.Fatalf("Error getting need summary: %v", err)
+		}
+		if len(summary) != needSummaryHeaderSize+1 {
+			t.Fatalf("Expected a %d byte summary, got %d", needSummaryHeaderSize+1, len(summary))
+		}
+		for i := rank; i < numChunks; i++ {
+			chunk, err := sourceNode.ChunkForNeeds(summary)
+			if err != nil {
+				t.Fatalf("Error sending chunk for needs: %v", err)
+			}
+			innovative, err := node.ReceiveChunk(chunk)
+			if err != nil || !innovative {
+				t.Fatalf("Expected chunk %d for a rank %d receiver to be innovative, got %v", i, rank, err)
+			}
+		}
+		if !node.IsFull() {
+			t.Fatalf("Expected the node to be full after %d targeted chunks", numChunks-rank)
+		}
+		data, err := node.Data()
+		if err != nil {
+			t.Fatalf("Error decoding: %v", err)
+		}
+		if !bytes.Equal(data, block) {
+			t.Fatalf("Decoded data doesn't match the block")
+		}
+
+		summary, err = node.NeedSummary()
+		if err != nil {
+			t.Fatalf("Error getting need summary: %v", err)
+		}
+		if _, err := sourceNode.ChunkForNeeds(summary); !errors.Is(err, ErrSendFailed) {
+			t.Fatalf("Expected ErrSendFailed for a full receiver, got %v", err)
+		}
+		if _, err := node.ChunkForNeeds(summary); !errors.Is(err, ErrSendFailed) {
+			t.Fatalf("Expected ErrSendFailed from a node without the original chunks, got %v", err)
+		}
+	}
+}
+
+func TestParseNeedSummary(t *testing.T) {
+	valid := []byte{needSummaryVersion, 10, 0, 0, 0, 2, 0, 0, 0, 0b0100_0001, 0}
+	pivots, err := parseNeedSummary(valid)
+	if err != nil {
+		t.Fatalf("Error parsing need summary: %v", err)
+	}
+	if want := []byte{1, 0, 0, 0, 0, 0, 1, 0, 0, 0}; !bytes.Equal(pivots, want) {
+		t.Fatalf("Expected pivots %v, got %v", want, pivots)
+	}
+
+	for name, summary := range map[string][]byte{
+		"short":        valid[:needSummaryHeaderSize-1],
+		"version":      append([]byte{2}, valid[1:]...),
+		"bitmap":       valid[:len(valid)-1],
+		"rank":         {needSummaryVersion, 10, 0, 0, 0, 3, 0, 0, 0, 0b0100_0001, 0},
+		"padding bits": {needSummaryVersion, 10, 0, 0, 0, 2, 0, 0, 0, 0b0000_0001, 0b1000_0000},
+	} {
+		if _, err := parseNeedSummary(summary); err == nil {
+			t.Errorf("Expected an error for a summary with a bad %s", name)
+		}
+	}
+}
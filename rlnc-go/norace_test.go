@@ -0,0 +1,5 @@
+//go:build !race
+
+package rlnc
+
+const raceEnabled = false
@@ -0,0 +1,48 @@
+package rlnc
+
+type options struct {
+	libPath     string
+	debug       bool
+	dlopenFlags int
+	leakLog     func(format string, args ...any)
+}
+
+// Option configures NewRLNCWithOptions.
+type Option func(*options)
+
+// WithLibraryPath loads the library at path instead of extracting the
+// embedded copy.
+func WithLibraryPath(path string) Option {
+	return func(o *options) {
+		o.libPath = path
+	}
+}
+
+// WithDebugLibrary loads the embedded debug build of the library instead of
+// the release build.
+func WithDebugLibrary() Option {
+	return func(o *options) {
+		o.debug = true
+	}
+}
+
+// WithDlopenFlags sets the flags the library is dlopened with, such as
+// purego.RTLD_NOW|purego.RTLD_LOCAL to keep its symbols out of the global
+// namespace. The default is RTLD_NOW|RTLD_GLOBAL. It has no effect on
+// Windows or with the rlnc_cgo and rlnc_wasm backends.
+func WithDlopenFlags(flags int) Option {
+	return func(o *options) {
+		o.dlopenFlags = flags
+	}
+}
+
+// BuildFlavor identifies which build of the native library an RLNC loaded.
+type BuildFlavor string
+
+const (
+	FlavorRelease BuildFlavor = "release"
+	FlavorDebug   BuildFlavor = "debug"
+	// FlavorExternal is a library that wasn't embedded in this package,
+	// loaded via WithLibraryPath or the rlnc_nolib build tag.
+	FlavorExternal BuildFlavor = "external"
+)
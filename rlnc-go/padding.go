@@ -0,0 +1,42 @@
+package rlnc
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// padLenSize is the size of the original length stored at the end of a
+// padded block.
+const padLenSize = 8
+
+// PaddedSize returns the size of a block of size bytes once padded for
+// numChunks chunks by NewSourceNodePadded.
+func PaddedSize(size int, numChunks int) int {
+	align := numChunks * 32
+	return (size + padLenSize + align - 1) / align * align
+}
+
+// PadBlock pads data so it splits into numChunks chunks of a whole number of
+// scalars, ending with data's length so UnpadBlock can strip the padding.
+// The length is always present, even when data needed no padding. It is the
+// padding NewSourceNodePadded applies, for coders that want the same layout.
+func PadBlock(data []byte, numChunks int) []byte {
+	size := PaddedSize(len(data), numChunks)
+	block := make([]byte, size)
+	copy(block, data)
+	binary.LittleEndian.PutUint64(block[size-padLenSize:], uint64(len(data)))
+	return block
+}
+
+// UnpadBlock returns the data PadBlock padded into block. The returned slice
+// aliases block.
+func UnpadBlock(block []byte) ([]byte, error) {
+	if len(block) < padLenSize {
+		return nil, errors.New("padded block is too short")
+	}
+	n := binary.LittleEndian.Uint64(block[len(block)-padLenSize:])
+	if n > uint64(len(block)-padLenSize) {
+		return nil, errors.New("padded block has an invalid length")
+	}
+	return block[:n], nil
+}
@@ -0,0 +1,91 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestPaddedRoundTrip(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	align := 32 * numChunks
+	for _, size := range []int{0, 1, align - padLenSize - 1, align - padLenSize, align - 1, align, align + 1, 1000, 4093} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		committer, err := rlnc.GenCommitterForMessage(PaddedSize(size, numChunks), numChunks)
+		if err != nil {
+			t.Fatalf("Error creating committer: %v", err)
+		}
+		defer committer.Close()
+		sourceNode, err := committer.NewSourceNodePadded(data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node for %d bytes: %v", size, err)
+		}
+		defer sourceNode.Close()
+		destinationNode, err := committer.NewNodePadded(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer destinationNode.Close()
+
+		for !destinationNode.IsFull() {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+
+		got, err := destinationNode.Data()
+		if err != nil {
+			t.Fatalf("Error getting data: %v", err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("Decoded %d bytes don't match, got %d bytes", size, len(got))
+		}
+		var written bytes.Buffer
+		if _, err := destinationNode.WriteTo(&written); err != nil {
+			t.Fatalf("Error writing data: %v", err)
+		}
+		if !bytes.Equal(data, written.Bytes()) {
+			t.Fatalf("Written %d bytes don't match, got %d bytes", size, written.Len())
+		}
+	}
+}
+
+func TestPaddedSize(t *testing.T) {
+	for _, tc := range []struct{ size, numChunks, padded int }{
+		{0, 1, 32},
+		{24, 1, 32},
+		{25, 1, 64},
+		// Even a block that is already a multiple needs room for its length.
+		{64, 2, 128},
+		{63, 2, 128},
+	} {
+		if got := PaddedSize(tc.size, tc.numChunks); got != tc.padded {
+			t.Fatalf("Expected %d bytes padding %d for %d chunks, got %d", tc.padded, tc.size, tc.numChunks, got)
+		}
+	}
+}
+
+func TestUnpadInvalid(t *testing.T) {
+	for _, block := range [][]byte{
+		nil,
+		{1, 2, 3},
+		{1, 0, 0, 0, 0, 0, 0, 0},
+		{0, 9, 0, 0, 0, 0, 0, 0, 0},
+	} {
+		if _, err := UnpadBlock(block); err == nil {
+			t.Fatalf("Expected error unpadding %v", block)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build !rlnc_wasm
+
+package rlnc
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// pin pins the Go objects ptrs point into on p. Nil pointers, as the data of
+// an empty slice may be, are skipped, and memory Go didn't allocate is left
+// alone by Pin.
+func pin(p *runtime.Pinner, ptrs ...unsafe.Pointer) {
+	for _, ptr := range ptrs {
+		if ptr != nil {
+			p.Pin(ptr)
+		}
+	}
+}
+
+// pinBuffers wraps the functions of r's table passing Go memory to the
+// library so it is pinned for the duration of the call. The library doesn't
+// keep the pointers past the call today, and Go's collector doesn't move heap
+// objects, but neither is a promise: pinned memory stays put and alive
+// whatever changes on either side.
+func (r *RLNC) pinBuffers() {
+	deserializeCommitter := r.deserializeCommitter
+	r.deserializeCommitter = func(serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, serializedPtr)
+		return deserializeCommitter(serializedPtr, serializedLen)
+	}
+	newSourceNode := r.newSourceNode
+	r.newSourceNode = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32) unsafe.Pointer {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(block)))
+		return newSourceNode(commiter, block, blockLen, numChunks)
+	}
+	commitBlock := r.commitBlock
+	r.commitBlock = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(block)))
+		return commitBlock(commiter, block, blockLen, numChunks, outPtr, outLen)
+	}
+	newSourceNodeWith := r.newSourceNodeWith
+	r.newSourceNodeWith = func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, commitments []byte, commitmentsLen uint64) unsafe.Pointer {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(block)), unsafe.Pointer(unsafe.SliceData(commitments)))
+		return newSourceNodeWith(commiter, block, blockLen, numChunks, commitments, commitmentsLen)
+	}
+	expectCommitments := r.expectCommitments
+	r.expectCommitments = func(node unsafe.Pointer, commitments []byte, commitmentsLen uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(commitments)))
+		return expectCommitments(node, commitments, commitmentsLen)
+	}
+	deserializeNode := r.deserializeNode
+	r.deserializeNode = func(commiter unsafe.Pointer, numChunks uint32, serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, serializedPtr)
+		return deserializeNode(commiter, numChunks, serializedPtr, serializedLen)
+	}
+	sendChunkForNeeds := r.sendChunkForNeeds
+	r.sendChunkForNeeds = func(node unsafe.Pointer, pivots []byte, pivotsLen uint64, outData *unsafe.Pointer, outDataLen *uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(pivots)))
+		return sendChunkForNeeds(node, pivots, pivotsLen, outData, outDataLen)
+	}
+	receiveChunk := r.receiveChunk
+	r.receiveChunk = func(node unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(chunk)))
+		return receiveChunk(node, chunk, chunkLen)
+	}
+	receiveChunks := r.receiveChunks
+	r.receiveChunks = func(node unsafe.Pointer, chunks []byte, lens []uint64, count uint64, codes []int32) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(chunks)), unsafe.Pointer(unsafe.SliceData(lens)), unsafe.Pointer(unsafe.SliceData(codes)))
+		return receiveChunks(node, chunks, lens, count, codes)
+	}
+	receiveSourceChunk := r.receiveSourceChunk
+	r.receiveSourceChunk = func(node unsafe.Pointer, index uint32, data []byte, dataLen uint64, commitments []byte, commitmentsLen uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(data)), unsafe.Pointer(unsafe.SliceData(commitments)))
+		return receiveSourceChunk(node, index, data, dataLen, commitments, commitmentsLen)
+	}
+	verifyChunk := r.verifyChunk
+	r.verifyChunk = func(commiter unsafe.Pointer, chunk []byte, chunkLen uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, unsafe.Pointer(unsafe.SliceData(chunk)))
+		return verifyChunk(commiter, chunk, chunkLen)
+	}
+	commitmentsHash := r.commitmentsHash
+	r.commitmentsHash = func(messageData unsafe.Pointer, messageLen uint64, outPtr *unsafe.Pointer, outLen *uint64) int32 {
+		var p runtime.Pinner
+		defer p.Unpin()
+		pin(&p, messageData)
+		return commitmentsHash(messageData, messageLen, outPtr, outLen)
+	}
+}
@@ -0,0 +1,102 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReceiveUnderGC(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks, err := sourceNode.Chunks(64)
+	if err != nil {
+		t.Fatalf("Error getting chunks: %v", err)
+	}
+
+	// The collector runs flat out while chunks are received from buffers
+	// that are garbage as soon as the call returns, so memory the library
+	// reads is reused right after if anything about its lifetime is off.
+	var stop atomic.Bool
+	var collector sync.WaitGroup
+	collector.Add(1)
+	go func() {
+		defer collector.Done()
+		var keep [][]byte
+		for !stop.Load() {
+			for range 64 {
+				keep = append(keep, bytes.Repeat([]byte{0xff}, 4096))
+			}
+			keep = keep[:0]
+			runtime.GC()
+		}
+	}()
+
+	const workers, rounds = 4, 250
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := range rounds {
+				node, err := committer.NewNode(numChunks)
+				if err != nil {
+					t.Errorf("Error creating node: %v", err)
+					return
+				}
+				for i := 0; !node.IsFull(); i++ {
+					chunk := bytes.Clone(chunks[(w+round+i)%len(chunks)])
+					if round%2 == 0 {
+						_, err = node.ReceiveChunk(chunk)
+					} else {
+						_, errs := node.ReceiveChunks([][]byte{chunk, bytes.Clone(chunk)})
+						err = errs[0]
+					}
+					if err != nil {
+						t.Errorf("Error receiving chunk in round %d: %v", round, err)
+						node.Close()
+						return
+					}
+					if err := committer.VerifyChunk(bytes.Clone(chunk)); err != nil {
+						t.Errorf("Error verifying chunk in round %d: %v", round, err)
+						node.Close()
+						return
+					}
+				}
+				got, err := node.Data()
+				node.Close()
+				if err != nil {
+					t.Errorf("Error getting data in round %d: %v", round, err)
+					return
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("Decoded data doesn't match in round %d", round)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	stop.Store(true)
+	collector.Wait()
+}
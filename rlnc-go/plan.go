@@ -0,0 +1,46 @@
+package rlnc
+
+import "fmt"
+
+// PlanBlock picks how to split a block of blockSize bytes so that its coded
+// chunks fit in mtu bytes. It returns the fewest chunks, and so the largest
+// chunk, for which a chunk on the wire, coefficients and commitments included,
+// is at most mtu bytes. The block is split as NewSourceNodePadded splits it,
+// so chunkSize is the padded chunk size to generate the Committer for, and
+// wireChunkSize the exact length of the chunks sent.
+//
+// Every chunk carries a coefficient and a commitment per chunk of the block,
+// so past some point more chunks make them larger rather than smaller. An
+// error is returned if no split fits.
+func PlanBlock(blockSize, mtu int) (numChunks, chunkSize, wireChunkSize int, err error) {
+	if blockSize <= 0 {
+		return 0, 0, 0, fmt.Errorf("block size must be positive, got %d", blockSize)
+	}
+	// A chunk of at least one scalar for n chunks takes 24+32*(1+2n) bytes.
+	for n := 1; chunkWireSize(1, n) <= mtu; n++ {
+		size := PaddedSize(blockSize, n) / n
+		if wire := chunkWireSize(size, n); wire <= mtu {
+			return n, size, wire, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("no split of a %d byte block fits in %d byte chunks", blockSize, mtu)
+}
+
+// Overhead returns how many bytes coding adds to a block of blockSize bytes
+// split into numChunks chunks, split as NewSourceNodePadded splits it.
+// perChunkBytes is what a chunk on the wire carries besides its share of the
+// padded block: length prefixes, the high bits scalars, a coefficient and a
+// commitment per chunk. perBlockBytes is what the numChunks chunks needed to
+// decode carry besides the block itself, padding included, and ratio is
+// perBlockBytes relative to blockSize. It returns zeros for a non-positive
+// blockSize or numChunks.
+func Overhead(blockSize, numChunks int) (perChunkBytes, perBlockBytes int, ratio float64) {
+	if blockSize <= 0 || numChunks <= 0 {
+		return 0, 0, 0
+	}
+	chunkSize := PaddedSize(blockSize, numChunks) / numChunks
+	wire := chunkWireSize(chunkSize, numChunks)
+	perChunkBytes = wire - chunkSize
+	perBlockBytes = numChunks*wire - blockSize
+	return perChunkBytes, perBlockBytes, float64(perBlockBytes) / float64(blockSize)
+}
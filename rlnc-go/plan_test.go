@@ -0,0 +1,135 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestPlanBlock(t *testing.T) {
+	for _, tc := range []struct {
+		blockSize, mtu                      int
+		numChunks, chunkSize, wireChunkSize int
+	}{
+		{128 * 1024, 9000, 17, 7712, 8952},
+		{16 * 1024, 9000, 2, 8224, 8536},
+		{4096, 9000, 1, 4128, 4312},
+		{4096, 1500, 4, 1056, 1368},
+		{4096, 1200, 6, 704, 1144},
+	} {
+		numChunks, chunkSize, wireChunkSize, err := PlanBlock(tc.blockSize, tc.mtu)
+		if err != nil {
+			t.Fatalf("Error planning a %d byte block for MTU %d: %v", tc.blockSize, tc.mtu, err)
+		}
+		if numChunks != tc.numChunks || chunkSize != tc.chunkSize || wireChunkSize != tc.wireChunkSize {
+			t.Fatalf("PlanBlock(%d, %d) = %d, %d, %d, want %d, %d, %d", tc.blockSize, tc.mtu,
+				numChunks, chunkSize, wireChunkSize, tc.numChunks, tc.chunkSize, tc.wireChunkSize)
+		}
+	}
+
+	// The coefficients and commitments of a 128KB block outgrow small MTUs
+	// before its chunks get small enough.
+	for _, tc := range []struct{ blockSize, mtu int }{
+		{128 * 1024, 1200},
+		{128 * 1024, 1500},
+		{1, 100},
+		{0, 9000},
+	} {
+		if _, _, _, err := PlanBlock(tc.blockSize, tc.mtu); err == nil {
+			t.Fatalf("Expected no plan for a %d byte block and MTU %d", tc.blockSize, tc.mtu)
+		}
+	}
+}
+
+func TestPlanBlockChunks(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	data := make([]byte, 128*1024)
+	rand.Read(data)
+	mtu := 9000
+	numChunks, chunkSize, wireChunkSize, err := PlanBlock(len(data), mtu)
+	if err != nil {
+		t.Fatalf("Error planning block: %v", err)
+	}
+	committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNodePadded(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNodePadded(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if len(chunk) != wireChunkSize || len(chunk) > mtu {
+			t.Fatalf("Expected %d byte chunks within MTU %d, got %d", wireChunkSize, mtu, len(chunk))
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	for _, tc := range []struct{ blockSize, numChunks int }{
+		{1, 1},
+		{5000, 4},
+		{128 * 1024, 8},
+		{128 * 1024, 17},
+	} {
+		data := make([]byte, tc.blockSize)
+		rand.Read(data)
+		encoder, err := NewEncoder(rlnc, data, tc.numChunks)
+		if err != nil {
+			t.Fatalf("Error creating encoder: %v", err)
+		}
+		defer encoder.Close()
+		chunk, err := encoder.NextChunk()
+		if err != nil {
+			t.Fatalf("Error getting chunk: %v", err)
+		}
+
+		perChunk, perBlock, ratio := Overhead(tc.blockSize, tc.numChunks)
+		chunkSize := PaddedSize(tc.blockSize, tc.numChunks) / tc.numChunks
+		if want := len(chunk) - chunkSize; perChunk != want {
+			t.Fatalf("Overhead(%d, %d): expected %d bytes per chunk, got %d", tc.blockSize, tc.numChunks, want, perChunk)
+		}
+		if want := tc.numChunks*len(chunk) - tc.blockSize; perBlock != want {
+			t.Fatalf("Overhead(%d, %d): expected %d bytes per block, got %d", tc.blockSize, tc.numChunks, want, perBlock)
+		}
+		if want := float64(perBlock) / float64(tc.blockSize); ratio != want {
+			t.Fatalf("Overhead(%d, %d): expected ratio %v, got %v", tc.blockSize, tc.numChunks, want, ratio)
+		}
+	}
+	if perChunk, perBlock, ratio := Overhead(0, 8); perChunk != 0 || perBlock != 0 || ratio != 0 {
+		t.Fatalf("Expected no overhead for an empty block")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package rlnc
+
+// raceEnabled is set when the race detector is on, which makes sync.Pool
+// drop items at random, so allocation counts can't be relied on.
+const raceEnabled = true
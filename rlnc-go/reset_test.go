@@ -0,0 +1,143 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	chunkSize := 32 * 64
+	committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	for block := range 3 {
+		data := make([]byte, chunkSize*numChunks)
+		rand.Read(data)
+		sourceNode, err := committer.NewSourceNode(data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer sourceNode.Close()
+		for !destinationNode.IsFull() {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+				t.Fatalf("Error receiving chunk of block %d: %v", block, err)
+			}
+		}
+		got, err := destinationNode.Data()
+		if err != nil {
+			t.Fatalf("Error getting data: %v", err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("Data of block %d doesn't match", block)
+		}
+
+		if err := destinationNode.Reset(); err != nil {
+			t.Fatalf("Error resetting node: %v", err)
+		}
+		if destinationNode.IsFull() || destinationNode.Rank() != 0 {
+			t.Fatalf("Expected an empty node after Reset, got rank %d", destinationNode.Rank())
+		}
+		if _, err := destinationNode.CommitmentsHash(); err == nil {
+			t.Fatalf("Expected no commitments after Reset")
+		}
+	}
+
+	destinationNode.Close()
+	if err := destinationNode.Reset(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed resetting a closed node, got %v", err)
+	}
+}
+
+// BenchmarkNodeReuse decodes blocks with a node per block, or with one node
+// Reset between blocks. Receiving dominates the time of a block either way,
+// so it also reports the time to get a node ready for the next block.
+func BenchmarkNodeReuse(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 8
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		b.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		b.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks := make([][]byte, numChunks)
+	for i := range chunks {
+		if chunks[i], err = sourceNode.SystematicChunk(i); err != nil {
+			b.Fatalf("Error getting chunk: %v", err)
+		}
+	}
+	decode := func(b *testing.B, node *Node) {
+		for _, chunk := range chunks {
+			if _, err := node.ReceiveChunk(chunk); err != nil {
+				b.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+		if _, err := node.Data(); err != nil {
+			b.Fatalf("Error getting data: %v", err)
+		}
+	}
+
+	for _, bc := range []struct {
+		name string
+		next func(*Node) (*Node, error)
+	}{
+		{"NewNode", func(node *Node) (*Node, error) {
+			node.Close()
+			return committer.NewNode(numChunks)
+		}},
+		{"Reset", func(node *Node) (*Node, error) {
+			return node, node.Reset()
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			node, err := committer.NewNode(numChunks)
+			if err != nil {
+				b.Fatalf("Error creating node: %v", err)
+			}
+			var turnover time.Duration
+			for range b.N {
+				decode(b, node)
+				start := time.Now()
+				if node, err = bc.next(node); err != nil {
+					b.Fatalf("Error getting the next node: %v", err)
+				}
+				turnover += time.Since(start)
+			}
+			node.Close()
+			b.ReportMetric(float64(turnover.Nanoseconds())/float64(b.N), "turnover-ns/op")
+		})
+	}
+}
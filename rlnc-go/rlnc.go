@@ -1,77 +1,265 @@
 package rlnc
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"runtime"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"unsafe"
-
-	"github.com/ebitengine/purego"
 )
 
+// BindingsVersion is the version of these Go bindings.
+const BindingsVersion = "0.1.0"
+
+// abiVersion is the ABI_VERSION of the library these bindings are written
+// against.
+const abiVersion = 2
+
 type RLNC struct {
-	lib uintptr
+	lib     uintptr
+	libPath string
+	flavor  BuildFlavor
+	// shared is set on the Default RLNC, which must never be closed.
+	shared bool
+	// wasm is the instantiated module for the rlnc_wasm backend.
+	wasm *wasmInstance
+
+	// The library stays loaded after Close until every Committer and Node
+	// created from it has been closed too.
+	mu       sync.Mutex
+	handles  int
+	closing  bool
+	unloaded bool
+	// leakLog is set by WithLeakLog.
+	leakLog func(format string, args ...any)
+
+	getVersion func() uint32
+	lastError  func(outPtr *unsafe.Pointer, outLen *uint64) int32
 
 	genCommitter         func(chunkSizeInScalars uint32) unsafe.Pointer
 	serializeCommitter   func(commiter unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64)
 	deserializeCommitter func(serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer
 	freeCommitter        func(commiter unsafe.Pointer)
+	committerLen         func(commiter unsafe.Pointer) uint32
 	newNode              func(commiter unsafe.Pointer, numChunks uint32) unsafe.Pointer
 	newSourceNode        func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32) unsafe.Pointer
+	commitBlock          func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, outPtr *unsafe.Pointer, outLen *uint64) int32
+	newSourceNodeWith    func(commiter unsafe.Pointer, block []byte, blockLen uint64, numChunks uint32, commitments []byte, commitmentsLen uint64) unsafe.Pointer
+	expectCommitments    func(node unsafe.Pointer, commitments []byte, commitmentsLen uint64) int32
+	cloneNode            func(node unsafe.Pointer) unsafe.Pointer
 	freeNode             func(node unsafe.Pointer)
+	serializeNode        func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32
+	deserializeNode      func(commiter unsafe.Pointer, numChunks uint32, serializedPtr unsafe.Pointer, serializedLen uint64) unsafe.Pointer
 	sendChunk            func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
+	sendChunks           func(node unsafe.Pointer, count uint32, outData *unsafe.Pointer, outDataLen *uint64) int32
+	sendSystematicChunk  func(node unsafe.Pointer, index uint32, outData *unsafe.Pointer, outDataLen *uint64) int32
+	sendChunkForNeeds    func(node unsafe.Pointer, pivots []byte, pivotsLen uint64, outData *unsafe.Pointer, outDataLen *uint64) int32
+	pivotColumns         func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
+	setSeed              func(node unsafe.Pointer, seed uint64)
+	resetNode            func(node unsafe.Pointer)
 	receiveChunk         func(node unsafe.Pointer, chunk []byte, chunkLen uint64) int32
+	receiveChunks        func(node unsafe.Pointer, chunks []byte, lens []uint64, count uint64, codes []int32) int32
+	receiveSourceChunk   func(node unsafe.Pointer, index uint32, data []byte, dataLen uint64, commitments []byte, commitmentsLen uint64) int32
+	verifyChunk          func(commiter unsafe.Pointer, chunk []byte, chunkLen uint64) int32
 	decode               func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
+	decodeVerified       func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
+	decodedChunks        func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
 	freeBuffer           func(buffer unsafe.Pointer, len uint64)
 	isFull               func(node unsafe.Pointer) bool
+	rank                 func(node unsafe.Pointer) uint32
+	memoryUsage          func(node unsafe.Pointer) uint64
+	liveHandles          func() uint64
+	coefficients         func(node unsafe.Pointer, outData *unsafe.Pointer, outDataLen *uint64) int32
 
-	commitmentsHash func(messageData unsafe.Pointer, messageLen uint64, outPtr *unsafe.Pointer, outLen *uint64) int32
+	nodeCommitmentsHash func(node unsafe.Pointer, outPtr *unsafe.Pointer, outLen *uint64) int32
+	commitmentsHash     func(messageData unsafe.Pointer, messageLen uint64, outPtr *unsafe.Pointer, outLen *uint64) int32
 }
 
 func NewRLNC() (*RLNC, error) {
-	libPath := getLibPath()
-	lib, err := purego.Dlopen(libPath, purego.RTLD_NOW|purego.RTLD_GLOBAL)
-	if err != nil {
-		return nil, err
+	return NewRLNCWithOptions()
+}
+
+func NewRLNCWithOptions(opts ...Option) (*RLNC, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	r := &RLNC{lib: lib}
+	r := &RLNC{leakLog: o.leakLog}
+	if err := r.load(o); err != nil {
+		return nil, err
+	}
 
-	purego.RegisterLibFunc(&r.genCommitter, lib, "gen_committer")
-	purego.RegisterLibFunc(&r.serializeCommitter, lib, "serialize_committer")
-	purego.RegisterLibFunc(&r.deserializeCommitter, lib, "deserialize_committer")
-	purego.RegisterLibFunc(&r.freeCommitter, lib, "free_committer")
-	purego.RegisterLibFunc(&r.newNode, lib, "new_node")
-	purego.RegisterLibFunc(&r.newSourceNode, lib, "new_source_node")
-	purego.RegisterLibFunc(&r.freeNode, lib, "free_node")
-	purego.RegisterLibFunc(&r.sendChunk, lib, "send_chunk")
-	purego.RegisterLibFunc(&r.receiveChunk, lib, "receive_chunk")
-	purego.RegisterLibFunc(&r.decode, lib, "decode")
-	purego.RegisterLibFunc(&r.freeBuffer, lib, "free_buffer")
-	purego.RegisterLibFunc(&r.isFull, lib, "is_full")
-	purego.RegisterLibFunc(&r.commitmentsHash, lib, "commitments_hash")
+	if err := r.checkVersion(); err != nil {
+		r.unloadLib()
+		return nil, err
+	}
 	return r, nil
 }
 
+func (r *RLNC) checkVersion() error {
+	if got := r.Version(); got != abiVersion {
+		return fmt.Errorf("Go bindings v%s expect library ABI %d, got %d", BindingsVersion, abiVersion, got)
+	}
+	return nil
+}
+
+// Version returns the ABI version of the loaded library.
+func (r *RLNC) Version() uint32 {
+	return r.getVersion()
+}
+
+// BuildFlavor reports which build of the native library r loaded.
+func (r *RLNC) BuildFlavor() BuildFlavor {
+	return r.flavor
+}
+
+// Close unloads the library once all Committers and Nodes created from r are
+// closed. Until then they keep working, but r can't create new Committers.
+// Calling Close again does nothing.
 func (r *RLNC) Close() {
-	purego.Dlclose(r.lib)
+	if r.shared {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closing = true
+	r.maybeUnload()
+}
+
+func (r *RLNC) acquire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handles++
+}
+
+// acquireNew is acquire for a Committer created from r itself, which fails
+// once r is closed.
+func (r *RLNC) acquireNew() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closing {
+		return ErrClosed
+	}
+	r.handles++
+	return nil
+}
+
+// loaded reports whether the library can still be called.
+func (r *RLNC) loaded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.unloaded
+}
+
+func (r *RLNC) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handles--
+	r.maybeUnload()
+}
+
+// maybeUnload must be called with r.mu held.
+func (r *RLNC) maybeUnload() {
+	if r.closing && r.handles == 0 && !r.unloaded {
+		r.unloadLib()
+		r.unloaded = true
+	}
+}
+
+// GenCommitterForChunkSize generates a Committer for chunks of chunkSize
+// bytes.
+func (r *RLNC) GenCommitterForChunkSize(chunkSize int) (*Committer, error) {
+	if err := checkChunkSize(int64(chunkSize)); err != nil {
+		return nil, err
+	}
+	if err := r.acquireNew(); err != nil {
+		return nil, err
+	}
+	commiter := r.genCommitter(uint32(chunkSizeInScalars(int64(chunkSize))))
+	return (&Committer{r: r, p: commiter, chunkSize: chunkSize}).track(), nil
 }
 
-func (r *RLNC) GenCommitter(messageSize int, numChunks int) (*Committer, error) {
+// GenCommitterForMessage generates a Committer for messages of messageSize
+// bytes split into numChunks chunks.
+func (r *RLNC) GenCommitterForMessage(messageSize int, numChunks int) (*Committer, error) {
+	if err := checkNumChunks(numChunks); err != nil {
+		return nil, err
+	}
+	if messageSize <= 0 {
+		return nil, fmt.Errorf("message size must be positive, got %d", messageSize)
+	}
 	if messageSize%numChunks != 0 {
 		return nil, fmt.Errorf("message size must be a multiple of num chunks")
 	}
-	chunkSize := messageSize / numChunks
-	chunkSizeInScalars := (chunkSize*8 + 251) / 252
-	commiter := r.genCommitter(uint32(chunkSizeInScalars))
-	return &Committer{r: r, p: commiter}, nil
+	return r.GenCommitterForChunkSize(messageSize / numChunks)
 }
 
-func (r *RLNC) CommitmentsHash(message []byte) ([]byte, error) {
+// MaxNumChunks is the most chunks a block can be split into. A node keeps a
+// coefficient per chunk for each chunk it holds, so its memory grows with the
+// square of the number of chunks.
+const MaxNumChunks = 1 << 16
+
+// checkNumChunks checks numChunks is in range before it is converted for the
+// library.
+func checkNumChunks(numChunks int) error {
+	if numChunks <= 0 {
+		return fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	if numChunks > MaxNumChunks {
+		return fmt.Errorf("num chunks %d exceeds the maximum of %d", numChunks, MaxNumChunks)
+	}
+	return nil
+}
+
+// MaxChunkSize is the largest chunk size, in bytes, a Committer can be
+// generated for. The library counts the scalars of a chunk in 32 bits.
+const MaxChunkSize int64 = 252 * math.MaxUint32 / 8
+
+// checkChunkSize checks chunkSize is in range before its number of scalars is
+// converted to the uint32 the library takes.
+func checkChunkSize(chunkSize int64) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if chunkSize > MaxChunkSize {
+		return fmt.Errorf("chunk size %d exceeds the maximum of %d", chunkSize, MaxChunkSize)
+	}
+	return nil
+}
+
+// chunkSizeInScalars returns the number of scalars a committer needs for
+// chunks of chunkSize bytes, 8 bits a byte at 252 bits a scalar, rounded up.
+// Multiplying by 8 first would overflow int for chunks past 256MB on 32-bit
+// platforms, so it is computed in int64 and as 2/63 of a scalar a byte, which
+// can't overflow at all.
+func chunkSizeInScalars(chunkSize int64) int64 {
+	return chunkSize/63*2 + (chunkSize%63*2+62)/63
+}
+
+// CommitmentsHash returns the hash of the commitments chunk carries, which
+// identifies its block. An empty chunk, as a zero byte read gives, returns
+// ErrEmptyChunk, and one that can't be parsed an error wrapping
+// ErrMalformedChunk.
+func (r *RLNC) CommitmentsHash(chunk []byte) ([]byte, error) {
+	if !r.loaded() {
+		return nil, ErrClosed
+	}
+	if len(chunk) == 0 {
+		return nil, ErrEmptyChunk
+	}
 	var outPtr unsafe.Pointer
 	var outLen uint64
-	res := r.commitmentsHash(unsafe.Pointer(&message[0]), uint64(len(message)), &outPtr, &outLen)
+	res := r.commitmentsHash(unsafe.Pointer(&chunk[0]), uint64(len(chunk)), &outPtr, &outLen)
 	if res != 0 {
-		return nil, fmt.Errorf("failed to get commitments hash")
+		return nil, codeError(ErrMalformedChunk, res)
 	}
 	defer r.freeBuffer(outPtr, outLen)
 	s := unsafe.Slice((*byte)(outPtr), int(outLen))
@@ -82,9 +270,71 @@ func (r *RLNC) CommitmentsHash(message []byte) ([]byte, error) {
 type Committer struct {
 	r *RLNC
 	p unsafe.Pointer
+	// chunkSize is the chunk size in bytes the committer was generated for.
+	// It isn't part of the serialized form, so a deserialized committer
+	// uses ChunkSizeBytes.
+	chunkSize int
+	// shared is set on Committers handed out by a CommitterCache, whose
+	// Close drops a reference instead of freeing p.
+	shared *sharedCommitter
+	// mu guards p: the calls using it hold a read lock, so Close, which
+	// takes the write lock, can't free it under them and frees it only once.
+	mu sync.RWMutex
+	// tracked is set while a finalizer frees p if c is dropped unclosed.
+	tracked bool
+	// nodes counts the open Nodes created from c, which use p in the
+	// library. A Close while there are any leaves freeing p to the last of
+	// them, in deferred.
+	nodes    atomic.Int64
+	deferred []func()
+}
+
+// minSerializedCommitterSize is the size of a serialized Committer with a
+// single generator: the generator count and one compressed point.
+const minSerializedCommitterSize = 8 + 32
+
+// DeserializeCommitter restores a Committer from the output of Serialize or
+// MarshalBinary. Bytes that aren't one, which a peer can send, are rejected
+// with an error wrapping ErrInvalidCommitter.
+func DeserializeCommitter(r *RLNC, serialized []byte) (*Committer, error) {
+	if err := checkSerializedCommitter(serialized); err != nil {
+		return nil, err
+	}
+	if err := r.acquireNew(); err != nil {
+		return nil, err
+	}
+	p, err := r.newHandle("deserialize committer", func() unsafe.Pointer {
+		return r.deserializeCommitter(unsafe.Pointer(&serialized[0]), uint64(len(serialized)))
+	})
+	if err != nil {
+		r.release()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommitter, err)
+	}
+	c := &Committer{r: r, p: p}
+	c.chunkSize = c.ChunkSizeBytes()
+	return c.track(), nil
+}
+
+// checkSerializedCommitter checks the framing of a serialized Committer, a
+// little-endian uint64 generator count followed by that many points, leaving
+// checking the points to the library.
+func checkSerializedCommitter(serialized []byte) error {
+	if len(serialized) < minSerializedCommitterSize {
+		return fmt.Errorf("%w: %d bytes is too short", ErrInvalidCommitter, len(serialized))
+	}
+	count := binary.LittleEndian.Uint64(serialized)
+	if count != uint64(len(serialized)-8)/32 || (len(serialized)-8)%32 != 0 {
+		return fmt.Errorf("%w: %d bytes can't hold %d generators", ErrInvalidCommitter, len(serialized), count)
+	}
+	return nil
 }
 
 func (c *Committer) Serialize() ([]byte, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
 	var outPtr unsafe.Pointer
 	var outLen uint64
 	c.r.serializeCommitter(c.p, &outPtr, &outLen)
@@ -93,43 +343,695 @@ func (c *Committer) Serialize() ([]byte, error) {
 	return copied, nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler. It is the same as
+// Serialize.
+func (c *Committer) MarshalBinary() ([]byte, error) {
+	return c.Serialize()
+}
+
+// Fingerprint returns the SHA-256 hash of c's serialization. Committers only
+// depend on the number of scalars they support, so peers can compare
+// fingerprints to check they use the same one without sending it.
+func (c *Committer) Fingerprint() ([32]byte, error) {
+	serialized, err := c.Serialize()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(serialized), nil
+}
+
+// Equal reports whether c and other commit alike.
+func (c *Committer) Equal(other *Committer) (bool, error) {
+	a, err := c.Fingerprint()
+	if err != nil {
+		return false, err
+	}
+	b, err := other.Fingerprint()
+	if err != nil {
+		return false, err
+	}
+	return a == b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing c with the
+// serialized Committer. The new Committer uses c's RLNC, or Default if c is
+// the zero Committer.
+func (c *Committer) UnmarshalBinary(data []byte) error {
+	r := c.r
+	if r == nil {
+		var err error
+		if r, err = Default(); err != nil {
+			return err
+		}
+	}
+	restored, err := DeserializeCommitter(r, data)
+	if err != nil {
+		return err
+	}
+	c.replace(restored)
+	return nil
+}
+
+// Deserialize sets c to the serialized Committer.
+//
+// Deprecated: Use DeserializeCommitter or UnmarshalBinary.
 func (c *Committer) Deserialize(r *RLNC, serialized []byte) error {
-	c.r = r
-	c.p = c.r.deserializeCommitter(unsafe.Pointer(&serialized[0]), uint64(len(serialized)))
+	restored, err := DeserializeCommitter(r, serialized)
+	if err != nil {
+		return err
+	}
+	c.replace(restored)
 	return nil
 }
 
+// replace closes c and moves restored's handle into it. Only Close frees it
+// from then on, since c can't be tracked.
+func (c *Committer) replace(restored *Committer) {
+	c.Close()
+	restored.untrack()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.r, c.p, c.chunkSize, c.shared = restored.r, restored.p, restored.chunkSize, restored.shared
+}
+
+// ChunkSizeScalars returns the number of scalars per chunk c supports, or 0
+// once c is closed.
+func (c *Committer) ChunkSizeScalars() int {
+	if err := c.rlock(); err != nil {
+		return 0
+	}
+	defer c.mu.RUnlock()
+	return c.chunkSizeScalars()
+}
+
+// chunkSizeScalars is ChunkSizeScalars for a caller holding c's read lock.
+func (c *Committer) chunkSizeScalars() int {
+	if c.p == nil {
+		return 0
+	}
+	defer runtime.KeepAlive(c)
+	return int(c.r.committerLen(c.p))
+}
+
+// ChunkSizeBytes returns the largest chunk c supports, in bytes. Chunks are
+// made of 32 byte words, and every 63 words need an extra scalar for their
+// high bits.
+func (c *Committer) ChunkSizeBytes() int {
+	scalars := c.ChunkSizeScalars()
+	words := scalars / 64 * 63
+	if rem := scalars % 64; rem > 1 {
+		words += rem - 1
+	}
+	return words * 32
+}
+
+// ChunkWireSize returns the exact length of the chunks sent for a block of
+// numChunks chunks of the size c was generated for, coefficients and
+// commitments included, or 0 if numChunks isn't positive.
+func (c *Committer) ChunkWireSize(numChunks int) int {
+	if numChunks <= 0 {
+		return 0
+	}
+	return chunkWireSize(c.chunkSize, numChunks)
+}
+
+// MaxMessageSize returns the largest block c supports split into numChunks
+// chunks.
+func (c *Committer) MaxMessageSize(numChunks int) int {
+	return numChunks * c.ChunkSizeBytes()
+}
+
+// CommitmentsHashForBlock returns the hash of the commitments the chunks of
+// block split into numChunks chunks will carry, the same as CommitmentsHash of
+// any of them, so a sender can advertise it before sending anything.
+func (c *Committer) CommitmentsHashForBlock(block []byte, numChunks int) ([]byte, error) {
+	n, err := c.NewSourceNode(block, numChunks)
+	if err != nil {
+		return nil, err
+	}
+	defer n.Close()
+	return n.CommitmentsHash()
+}
+
+// VerifyChunk checks chunk's payload against the commitments it carries,
+// without a Node, so relays can drop bad chunks before forwarding them. It
+// returns an error wrapping ErrMalformedChunk if chunk can't be parsed and
+// ErrVerificationFailed if it doesn't verify. It doesn't check that the
+// commitments are the ones of a particular block.
+func (c *Committer) VerifyChunk(chunk []byte) error {
+	if err := c.rlock(); err != nil {
+		return err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
+	return verifyError(c.r.verifyChunk(c.p, chunk, uint64(len(chunk))))
+}
+
+// Close frees the committer. Calling it again, even while the first call is
+// running in another goroutine, does nothing. Nodes created from c keep
+// working after Close, and the committer is only freed once the last of them
+// is closed. A Committer from a CommitterCache is only freed once every user
+// and the cache let go of it.
 func (c *Committer) Close() {
-	c.r.freeCommitter(c.p)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.p == nil {
+		return
+	}
+	c.untrack()
+	r, p, shared := c.r, c.p, c.shared
+	free := func() {
+		if shared != nil {
+			shared.release()
+			return
+		}
+		r.freeCommitter(p)
+		r.release()
+	}
+	c.p = nil
+	if c.nodes.Load() > 0 {
+		c.deferred = append(c.deferred, free)
+		return
+	}
+	free()
 }
 
+// releaseNode is called by Node.Close for a node created from c, and frees c
+// if it is the last one left of a closed c.
+func (c *Committer) releaseNode() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes.Add(-1) > 0 {
+		return
+	}
+	for _, free := range c.deferred {
+		free()
+	}
+	c.deferred = nil
+}
+
+// rlock read-locks c for a call using its handle, or returns ErrClosed if c
+// is closed.
+func (c *Committer) rlock() error {
+	c.mu.RLock()
+	if c.p == nil {
+		c.mu.RUnlock()
+		return ErrClosed
+	}
+	return nil
+}
+
+// A Node is safe for concurrent use by multiple goroutines: its calls into
+// the library take turns, so receivers can feed one node from several
+// connections.
 type Node struct {
 	r *RLNC
 	p unsafe.Pointer
+	// committer is the Committer n was created from, which p borrows. The
+	// reference keeps its finalizer from freeing it while n is in use.
+	committer *Committer
+	numChunks int
+	chunkSize int
+	// padded is set if the block was padded by NewSourceNodePadded, so the
+	// padding must be stripped from the decoded data.
+	padded bool
+	// id is the block ID set by SetBlockID or adopted from the first wrapped
+	// chunk received, if hasID is set.
+	id    BlockID
+	hasID bool
+	// chunkLen is the length of the chunks n received, once it has any.
+	chunkLen int
+	// onDecoded is the OnDecoded callback, cleared once it fires.
+	onDecoded func(data []byte)
+	// stats counts the chunks n received, for DebugState.
+	stats receiveStats
+	// done is the channel Done returns, made on demand. Once n is full or
+	// closed, finished is set and doneErr holds what Err reports.
+	doneMu   sync.Mutex
+	done     chan struct{}
+	finished bool
+	doneErr  error
+	// inflight counts calls DataContext and ReceiveChunksContext left running
+	// in the background, which Close waits for.
+	inflight sync.WaitGroup
+	// mu serializes the calls into the library on p, which a handle can't
+	// take concurrently, and guards p and the fields they update. It is
+	// taken before doneMu, and never held while an OnDecoded callback runs.
+	mu sync.Mutex
+	// tracked is set while a finalizer frees p if n is dropped unclosed.
+	tracked bool
 }
 
-func (c *Committer) NewNode(numChunks int) *Node {
-	return &Node{r: c.r, p: c.r.newNode(c.p, uint32(numChunks))}
+// NewNode creates a node to decode a block of numChunks chunks. It returns
+// ErrClosed if c is closed.
+func (c *Committer) NewNode(numChunks int) (*Node, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
+	if err := checkNumChunks(numChunks); err != nil {
+		return nil, err
+	}
+	p, err := c.r.newHandle("create node", func() unsafe.Pointer {
+		return c.r.newNode(c.p, uint32(numChunks))
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.r.acquire()
+	c.nodes.Add(1)
+	return (&Node{r: c.r, p: p, committer: c, numChunks: numChunks, chunkSize: c.chunkSize}).track(), nil
 }
 
 func (c *Committer) NewSourceNode(block []byte, numChunks int) (*Node, error) {
-	if len(block)%numChunks != 0 {
-		return nil, fmt.Errorf("block size must be a multiple of chunk size")
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	chunkSize, err := c.checkBlock(block, numChunks)
+	if err != nil {
+		return nil, err
 	}
+	return c.newSourceNode(block, numChunks, chunkSize)
+}
 
-	return &Node{r: c.r, p: c.r.newSourceNode(c.p, block, uint64(len(block)), uint32(numChunks))}, nil
+// newSourceNode creates the source node of a block already checked to split
+// into numChunks chunks of chunkSize bytes. The caller holds c's read lock.
+func (c *Committer) newSourceNode(block []byte, numChunks, chunkSize int) (*Node, error) {
+	defer runtime.KeepAlive(c)
+	p, err := c.r.newHandle("create source node", func() unsafe.Pointer {
+		return c.r.newSourceNode(c.p, block, uint64(len(block)), uint32(numChunks))
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.r.acquire()
+	c.nodes.Add(1)
+	return (&Node{r: c.r, p: p, committer: c, numChunks: numChunks, chunkSize: chunkSize}).track(), nil
 }
 
+// newHandle returns the handle f creates in the library, or an error with
+// the reason the library recorded if f returns nil. The library records it
+// per thread, so the goroutine stays on its OS thread in between.
+func (r *RLNC) newHandle(what string, f func() unsafe.Pointer) (unsafe.Pointer, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if p := f(); p != nil {
+		return p, nil
+	}
+	var outPtr unsafe.Pointer
+	var outLen uint64
+	if r.lastError(&outPtr, &outLen) != 0 {
+		return nil, fmt.Errorf("failed to %s", what)
+	}
+	defer r.freeBuffer(outPtr, outLen)
+	return nil, fmt.Errorf("failed to %s: %s", what, unsafe.Slice((*byte)(outPtr), int(outLen)))
+}
+
+// checkBlock checks that block splits into numChunks chunks c supports and
+// returns their size. The caller holds c's read lock.
+func (c *Committer) checkBlock(block []byte, numChunks int) (int, error) {
+	return c.checkBlockSize(int64(len(block)), numChunks)
+}
+
+// checkBlockSize checks that a block of size bytes splits into numChunks
+// chunks c supports and returns their size, so a block too large for c is
+// rejected before it is read.
+func (c *Committer) checkBlockSize(size int64, numChunks int) (int, error) {
+	if err := checkNumChunks(numChunks); err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("block size must be positive, got %d", size)
+	}
+	if size > math.MaxInt {
+		return 0, fmt.Errorf("block size %d exceeds the %d bytes a slice can hold", size, math.MaxInt)
+	}
+	if size%int64(numChunks) != 0 {
+		return 0, fmt.Errorf("block size must be a multiple of chunk size")
+	}
+	chunkSize := size / int64(numChunks)
+	if chunkSize%32 != 0 {
+		return 0, fmt.Errorf("block implies %d byte chunks, which aren't a multiple of 32 bytes", chunkSize)
+	}
+	if err := checkChunkSize(chunkSize); err != nil {
+		return 0, fmt.Errorf("block of %d bytes: %w", size, err)
+	}
+	if scalars, supported := chunkSizeInScalars(chunkSize), c.chunkSizeScalars(); scalars > int64(supported) {
+		return 0, fmt.Errorf("block implies %d-scalar chunks but committer supports %d", scalars, supported)
+	}
+	return int(chunkSize), nil
+}
+
+// NewSourceNodeFromReader reads a size byte block from r and creates a source
+// node for it, like NewSourceNode. It returns io.ErrUnexpectedEOF if r ends
+// early.
+func (c *Committer) NewSourceNodeFromReader(r io.Reader, size int64, numChunks int) (*Node, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	// The lock isn't held while reading, so a slow r doesn't hold up Close.
+	chunkSize, err := c.checkBlockSize(size, numChunks)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read straight into the block one chunk at a time, rather than through
+	// io.ReadAll, which would grow and copy it.
+	block := make([]byte, size)
+	for off := 0; off < len(block); off += chunkSize {
+		if _, err := io.ReadFull(r, block[off:off+chunkSize]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return c.NewSourceNode(block, numChunks)
+}
+
+// NewSourceNodePadded creates a source node for a block of any size, padding
+// it so it splits into numChunks chunks. The Committer must be generated for
+// PaddedSize(len(block), numChunks) bytes. The padding is stripped by the Data
+// and WriteTo of nodes created with NewNodePadded.
+func (c *Committer) NewSourceNodePadded(block []byte, numChunks int) (*Node, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	if err := checkNumChunks(numChunks); err != nil {
+		return nil, err
+	}
+	if err := c.checkPaddedSize(len(block), numChunks); err != nil {
+		return nil, err
+	}
+	padded := PadBlock(block, numChunks)
+	n, err := c.newSourceNode(padded, numChunks, len(padded)/numChunks)
+	if err != nil {
+		return nil, err
+	}
+	n.padded = true
+	return n, nil
+}
+
+// checkPaddedSize checks that a block of size bytes splits into numChunks
+// chunks c supports once padded, so a block too large for c isn't copied.
+func (c *Committer) checkPaddedSize(size, numChunks int) error {
+	if size > math.MaxInt-padLenSize-32*numChunks {
+		return fmt.Errorf("block of %d bytes is too large to pad", size)
+	}
+	_, err := c.checkBlockSize(int64(PaddedSize(size, numChunks)), numChunks)
+	return err
+}
+
+// NewNodePadded creates a node to decode a block sent by a node created with
+// NewSourceNodePadded.
+func (c *Committer) NewNodePadded(numChunks int) (*Node, error) {
+	n, err := c.NewNode(numChunks)
+	if err != nil {
+		return nil, err
+	}
+	n.padded = true
+	return n, nil
+}
+
+// Snapshot serializes everything n has received, so it can be restored with
+// RestoreNode on the same Committer, say after a restart.
+func (n *Node) Snapshot() ([]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	var outPtr unsafe.Pointer
+	var outLen uint64
+	res := n.r.serializeNode(n.p, &outPtr, &outLen)
+	if res != 0 {
+		return nil, fmt.Errorf("failed to serialize node")
+	}
+	defer n.r.freeBuffer(outPtr, outLen)
+
+	// The library doesn't know about the Go side's fields, so they go first.
+	snapshot := binary.AppendUvarint(nil, uint64(n.numChunks))
+	snapshot = binary.AppendUvarint(snapshot, uint64(n.chunkSize))
+	padded := byte(0)
+	if n.padded {
+		padded = 1
+	}
+	snapshot = append(snapshot, padded)
+	return append(snapshot, unsafe.Slice((*byte)(outPtr), int(outLen))...), nil
+}
+
+// RestoreNode restores a node from the output of Node.Snapshot.
+func (c *Committer) RestoreNode(snapshot []byte) (*Node, error) {
+	if err := c.rlock(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	defer runtime.KeepAlive(c)
+	numChunks, n := binary.Uvarint(snapshot)
+	if n <= 0 || numChunks == 0 || numChunks > MaxNumChunks {
+		return nil, errors.New("invalid node snapshot")
+	}
+	snapshot = snapshot[n:]
+	chunkSize, n := binary.Uvarint(snapshot)
+	if n <= 0 || len(snapshot) < n+2 || snapshot[n] > 1 || chunkSize == 0 || chunkSize > uint64(MaxChunkSize) || chunkSize > math.MaxInt {
+		return nil, errors.New("invalid node snapshot")
+	}
+	padded := snapshot[n] == 1
+	snapshot = snapshot[n+1:]
+
+	p, err := c.r.newHandle("restore node", func() unsafe.Pointer {
+		return c.r.deserializeNode(c.p, uint32(numChunks), unsafe.Pointer(&snapshot[0]), uint64(len(snapshot)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.r.acquire()
+	c.nodes.Add(1)
+	return (&Node{r: c.r, p: p, committer: c, numChunks: int(numChunks), chunkSize: int(chunkSize), padded: padded}).track(), nil
+}
+
+// Clone returns an independent copy of n holding the same chunks, say to try
+// decoding a snapshot of a decoder while it keeps receiving. The copy uses
+// the same Committer and must be closed separately.
+func (n *Node) Clone() (*Node, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	p := n.r.cloneNode(n.p)
+	if p == nil {
+		return nil, errors.New("failed to clone node")
+	}
+	n.r.acquire()
+	n.committer.nodes.Add(1)
+	return (&Node{r: n.r, p: p, committer: n.committer, numChunks: n.numChunks, chunkSize: n.chunkSize, padded: n.padded, id: n.id, hasID: n.hasID, chunkLen: n.chunkLen}).track(), nil
+}
+
+// Close frees the node and closes its Done channel. If a DataContext or
+// ReceiveChunksContext call was abandoned, Close waits for it to finish first.
+// Calling Close again, even while the first call is running in another
+// goroutine, does nothing.
 func (n *Node) Close() {
+	n.inflight.Wait()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.p == nil {
+		return
+	}
+	if n.r.isFull(n.p) {
+		n.finish(nil)
+	} else {
+		n.finish(ErrClosed)
+	}
+	n.untrack()
 	n.r.freeNode(n.p)
+	n.p = nil
+	n.onDecoded = nil
+	n.r.release()
+	n.committer.releaseNode()
+}
+
+// lock locks n for a call into the library, or returns ErrClosed if n is
+// closed. The deferred Unlock keeps n alive through the call, like
+// runtime.KeepAlive.
+func (n *Node) lock() error {
+	n.mu.Lock()
+	if n.p == nil {
+		n.mu.Unlock()
+		return ErrClosed
+	}
+	return nil
 }
 
+// ChunkToSend returns a random combination of the chunks n holds. On a
+// decoder this recodes what it has received so far: the chunk carries the
+// same commitments and verifies like one from the source, but it only spans
+// the Rank() chunks held. Recoding needs a rank of at least 1: a decoder that
+// has received nothing returns an error wrapping ErrNoChunksHeld.
 func (n *Node) ChunkToSend() ([]byte, error) {
+	var chunk []byte
+	err := n.sent(func(s []byte) error {
+		chunk = slices.Clone(s)
+		return nil
+	})
+	return chunk, err
+}
+
+// ChunkToSendInto is ChunkToSend writing the chunk into dst and returning
+// its length, so the send path can reuse buffers. Like DataInto, it
+// allocates nothing with the rlnc_cgo backend or with purego on amd64 and
+// arm64 outside Windows. If dst is shorter than ChunkLen, it writes nothing
+// and returns a *ShortBufferError with the length needed.
+func (n *Node) ChunkToSendInto(dst []byte) (int, error) {
+	var size int
+	err := n.sent(func(s []byte) error {
+		size = len(s)
+		if len(dst) < len(s) {
+			return &ShortBufferError{Need: len(s)}
+		}
+		copy(dst, s)
+		return nil
+	})
+	return size, err
+}
+
+// sent calls f with a new chunk to send while it is still in the library's
+// buffer, freeing the buffer afterwards.
+func (n *Node) sent(f func([]byte) error) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	if err := n.checkHolds(); err != nil {
+		return err
+	}
+	out := getOutBuffer()
+	defer outBuffers.Put(out)
+	res := n.r.sendChunk(n.p, &out.ptr, &out.len)
+	if res == -2 {
+		return codeError(ErrNoChunksHeld, res)
+	}
+	if res != 0 {
+		return codeError(ErrSendFailed, res)
+	}
+	defer n.r.freeBuffer(out.ptr, out.len)
+	return f(unsafe.Slice((*byte)(out.ptr), int(out.len)))
+}
+
+// checkHolds returns ErrNoChunksHeld if n is at rank 0, so sending from a
+// relay that hasn't received anything yet never asks the library to combine
+// an empty set of chunks.
+func (n *Node) checkHolds() error {
+	if n.r.rank(n.p) == 0 {
+		return ErrNoChunksHeld
+	}
+	return nil
+}
+
+// Chunks returns count new chunks, like calling ChunkToSend count times but
+// in a single call into the library. The chunks share one backing array. Like
+// ChunkToSend, it returns an error wrapping ErrNoChunksHeld at rank 0.
+func (n *Node) Chunks(count int) ([][]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	if count <= 0 || uint64(count) > math.MaxUint32 {
+		return nil, fmt.Errorf("chunk count must be positive, got %d", count)
+	}
+	if err := n.checkHolds(); err != nil {
+		return nil, err
+	}
 	var outData unsafe.Pointer
 	var outDataLen uint64
-	res := n.r.sendChunk(n.p, &outData, &outDataLen)
+	res := n.r.sendChunks(n.p, uint32(count), &outData, &outDataLen)
+	if res == -2 {
+		return nil, codeError(ErrNoChunksHeld, res)
+	}
 	if res != 0 {
-		return nil, fmt.Errorf("failed to get chunk")
+		return nil, codeError(ErrSendFailed, res)
+	}
+	defer n.r.freeBuffer(outData, outDataLen)
+	flat := slices.Clone(unsafe.Slice((*byte)(outData), int(outDataLen)))
+	if len(flat)%count != 0 {
+		return nil, fmt.Errorf("chunks are %d bytes, not %d chunks of the same size", len(flat), count)
+	}
+	size := len(flat) / count
+	chunks := make([][]byte, count)
+	for i := range chunks {
+		chunks[i] = flat[i*size : (i+1)*size : (i+1)*size]
+	}
+	return chunks, nil
+}
+
+// ChunkLen returns the length of the chunks n sends and receives, to size
+// buffers for ChunkToSendInto. A decoder made from a deserialized Committer
+// only knows it exactly once it has received a chunk.
+func (n *Node) ChunkLen() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.chunkLen != 0 {
+		return n.chunkLen
+	}
+	return chunkWireSize(n.chunkSize, n.numChunks)
+}
+
+// SetSeed makes the coefficients of the chunks n sends from now on a function
+// of seed, so two nodes holding the same chunks and seeded alike send the same
+// chunks. It is meant for reproducing failures in tests and debugging only:
+// chunks of a seeded node are predictable.
+func (n *Node) SetSeed(seed uint64) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	n.r.setSeed(n.p, seed)
+	return nil
+}
+
+// Reset drops everything n received, along with its block ID, seed and
+// OnDecoded callback, so it can decode another block of as many chunks under
+// the same Committer. It keeps the native memory n already allocated, which
+// is cheaper than closing n and creating a new node for every block. A reset
+// source node becomes a decoder. A Done channel that was closed is replaced
+// by a new one for the next block, while one still open carries over.
+func (n *Node) Reset() error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	n.r.resetNode(n.p)
+	n.id, n.hasID = BlockID{}, false
+	n.chunkLen = 0
+	n.onDecoded = nil
+	n.doneMu.Lock()
+	if n.finished {
+		n.done, n.finished, n.doneErr = nil, false, nil
+	}
+	n.doneMu.Unlock()
+	return nil
+}
+
+// SystematicChunk returns the i-th original chunk of a source node, with the
+// i-th unit vector as its coefficients. Sending every original chunk before
+// switching to ChunkToSend lets a receiver that loses nothing decode from
+// exactly NumChunks chunks.
+func (n *Node) SystematicChunk(i int) ([]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	if i < 0 || i >= n.numChunks {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d)", i, n.numChunks)
+	}
+	var outData unsafe.Pointer
+	var outDataLen uint64
+	res := n.r.sendSystematicChunk(n.p, uint32(i), &outData, &outDataLen)
+	if res != 0 {
+		return nil, codeError(ErrSendFailed, res)
 	}
 	defer n.r.freeBuffer(outData, outDataLen)
 	s := unsafe.Slice((*byte)(outData), int(outDataLen))
@@ -137,39 +1039,639 @@ func (n *Node) ChunkToSend() ([]byte, error) {
 	return copied, nil
 }
 
-func (n *Node) ReceiveChunk(chunk []byte) error {
-	res := n.r.receiveChunk(n.p, chunk, uint64(len(chunk)))
-	switch res {
-	case 0:
+// ReceiveChunk adds chunk to n and reports whether it was innovative, that is
+// linearly independent of the chunks n already holds. A valid chunk that
+// isn't innovative is dropped without an error. A chunk of a block split into
+// a different number of chunks than n decodes is rejected with a
+// NumChunksError, one too short to be a chunk of n's block with an error
+// wrapping ErrMalformedChunk, and one of another block than the chunks n
+// already holds with a *BlockMismatchError. Neither n nor the error keeps
+// chunk, so it may be reused, say as a socket's read buffer, as soon as
+// ReceiveChunk returns.
+func (n *Node) ReceiveChunk(chunk []byte) (bool, error) {
+	return n.received(n.receiveChunk(chunk))
+}
+
+func (n *Node) receiveChunk(chunk []byte) (bool, error) {
+	if err := n.lock(); err != nil {
+		return false, err
+	}
+	defer n.mu.Unlock()
+	if err := n.checkChunk(chunk); err != nil {
+		n.stats.record(err)
+		return false, err
+	}
+	err := receiveError(n.r.receiveChunk(n.p, chunk, uint64(len(chunk))))
+	n.stats.record(err)
+	if err != nil && !errors.Is(err, ErrLinearlyDependent) {
+		return false, n.blockMismatch(err, chunkCommitments(chunk, n.numChunks))
+	}
+	n.chunkLen = len(chunk)
+	return err == nil, nil
+}
+
+// blockMismatch returns the library's error for a chunk of another block as
+// a *BlockMismatchError naming both blocks, so a misrouted chunk can be told
+// from a corrupted one in a single log line, and other errors as they are.
+// commitments are the chunk's, serialized like in a chunk.
+func (n *Node) blockMismatch(err error, commitments []byte) error {
+	if !errors.Is(err, ErrCommitmentMismatch) && !errors.Is(err, ErrChunkMismatch) {
+		return err
+	}
+	e := &BlockMismatchError{Err: err}
+	if hash, err := n.commitmentsHash(); err == nil {
+		e.Node = hash[:blockMismatchPrefix]
+	}
+	hash := sha256.Sum256(commitments)
+	e.Chunk = hash[:blockMismatchPrefix]
+	return e
+}
+
+// received fires the OnDecoded callback after an innovative receive, once n
+// is unlocked, since the receive may have completed n.
+func (n *Node) received(innovative bool, err error) (bool, error) {
+	if !innovative {
+		return false, err
+	}
+	return true, n.fireDecoded()
+}
+
+// OnDecoded registers f to be called with the decoded block once n is full.
+// It fires once, synchronously from the receive that completes n, so f should
+// hand long work off to a goroutine. If n is already full it fires right away
+// instead. A chunk that fails to verify doesn't complete n, so f doesn't fire
+// for it, and f is dropped without being called if n is closed first.
+// Registering again replaces f if it hasn't fired yet. Clones don't inherit f.
+func (n *Node) OnDecoded(f func(data []byte)) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	n.onDecoded = f
+	n.mu.Unlock()
+	return n.fireDecoded()
+}
+
+// fireDecoded calls the OnDecoded callback if n is full and it hasn't fired
+// yet. A failure decoding the block is returned to the receive that completed
+// n, and the callback is dropped. It must be called with n unlocked, so the
+// callback can use n.
+func (n *Node) fireDecoded() error {
+	f, data, err := n.takeDecoded()
+	if err != nil {
+		return fmt.Errorf("decoding completed block: %w", err)
+	}
+	if f != nil {
+		f(data)
+	}
+	return nil
+}
+
+// takeDecoded closes the Done channel if n is full, and takes the OnDecoded
+// callback along with the block to call it with, if it is set.
+func (n *Node) takeDecoded() (func(data []byte), []byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.p == nil || (n.onDecoded == nil && !n.watched()) || !n.r.isFull(n.p) {
+		return nil, nil, nil
+	}
+	n.finish(nil)
+	if n.onDecoded == nil {
+		return nil, nil, nil
+	}
+	f := n.onDecoded
+	n.onDecoded = nil
+	var data []byte
+	err := n.decodedLocked(false, func(s []byte) error {
+		data = slices.Clone(s)
 		return nil
-	case -1:
-		return fmt.Errorf("failed to receive chunk")
-	case -2:
-		return fmt.Errorf("existing commitments mismatch")
-	case -3:
-		return fmt.Errorf("existing chunks mismatch")
-	case -4:
-		return fmt.Errorf("invalid message")
-	case -5:
-		return fmt.Errorf("linearly dependent chunk")
-	default:
-		return fmt.Errorf("unknown error")
+	})
+	return f, data, err
+}
+
+// Done returns a channel that is closed once n is full, by the time the
+// receive that completed it returns, or once n is closed before that,
+// whichever comes first. Err tells which. It can be called before any chunk
+// arrives, and waiting on it doesn't start a goroutine.
+func (n *Node) Done() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.doneMu.Lock()
+	defer n.doneMu.Unlock()
+	if n.done == nil {
+		n.done = make(chan struct{})
+		switch {
+		case n.finished:
+		case n.p == nil:
+			n.finished, n.doneErr = true, ErrClosed
+		case n.r.isFull(n.p):
+			n.finished = true
+		}
+		if n.finished {
+			close(n.done)
+		}
+	}
+	return n.done
+}
+
+// Err returns nil until Done is closed. Then it returns nil if n became full
+// and ErrClosed if it was closed first.
+func (n *Node) Err() error {
+	n.doneMu.Lock()
+	defer n.doneMu.Unlock()
+	return n.doneErr
+}
+
+// watched reports whether Done was called, so completing n must close it.
+func (n *Node) watched() bool {
+	n.doneMu.Lock()
+	defer n.doneMu.Unlock()
+	return n.done != nil
+}
+
+// finish closes the Done channel, if it wasn't already, with Err reporting
+// err.
+func (n *Node) finish(err error) {
+	n.doneMu.Lock()
+	defer n.doneMu.Unlock()
+	if n.finished {
+		return
+	}
+	n.finished = true
+	n.doneErr = err
+	if n.done != nil {
+		close(n.done)
+	}
+}
+
+// ReceiveSourceChunk adds the original chunk at index, as plain bytes, to n
+// and reports whether it was innovative, like ReceiveChunk. It's checked
+// against the commitments n learned from the chunks it received, so a node
+// that has none yet returns an error wrapping ErrNoCommitments; use
+// ReceiveSourceChunkWithCommitments for the first chunk instead.
+func (n *Node) ReceiveSourceChunk(index int, data []byte) (bool, error) {
+	return n.receiveSource(index, data, nil)
+}
+
+// ReceiveSourceChunkWithCommitments is ReceiveSourceChunk for a node that
+// may not know the commitments of its block yet. A node that does rejects
+// other commitments with a *BlockMismatchError, which matches
+// ErrCommitmentMismatch too.
+func (n *Node) ReceiveSourceChunkWithCommitments(index int, data []byte, commitments []Point) (bool, error) {
+	if len(commitments) == 0 {
+		return false, errors.New("no commitments given")
 	}
+	return n.receiveSource(index, data, appendVector(nil, commitments))
+}
+
+func (n *Node) receiveSource(index int, data, commitments []byte) (bool, error) {
+	return n.received(n.addSource(index, data, commitments))
 }
 
+func (n *Node) addSource(index int, data, commitments []byte) (bool, error) {
+	if err := n.lock(); err != nil {
+		return false, err
+	}
+	defer n.mu.Unlock()
+	if index < 0 || index >= n.numChunks {
+		return false, fmt.Errorf("chunk index %d out of range [0, %d)", index, n.numChunks)
+	}
+	if len(data) == 0 || len(data)%32 != 0 {
+		return false, fmt.Errorf("chunk of %d bytes isn't a positive multiple of 32 bytes", len(data))
+	}
+	err := receiveError(n.r.receiveSourceChunk(n.p, uint32(index), data, uint64(len(data)), commitments, uint64(len(commitments))))
+	n.stats.record(err)
+	if err != nil && !errors.Is(err, ErrLinearlyDependent) {
+		if commitments != nil {
+			err = n.blockMismatch(err, commitments)
+		}
+		return false, err
+	}
+	n.chunkLen = chunkWireSize(len(data), n.numChunks)
+	return err == nil, nil
+}
+
+// checkChunk fails fast on chunks the library would reject anyway, so junk
+// never costs a call into it. One with coefficients for a different number
+// of chunks than n decodes, which the library would only report as a
+// confusing mismatch, gets a NumChunksError. One shorter than the smallest
+// chunk n could decode, a single scalar of payload with a coefficient and
+// commitment per chunk, or not framed like a chunk of n's block gets an error
+// wrapping ErrMalformedChunk saying which segment is wrong.
+func (n *Node) checkChunk(chunk []byte) error {
+	if count, ok := coefficientCount(chunk); ok && count != uint64(n.numChunks) {
+		return &NumChunksError{Node: n.numChunks, Chunk: count}
+	}
+	if smallest := chunkWireSize(1, n.numChunks); len(chunk) < smallest {
+		return fmt.Errorf("%w: %d bytes is shorter than the %d of the smallest chunk", ErrMalformedChunk, len(chunk), smallest)
+	}
+	return checkChunkFrame(chunk, n.numChunks, int(chunkSizeInScalars(int64(n.chunkSize))))
+}
+
+// ReceiveChunks receives a batch of chunks in one call into the library and
+// returns how many were innovative, along with the error of each chunk, like
+// ReceiveChunk. Once the batch fills n, the chunks left are skipped without
+// being verified and count as not innovative, with a nil error. An OnDecoded
+// callback fires once the whole batch is received. Like for ReceiveChunk, the
+// chunks may be reused once ReceiveChunks returns.
+func (n *Node) ReceiveChunks(chunks [][]byte) (innovative int, errs []error) {
+	innovative, errs, last := n.receiveChunks(chunks)
+	if last >= 0 {
+		// The last innovative chunk is the one that could have completed n.
+		errs[last] = n.fireDecoded()
+	}
+	return innovative, errs
+}
+
+// receiveChunks is ReceiveChunks up to firing the OnDecoded callback, and
+// also returns the index of the last innovative chunk, or -1.
+func (n *Node) receiveChunks(chunks [][]byte) (innovative int, errs []error, last int) {
+	errs = make([]error, len(chunks))
+	if err := n.lock(); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return 0, errs, -1
+	}
+	defer n.mu.Unlock()
+	if len(chunks) == 0 {
+		return 0, errs, -1
+	}
+
+	// Only the chunks that pass checkChunk are sent to the library, at
+	// the indices in sent.
+	sent := make([]int, 0, len(chunks))
+	lens := make([]uint64, 0, len(chunks))
+	total := 0
+	for i, chunk := range chunks {
+		if errs[i] = n.checkChunk(chunk); errs[i] != nil {
+			n.stats.record(errs[i])
+			continue
+		}
+		sent = append(sent, i)
+		lens = append(lens, uint64(len(chunk)))
+		total += len(chunk)
+	}
+	if len(sent) == 0 {
+		return 0, errs, -1
+	}
+	flat := make([]byte, 0, total)
+	for _, i := range sent {
+		flat = append(flat, chunks[i]...)
+	}
+	codes := make([]int32, len(sent))
+	innovative = int(n.r.receiveChunks(n.p, flat, lens, uint64(len(sent)), codes))
+	last = -1
+	for j, code := range codes {
+		i := sent[j]
+		err := receiveError(code)
+		n.stats.record(err)
+		if err != nil && !errors.Is(err, ErrLinearlyDependent) {
+			errs[i] = n.blockMismatch(err, chunkCommitments(chunks[i], n.numChunks))
+			continue
+		}
+		n.chunkLen = len(chunks[i])
+		if err == nil {
+			last = i
+		}
+	}
+	return innovative, errs, last
+}
+
+// ReceiveChunksContext is ReceiveChunks, but returns ctx.Err() as soon as ctx
+// is done. The batch can't be interrupted, so it is still received in the
+// background, and Rank tells how far it got once Close has waited for it.
+// That works on a copy of the batch, so the chunks may be reused once
+// ReceiveChunksContext returns either way.
+func (n *Node) ReceiveChunksContext(ctx context.Context, chunks [][]byte) (int, []error, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	chunks = cloneChunks(chunks)
+	type result struct {
+		innovative int
+		errs       []error
+	}
+	done := make(chan result, 1)
+	n.inflight.Add(1)
+	go func() {
+		defer n.inflight.Done()
+		innovative, errs := n.ReceiveChunks(chunks)
+		done <- result{innovative, errs}
+	}()
+	select {
+	case res := <-done:
+		return res.innovative, res.errs, nil
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// cloneChunks copies chunks into a single allocation.
+func cloneChunks(chunks [][]byte) [][]byte {
+	total := 0
+	for _, chunk := range chunks {
+		total += len(chunk)
+	}
+	flat := make([]byte, 0, total)
+	cloned := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		flat = append(flat, chunk...)
+		cloned[i] = flat[len(flat)-len(chunk):]
+	}
+	return cloned
+}
+
+// Data returns the decoded block in a new slice. Until n is full it returns
+// a NotReadyError, matching ErrNotReady, rather than ErrDecodeFailed.
 func (n *Node) Data() ([]byte, error) {
+	var data []byte
+	err := n.decoded(func(s []byte) error {
+		data = slices.Clone(s)
+		return nil
+	})
+	return data, err
+}
+
+// DataInto copies the decoded block into dst and returns its size, so a
+// decoding pipeline can reuse one buffer without allocating. That holds for
+// the rlnc_cgo backend and for purego on amd64 and arm64 outside Windows;
+// elsewhere purego allocates a few small objects per call. If dst is too
+// small, it copies nothing and returns a *ShortBufferError with the size
+// needed.
+func (n *Node) DataInto(dst []byte) (int, error) {
+	var size int
+	err := n.decoded(func(s []byte) error {
+		size = len(s)
+		if len(dst) < len(s) {
+			return &ShortBufferError{Need: len(s)}
+		}
+		copy(dst, s)
+		return nil
+	})
+	return size, err
+}
+
+// VerifiedData is Data, but first checks that the decoded block matches the
+// commitments n holds, at the cost of committing to every chunk again. Data
+// trusts that chunks which verified one by one decode to the block they were
+// committed from; VerifiedData returns an error wrapping
+// ErrDecodeVerificationFailed if they don't.
+func (n *Node) VerifiedData() ([]byte, error) {
+	var data []byte
+	err := n.decodedBy(true, func(s []byte) error {
+		data = slices.Clone(s)
+		return nil
+	})
+	return data, err
+}
+
+// decoded calls f with the decoded block while it is still in the library's
+// buffer, freeing the buffer afterwards.
+func (n *Node) decoded(f func([]byte) error) error {
+	return n.decodedBy(false, f)
+}
+
+// decodedBy is decoded, checking the block against the commitments if verify
+// is set.
+func (n *Node) decodedBy(verify bool, f func([]byte) error) error {
+	if err := n.lock(); err != nil {
+		return err
+	}
+	defer n.mu.Unlock()
+	return n.decodedLocked(verify, f)
+}
+
+// decodedLocked is decodedBy with n already locked.
+func (n *Node) decodedLocked(verify bool, f func([]byte) error) error {
+	if rank := int(n.r.rank(n.p)); rank < n.numChunks {
+		return &NotReadyError{Rank: rank, NumChunks: n.numChunks}
+	}
+	out := getOutBuffer()
+	defer outBuffers.Put(out)
+	var res int32
+	if verify {
+		res = n.r.decodeVerified(n.p, &out.ptr, &out.len)
+	} else {
+		res = n.r.decode(n.p, &out.ptr, &out.len)
+	}
+	if res == -3 && verify {
+		return codeError(ErrDecodeVerificationFailed, res)
+	}
+	// Otherwise -1 is a node that isn't full and -2 a failed decode, and
+	// neither sets the out pointers.
+	if res != 0 {
+		return codeError(ErrDecodeFailed, res)
+	}
+	defer n.r.freeBuffer(out.ptr, out.len)
+	s, err := n.unpad(unsafe.Slice((*byte)(out.ptr), int(out.len)))
+	if err != nil {
+		return err
+	}
+	return f(s)
+}
+
+// outBuffer holds the pointer and length a library function returns a buffer
+// through. Calls through the function table make locals passed by address
+// escape, so the paths meant not to allocate take one from outBuffers.
+type outBuffer struct {
+	ptr unsafe.Pointer
+	len uint64
+}
+
+var outBuffers = sync.Pool{New: func() any { return new(outBuffer) }}
+
+func getOutBuffer() *outBuffer {
+	out := outBuffers.Get().(*outBuffer)
+	*out = outBuffer{}
+	return out
+}
+
+// DecodedChunks returns the original chunks n can already recover, by index,
+// before it is full. A source node, or a decoder that got systematic chunks,
+// recovers those chunks right away, which lets streaming consumers use them
+// without waiting for the whole block. The chunks of a padded node are
+// returned with their padding.
+func (n *Node) DecodedChunks() (map[int][]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
 	var outData unsafe.Pointer
 	var outDataLen uint64
-	res := n.r.decode(n.p, &outData, &outDataLen)
+	res := n.r.decodedChunks(n.p, &outData, &outDataLen)
 	if res != 0 {
-		return nil, fmt.Errorf("failed to get data")
+		return nil, codeError(ErrDecodeFailed, res)
 	}
 	defer n.r.freeBuffer(outData, outDataLen)
 	s := unsafe.Slice((*byte)(outData), int(outDataLen))
-	copied := slices.Clone(s)
-	return copied, nil
+
+	// A count, the indices and then the chunks, all the same size.
+	if len(s) < 4 {
+		return nil, errors.New("decoded chunks are truncated")
+	}
+	count := int(binary.LittleEndian.Uint32(s))
+	s = s[4:]
+	if count == 0 {
+		return map[int][]byte{}, nil
+	}
+	if len(s) < 4*count || (len(s)-4*count)%count != 0 {
+		return nil, fmt.Errorf("decoded chunks are %d bytes, not %d chunks", len(s), count)
+	}
+	indices, data := s[:4*count], s[4*count:]
+	size := len(data) / count
+	chunks := make(map[int][]byte, count)
+	for i := range count {
+		chunks[int(binary.LittleEndian.Uint32(indices[4*i:]))] = slices.Clone(data[i*size : (i+1)*size])
+	}
+	return chunks, nil
+}
+
+// DataContext is Data, but returns ctx.Err() as soon as ctx is done. The
+// decode itself can't be interrupted, so it keeps running in the background
+// and its buffer is freed when it finishes.
+func (n *Node) DataContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	n.mu.Unlock()
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	n.inflight.Add(1)
+	go func() {
+		defer n.inflight.Done()
+		data, err := n.Data()
+		done <- result{data, err}
+	}()
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
+// WriteTo writes the decoded block to w straight from the library's buffer,
+// saving the copy Data makes.
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	var written int
+	err := n.decoded(func(s []byte) error {
+		var err error
+		written, err = w.Write(s)
+		if err == nil && written != len(s) {
+			err = io.ErrShortWrite
+		}
+		return err
+	})
+	return int64(written), err
+}
+
+func (n *Node) unpad(block []byte) ([]byte, error) {
+	if !n.padded {
+		return block, nil
+	}
+	return UnpadBlock(block)
+}
+
+// CommitmentsHash returns the hash of the commitments of the block n holds,
+// the same as CommitmentsHash of its chunks. A decoder only knows it once it
+// has received a chunk.
+func (n *Node) CommitmentsHash() ([]byte, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	return n.commitmentsHash()
+}
+
+// commitmentsHash is CommitmentsHash with n already locked.
+func (n *Node) commitmentsHash() ([]byte, error) {
+	var outPtr unsafe.Pointer
+	var outLen uint64
+	if res := n.r.nodeCommitmentsHash(n.p, &outPtr, &outLen); res != 0 {
+		return nil, errors.New("node has received no chunks yet")
+	}
+	defer n.r.freeBuffer(outPtr, outLen)
+	return slices.Clone(unsafe.Slice((*byte)(outPtr), int(outLen))), nil
+}
+
+// Remaining returns how many more innovative chunks n needs to decode.
+func (n *Node) Remaining() int {
+	return n.numChunks - n.Rank()
+}
+
+// Progress returns the fraction of the chunks needed to decode that n holds,
+// from 0 for a fresh decoder to 1 once it is full.
+func (n *Node) Progress() float64 {
+	if n.numChunks == 0 {
+		return 0
+	}
+	return float64(n.Rank()) / float64(n.numChunks)
+}
+
+// Coefficients returns the coefficient vector of each chunk n holds, so
+// Rank() rows of NumChunks() scalars. A source node holds the unit vectors.
+func (n *Node) Coefficients() ([][]Scalar, error) {
+	if err := n.lock(); err != nil {
+		return nil, err
+	}
+	defer n.mu.Unlock()
+	var outData unsafe.Pointer
+	var outDataLen uint64
+	res := n.r.coefficients(n.p, &outData, &outDataLen)
+	if res != 0 {
+		return nil, fmt.Errorf("failed to get coefficients")
+	}
+	defer n.r.freeBuffer(outData, outDataLen)
+	s := unsafe.Slice((*byte)(outData), int(outDataLen))
+	rowLen := n.numChunks * len(Scalar{})
+	if rowLen == 0 || len(s)%rowLen != 0 {
+		return nil, fmt.Errorf("coefficients are %d bytes, not rows of %d scalars", len(s), n.numChunks)
+	}
+
+	rows := make([][]Scalar, len(s)/rowLen)
+	for i := range rows {
+		rows[i] = make([]Scalar, n.numChunks)
+		for j := range rows[i] {
+			copy(rows[i][j][:], s[i*rowLen+j*len(Scalar{}):])
+		}
+	}
+	return rows, nil
+}
+
+// NumChunks returns the number of chunks the block held by n is split into.
+func (n *Node) NumChunks() int {
+	return n.numChunks
+}
+
+// ChunkSize returns the payload bytes in each original chunk, so Data
+// returns NumChunks()*ChunkSize() bytes, less any padding. A decoder made
+// from a deserialized Committer assumes the largest chunks it supports.
+func (n *Node) ChunkSize() int {
+	return n.chunkSize
+}
+
+// IsFull reports whether n holds enough chunks to decode. A closed node
+// isn't full.
 func (n *Node) IsFull() bool {
-	return n.r.isFull(n.p)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.p != nil && n.r.isFull(n.p)
+}
+
+// Rank returns the number of linearly independent chunks n holds. It reaches
+// the node's numChunks exactly when IsFull is true, and is 0 once n is
+// closed.
+func (n *Node) Rank() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.p == nil {
+		return 0
+	}
+	return int(n.r.rank(n.p))
 }
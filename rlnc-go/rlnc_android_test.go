@@ -0,0 +1,61 @@
+//go:build android
+
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestAndroidRoundTrip(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 32*numChunks)
+	rand.Read(data)
+	// Keep every 32 byte word below the scalar field modulus.
+	for i := 31; i < len(data); i += 32 {
+		data[i] = 0
+	}
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	destData, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build rlnc_nolib
+
+package rlnc
+
+import "testing"
+
+func TestSystemLibrary(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	committer, err := rlnc.GenCommitterForMessage(31*512*8, 8)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	committer.Close()
+}
@@ -2,10 +2,29 @@ package rlnc
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(RunAndCleanup(m))
+}
+
 func TestRoundTrip(t *testing.T) {
 	rlnc, err := NewRLNC()
 	if err != nil {
@@ -16,7 +35,7 @@ func TestRoundTrip(t *testing.T) {
 	numChunks := 8
 	chunkSize := 31 * 512
 
-	committer, err := rlnc.GenCommitter(chunkSize / 31)
+	committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
 	if err != nil {
 		t.Fatalf("Error creating committer: %v", err)
 	}
@@ -28,8 +47,10 @@ func TestRoundTrip(t *testing.T) {
 	}
 
 	{
-		var roundTripped Committer
-		roundTripped.Deserialize(rlnc, serialized)
+		roundTripped, err := DeserializeCommitter(rlnc, serialized)
+		if err != nil {
+			t.Fatalf("Error deserializing committer: %v", err)
+		}
 		roundTripped.Close()
 	}
 
@@ -42,8 +63,28 @@ func TestRoundTrip(t *testing.T) {
 	}
 	defer sourceNode.Close()
 
-	destinationNode := committer.NewNode(numChunks)
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
 	defer destinationNode.Close()
+	for _, node := range []*Node{sourceNode, destinationNode} {
+		if node.NumChunks() != numChunks || node.ChunkSize() != chunkSize {
+			t.Fatalf("Expected %d chunks of %d bytes, got %d of %d", numChunks, chunkSize, node.NumChunks(), node.ChunkSize())
+		}
+	}
+	if rank := destinationNode.Rank(); rank != 0 {
+		t.Fatalf("Expected a fresh node to have rank 0, got %d", rank)
+	}
+	if rank := sourceNode.Rank(); rank != numChunks {
+		t.Fatalf("Expected the source node to have rank %d, got %d", numChunks, rank)
+	}
+	if sourceNode.Remaining() != 0 || sourceNode.Progress() != 1 {
+		t.Fatalf("Expected the source node to be complete, got %d remaining, %v progress", sourceNode.Remaining(), sourceNode.Progress())
+	}
+	if destinationNode.Remaining() != numChunks || destinationNode.Progress() != 0 {
+		t.Fatalf("Expected a fresh node to have no progress, got %d remaining, %v progress", destinationNode.Remaining(), destinationNode.Progress())
+	}
 
 	chunkToSend, err := sourceNode.ChunkToSend()
 	if err != nil {
@@ -55,16 +96,44 @@ func TestRoundTrip(t *testing.T) {
 	}
 	t.Logf("Commitments hash: %x", commitmentsHash)
 
-	for i := 0; i < int(numChunks); i++ {
+	innovative := 0
+	progress := 0.0
+	for i := 0; i < numChunks+2; i++ {
 		chunkToSend, err := sourceNode.ChunkToSend()
 		if err != nil {
 			t.Fatalf("Error getting chunk to send: %v", err)
 		}
 
-		err = destinationNode.ReceiveChunk(chunkToSend)
+		ok, err := destinationNode.ReceiveChunk(chunkToSend)
 		if err != nil {
 			t.Fatalf("Error receiving chunk: %v", err)
 		}
+		if ok {
+			innovative++
+		}
+		if rank := destinationNode.Rank(); rank != innovative {
+			t.Fatalf("Expected rank %d after %d innovative chunks, got %d", innovative, innovative, rank)
+		}
+		if p := destinationNode.Progress(); p < progress || p > 1 {
+			t.Fatalf("Expected progress to grow from %v up to 1, got %v", progress, p)
+		}
+		progress = destinationNode.Progress()
+		if remaining := destinationNode.Remaining(); remaining != numChunks-innovative {
+			t.Fatalf("Expected %d chunks remaining, got %d", numChunks-innovative, remaining)
+		}
+		if innovative < numChunks {
+			var notReady *NotReadyError
+			_, err := destinationNode.Data()
+			if !errors.Is(err, ErrNotReady) || !errors.As(err, &notReady) || notReady.Rank != innovative || notReady.NumChunks != numChunks {
+				t.Fatalf("Expected ErrNotReady at rank %d of %d, got %v", innovative, numChunks, err)
+			}
+			if errors.Is(err, ErrDecodeFailed) {
+				t.Fatalf("Expected a node that isn't full not to report a decode failure")
+			}
+		}
+	}
+	if innovative != numChunks {
+		t.Fatalf("Expected exactly 2 non-innovative chunks, got %d", numChunks+2-innovative)
 	}
 
 	if !destinationNode.IsFull() {
@@ -75,7 +144,2992 @@ func TestRoundTrip(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error getting data: %v", err)
 	}
+	var written bytes.Buffer
+	if _, err := destinationNode.WriteTo(&written); err != nil {
+		t.Fatalf("Error writing data: %v", err)
+	}
+	if !bytes.Equal(data, written.Bytes()) {
+		t.Fatalf("Written data doesn't match the source data")
+	}
+	if len(destData) != destinationNode.NumChunks()*destinationNode.ChunkSize() {
+		t.Fatalf("Expected %d bytes of data, got %d", destinationNode.NumChunks()*destinationNode.ChunkSize(), len(destData))
+	}
 	if !bytes.Equal(data, destData) {
 		t.Fatalf("Source and destination nodes do not have the same data")
 	}
 }
+
+// TestChunkSizeInScalars runs on 32-bit platforms too, for example with
+// GOARCH=386 go test -tags rlnc_wasm, where chunk sizes past 256MB used to
+// overflow.
+func TestChunkSizeInScalars(t *testing.T) {
+	// A scalar holds 252 bits.
+	for _, tc := range []struct{ chunkSize, scalars int64 }{
+		{1, 1},
+		{31, 1},
+		{32, 2},
+		{62, 2},
+		{63, 2},
+		{64, 3},
+		{1<<28 - 1, 8521761},
+		{1 << 28, 8521761},
+		{math.MaxInt32, 68174085},
+		{MaxChunkSize, math.MaxUint32},
+		{MaxChunkSize + 1, math.MaxUint32 + 1},
+		{math.MaxInt64, 292805461487453201},
+	} {
+		if got := chunkSizeInScalars(tc.chunkSize); got != tc.scalars {
+			t.Fatalf("Expected %d scalars for %d bytes, got %d", tc.scalars, tc.chunkSize, got)
+		}
+	}
+
+	// Check it against the rounded up division it stands for, which
+	// doesn't overflow for the sizes around the int32 boundary in int64.
+	for chunkSize := int64(1<<28 - 1000); chunkSize < 1<<28+1000; chunkSize++ {
+		if got, want := chunkSizeInScalars(chunkSize), (chunkSize*8+251)/252; got != want {
+			t.Fatalf("Expected %d scalars for %d bytes, got %d", want, chunkSize, got)
+		}
+	}
+	for chunkSize := int64(1); chunkSize < 10000; chunkSize++ {
+		if got, want := chunkSizeInScalars(chunkSize), (chunkSize*8+251)/252; got != want {
+			t.Fatalf("Expected %d scalars for %d bytes, got %d", want, chunkSize, got)
+		}
+	}
+
+	// The library takes the count as a uint32, which the checks keep to.
+	if err := checkChunkSize(MaxChunkSize); err != nil {
+		t.Fatalf("Expected MaxChunkSize to be accepted, got %v", err)
+	}
+	if err := checkChunkSize(MaxChunkSize + 1); err == nil || !strings.Contains(err.Error(), fmt.Sprint(MaxChunkSize)) {
+		t.Fatalf("Expected an error naming the limit past MaxChunkSize, got %v", err)
+	}
+}
+
+func TestCommitterGeometry(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	for _, tc := range []struct{ chunkSize, scalars int }{
+		{32, 2},
+		{63 * 32, 64},
+		{64 * 32, 66},
+		{31 * 512, 504},
+	} {
+		committer, err := rlnc.GenCommitterForChunkSize(tc.chunkSize)
+		if err != nil {
+			t.Fatalf("Error creating committer: %v", err)
+		}
+		defer committer.Close()
+		if got := committer.ChunkSizeScalars(); got != tc.scalars {
+			t.Fatalf("Expected %d scalars for %d byte chunks, got %d", tc.scalars, tc.chunkSize, got)
+		}
+		if got := committer.ChunkSizeBytes(); got != tc.chunkSize {
+			t.Fatalf("Expected %d byte chunks, got %d", tc.chunkSize, got)
+		}
+		if got := committer.MaxMessageSize(8); got != 8*tc.chunkSize {
+			t.Fatalf("Expected a %d byte max message, got %d", 8*tc.chunkSize, got)
+		}
+	}
+
+	committer, err := rlnc.GenCommitterForChunkSize(32 * 512)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	if _, err := committer.NewSourceNode(make([]byte, 32*513*2), 2); err == nil || !strings.Contains(err.Error(), "committer supports 521") {
+		t.Fatalf("Expected an error for a block too large for the committer, got %v", err)
+	}
+	if _, err := committer.NewSourceNode(make([]byte, 31*2), 2); err == nil {
+		t.Fatalf("Expected an error for chunks that aren't a multiple of 32 bytes")
+	}
+}
+
+func TestCoefficients(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	coeffs, err := sourceNode.Coefficients()
+	if err != nil {
+		t.Fatalf("Error getting coefficients: %v", err)
+	}
+	for i, row := range coeffs {
+		for j, x := range row {
+			var want Scalar
+			if i == j {
+				want[0] = 1
+			}
+			if x != want {
+				t.Fatalf("Expected the source node to hold unit vectors, got %x at %d,%d", x, i, j)
+			}
+		}
+	}
+
+	coeffs, err = destinationNode.Coefficients()
+	if err != nil {
+		t.Fatalf("Error getting coefficients: %v", err)
+	}
+	if len(coeffs) != 0 {
+		t.Fatalf("Expected no coefficients on a fresh node, got %d rows", len(coeffs))
+	}
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	coeffs, err = destinationNode.Coefficients()
+	if err != nil {
+		t.Fatalf("Error getting coefficients: %v", err)
+	}
+	if len(coeffs) != 1 || len(coeffs[0]) != numChunks {
+		t.Fatalf("Expected 1 row of %d coefficients, got %d rows", numChunks, len(coeffs))
+	}
+	// The coefficients follow the data scalars and their length on the wire.
+	dataLen := binary.LittleEndian.Uint64(chunk)
+	wire := chunk[8+32*dataLen+8:]
+	for j, x := range coeffs[0] {
+		if !bytes.Equal(x[:], wire[32*j:32*(j+1)]) {
+			t.Fatalf("Coefficient %d doesn't match the chunk's", j)
+		}
+	}
+}
+
+func TestVerifiedData(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	if _, err := destinationNode.VerifiedData(); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Expected ErrNotReady from an empty node, got %v", err)
+	}
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	got, err := destinationNode.VerifiedData()
+	if err != nil {
+		t.Fatalf("Error getting verified data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Verified data doesn't match")
+	}
+
+	// Restoring a snapshot doesn't verify the chunks in it, so flipping a bit
+	// of the first chunk held corrupts what the node decodes. The snapshot
+	// header is followed by the chunks, prefixed by their count and length.
+	snapshot, err := destinationNode.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	header := len(binary.AppendUvarint(binary.AppendUvarint(nil, uint64(numChunks)), uint64(len(data)/numChunks))) + 1
+	snapshot[header+16] ^= 1
+	corrupted, err := committer.RestoreNode(snapshot)
+	if err != nil {
+		t.Fatalf("Error restoring node: %v", err)
+	}
+	defer corrupted.Close()
+	if got, err := corrupted.Data(); err != nil || bytes.Equal(data, got) {
+		t.Fatalf("Expected Data to decode the corrupted chunk unchecked, got %v", err)
+	}
+	if _, err := corrupted.VerifiedData(); !errors.Is(err, ErrDecodeVerificationFailed) {
+		t.Fatalf("Expected ErrDecodeVerificationFailed, got %v", err)
+	}
+}
+
+func TestSetSeed(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	chunks := func(seed uint64) [][]byte {
+		t.Helper()
+		sourceNode, err := committer.NewSourceNode(data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer sourceNode.Close()
+		if err := sourceNode.SetSeed(seed); err != nil {
+			t.Fatalf("Error seeding source node: %v", err)
+		}
+		var chunks [][]byte
+		for range 8 {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			chunks = append(chunks, chunk)
+		}
+		return chunks
+	}
+
+	a, b, other := chunks(42), chunks(42), chunks(43)
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			t.Fatalf("Chunk %d differs between nodes with the same seed", i)
+		}
+	}
+	if bytes.Equal(a[0], other[0]) {
+		t.Fatalf("Nodes with different seeds sent the same chunk")
+	}
+}
+
+func TestSystematicChunks(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	for i := 0; i < numChunks; i++ {
+		chunk, err := sourceNode.SystematicChunk(i)
+		if err != nil {
+			t.Fatalf("Error getting systematic chunk %d: %v", i, err)
+		}
+		if ok, err := destinationNode.ReceiveChunk(chunk); !ok || err != nil {
+			t.Fatalf("Expected systematic chunk %d to be innovative, got %v, %v", i, ok, err)
+		}
+	}
+	if !destinationNode.IsFull() {
+		t.Fatalf("Destination node is not full after %d systematic chunks", numChunks)
+	}
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+
+	if _, err := sourceNode.SystematicChunk(numChunks); err == nil {
+		t.Fatalf("Expected an error for an out of range index")
+	}
+
+	relayNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer relayNode.Close()
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	if _, err := relayNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	if _, err := relayNode.SystematicChunk(0); !errors.Is(err, ErrSendFailed) {
+		t.Fatalf("Expected ErrSendFailed from a node without the original chunks, got %v", err)
+	}
+}
+
+func TestRecoding(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	relayNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer relayNode.Close()
+	sinkNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer sinkNode.Close()
+
+	if _, err := relayNode.ChunkToSend(); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld from a relay at rank 0, got %v", err)
+	}
+
+	forward := func(from, to *Node, count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			chunk, err := from.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			if _, err := to.ReceiveChunk(chunk); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+	}
+
+	// A relay at partial rank can only pass on what it holds.
+	forward(sourceNode, relayNode, 2)
+	forward(relayNode, sinkNode, numChunks)
+	if rank := sinkNode.Rank(); rank != relayNode.Rank() {
+		t.Fatalf("Expected the sink to reach the relay's rank %d, got %d", relayNode.Rank(), rank)
+	}
+
+	for !relayNode.IsFull() {
+		forward(sourceNode, relayNode, 1)
+	}
+	for !sinkNode.IsFull() {
+		forward(relayNode, sinkNode, 1)
+	}
+	got, err := sinkNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded from recoded chunks doesn't match")
+	}
+}
+
+func TestCommitterFingerprint(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	committer, err := rlnc.GenCommitterForChunkSize(32 * 63)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	fingerprint, err := committer.Fingerprint()
+	if err != nil {
+		t.Fatalf("Error getting fingerprint: %v", err)
+	}
+
+	serialized, err := committer.Serialize()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+	roundTripped, err := DeserializeCommitter(rlnc, serialized)
+	if err != nil {
+		t.Fatalf("Error deserializing committer: %v", err)
+	}
+	defer roundTripped.Close()
+	same, err := rlnc.GenCommitterForChunkSize(32 * 63)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer same.Close()
+	other, err := rlnc.GenCommitterForChunkSize(32 * 64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer other.Close()
+
+	for _, tc := range []struct {
+		name  string
+		other *Committer
+		equal bool
+	}{
+		{"round tripped", roundTripped, true},
+		{"same size", same, true},
+		{"other size", other, false},
+	} {
+		equal, err := committer.Equal(tc.other)
+		if err != nil {
+			t.Fatalf("Error comparing committers: %v", err)
+		}
+		if equal != tc.equal {
+			t.Fatalf("Expected the %s committer to be equal: %v, got %v", tc.name, tc.equal, equal)
+		}
+	}
+
+	// Committers are derived from their size alone, so the fingerprint is
+	// the same in every process.
+	const golden = "e216571150bec6ef2716bc9c93e0824f64ada19b41b5d56472c9792c9f1b9dd5"
+	if got := hex.EncodeToString(fingerprint[:]); got != golden {
+		t.Fatalf("Expected fingerprint %s, got %s", golden, got)
+	}
+}
+
+func TestCommitterMarshalBinary(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	serialized, err := committer.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error marshaling committer: %v", err)
+	}
+
+	var restored Committer
+	if err := restored.UnmarshalBinary(serialized); err != nil {
+		t.Fatalf("Error unmarshaling committer: %v", err)
+	}
+	defer restored.Close()
+	if restored.ChunkSizeScalars() != committer.ChunkSizeScalars() {
+		t.Fatalf("Expected %d scalar chunks, got %d", committer.ChunkSizeScalars(), restored.ChunkSizeScalars())
+	}
+
+	// Chunks committed to with the original must verify with the copy.
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := restored.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	garbage := make([]byte, len(serialized))
+	rand.Read(garbage)
+	for _, b := range [][]byte{nil, serialized[:len(serialized)/2], serialized[:7], garbage} {
+		if _, err := DeserializeCommitter(rlnc, b); !errors.Is(err, ErrInvalidCommitter) {
+			t.Fatalf("Expected ErrInvalidCommitter deserializing %d bytes, got %v", len(b), err)
+		}
+	}
+}
+
+func TestDeserializeCommitterGarbage(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	committer, err := rlnc.GenCommitterForChunkSize(4 * 31)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	serialized, err := committer.Serialize()
+	committer.Close()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+
+	inputs := [][]byte{nil, {}, make([]byte, 8), make([]byte, minSerializedCommitterSize)}
+	for i := 1; i < len(serialized); i++ {
+		inputs = append(inputs, serialized[:i])
+	}
+	// Whole points, but a generator count that doesn't match them.
+	for _, count := range []uint64{0, 3, 5, 1 << 63} {
+		miscounted := bytes.Clone(serialized)
+		binary.LittleEndian.PutUint64(miscounted, count)
+		inputs = append(inputs, miscounted)
+	}
+	for range 200 {
+		garbage := make([]byte, mathrand.IntN(2*len(serialized)))
+		rand.Read(garbage)
+		inputs = append(inputs, garbage)
+	}
+	for _, b := range inputs {
+		if _, err := DeserializeCommitter(rlnc, b); !errors.Is(err, ErrInvalidCommitter) {
+			t.Fatalf("Expected ErrInvalidCommitter deserializing %x, got %v", b, err)
+		}
+		c := &Committer{r: rlnc}
+		if err := c.UnmarshalBinary(b); !errors.Is(err, ErrInvalidCommitter) {
+			t.Fatalf("Expected ErrInvalidCommitter unmarshaling %x, got %v", b, err)
+		}
+		if err := c.Deserialize(rlnc, b); !errors.Is(err, ErrInvalidCommitter) {
+			t.Fatalf("Expected ErrInvalidCommitter deserializing %x, got %v", b, err)
+		}
+	}
+
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected rejected committers not to hold the library")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 1000)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(PaddedSize(len(data), numChunks), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNodePadded(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	receive := func(n *Node) {
+		t.Helper()
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := n.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	destinationNode, err := committer.NewNodePadded(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	for destinationNode.Rank() < 2 {
+		receive(destinationNode)
+	}
+	snapshot, err := destinationNode.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	destinationNode.Close()
+
+	restored, err := committer.RestoreNode(snapshot)
+	if err != nil {
+		t.Fatalf("Error restoring node: %v", err)
+	}
+	defer restored.Close()
+	if restored.Rank() != 2 || restored.NumChunks() != numChunks {
+		t.Fatalf("Expected rank 2 of %d chunks, got %d of %d", numChunks, restored.Rank(), restored.NumChunks())
+	}
+	for !restored.IsFull() {
+		receive(restored)
+	}
+	got, err := restored.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded by the restored node doesn't match")
+	}
+
+	for _, b := range [][]byte{nil, snapshot[:3], snapshot[:len(snapshot)-1]} {
+		if _, err := committer.RestoreNode(b); err == nil {
+			t.Fatalf("Expected error restoring %d bytes", len(b))
+		}
+	}
+}
+
+func TestDecodedChunks(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 4)
+	chunkSize := len(data) / 4
+
+	decoded, err := destinationNode.DecodedChunks()
+	if err != nil {
+		t.Fatalf("Error getting decoded chunks: %v", err)
+	}
+	if len(decoded) != 0 {
+		t.Fatalf("Expected no decoded chunks from an empty node, got %d", len(decoded))
+	}
+
+	for k := range 3 {
+		chunk, err := sourceNode.SystematicChunk(k)
+		if err != nil {
+			t.Fatalf("Error getting systematic chunk: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		decoded, err := destinationNode.DecodedChunks()
+		if err != nil {
+			t.Fatalf("Error getting decoded chunks: %v", err)
+		}
+		if len(decoded) != k+1 {
+			t.Fatalf("Expected %d decoded chunks, got %d", k+1, len(decoded))
+		}
+		for i := range k + 1 {
+			if !bytes.Equal(decoded[i], data[i*chunkSize:(i+1)*chunkSize]) {
+				t.Fatalf("Decoded chunk %d doesn't match", i)
+			}
+		}
+	}
+
+	decoded, err = sourceNode.DecodedChunks()
+	if err != nil {
+		t.Fatalf("Error getting decoded chunks: %v", err)
+	}
+	if len(decoded) != 4 {
+		t.Fatalf("Expected a source node to have all 4 chunks, got %d", len(decoded))
+	}
+}
+
+func TestCommitmentsHashPaths(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	forBlock, err := committer.CommitmentsHashForBlock(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error getting commitments hash for block: %v", err)
+	}
+
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	fromChunk, err := rlnc.CommitmentsHash(chunk)
+	if err != nil {
+		t.Fatalf("Error getting commitments hash: %v", err)
+	}
+
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	if _, err := destinationNode.CommitmentsHash(); err == nil {
+		t.Fatalf("Expected error getting the commitments hash of an empty node")
+	}
+	if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+
+	for _, n := range []*Node{sourceNode, destinationNode} {
+		fromNode, err := n.CommitmentsHash()
+		if err != nil {
+			t.Fatalf("Error getting node commitments hash: %v", err)
+		}
+		if !bytes.Equal(fromNode, fromChunk) || !bytes.Equal(forBlock, fromChunk) {
+			t.Fatalf("Commitments hashes differ: block %x, chunk %x, node %x", forBlock, fromChunk, fromNode)
+		}
+	}
+}
+
+func TestCommitmentsHashInputs(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		chunk []byte
+		err   error
+	}{
+		{"nil", nil, ErrEmptyChunk},
+		{"empty", []byte{}, ErrEmptyChunk},
+		{"one byte", []byte{1}, ErrMalformedChunk},
+		{"huge length", bytes.Repeat([]byte{0xff}, 8), ErrMalformedChunk},
+		{"truncated", chunk[:len(chunk)-1], ErrMalformedChunk},
+		{"valid", chunk, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hash, err := rlnc.CommitmentsHash(tc.chunk)
+			if !errors.Is(err, tc.err) || (tc.err != nil) != (err != nil) {
+				t.Fatalf("Expected %v, got %v", tc.err, err)
+			}
+			if err == nil && len(hash) != 32 {
+				t.Fatalf("Expected a 32 byte hash, got %d bytes", len(hash))
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 3)
+	receive := func(n *Node) {
+		t.Helper()
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := n.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	for destinationNode.Rank() < 1 {
+		receive(destinationNode)
+	}
+	clone, err := destinationNode.Clone()
+	if err != nil {
+		t.Fatalf("Error cloning node: %v", err)
+	}
+	defer clone.Close()
+
+	for !destinationNode.IsFull() {
+		receive(destinationNode)
+	}
+	if clone.Rank() != 1 {
+		t.Fatalf("Expected the clone to keep rank 1, got %d", clone.Rank())
+	}
+
+	// Closing the original doesn't affect the clone.
+	destinationNode.Close()
+	for !clone.IsFull() {
+		receive(clone)
+	}
+	got, err := clone.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded by the clone doesn't match")
+	}
+	if _, err := destinationNode.Clone(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed cloning a closed node, got %v", err)
+	}
+}
+
+func TestRecodeByRank(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	chunkSize := len(data) / numChunks
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	relayNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer relayNode.Close()
+
+	// Rank 0: nothing to recode.
+	if _, err := relayNode.ChunkToSend(); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld at rank 0, got %v", err)
+	}
+	if _, err := relayNode.ChunkToSendInto(make([]byte, relayNode.ChunkLen())); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld from ChunkToSendInto at rank 0, got %v", err)
+	}
+	if _, err := relayNode.Chunks(2); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld from Chunks at rank 0, got %v", err)
+	}
+	relayNode.SetBlockID(BlockID{1})
+	if _, err := relayNode.WrappedChunkToSend(); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld from WrappedChunkToSend at rank 0, got %v", err)
+	}
+
+	// Rank 1: every recoded chunk is a multiple of the one chunk held, so a
+	// fresh decoder recovers exactly that original chunk from it.
+	systematic, err := sourceNode.SystematicChunk(1)
+	if err != nil {
+		t.Fatalf("Error getting systematic chunk: %v", err)
+	}
+	if _, err := relayNode.ReceiveChunk(systematic); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	recoded, err := relayNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error recoding at rank 1: %v", err)
+	}
+	if err := committer.VerifyChunk(recoded); err != nil {
+		t.Fatalf("Error verifying chunk recoded at rank 1: %v", err)
+	}
+	decoder, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer decoder.Close()
+	if _, err := decoder.ReceiveChunk(recoded); err != nil {
+		t.Fatalf("Error receiving recoded chunk: %v", err)
+	}
+	decoded, err := decoder.DecodedChunks()
+	if err != nil {
+		t.Fatalf("Error getting decoded chunks: %v", err)
+	}
+	if len(decoded) != 1 || !bytes.Equal(decoded[1], data[chunkSize:2*chunkSize]) {
+		t.Fatalf("Expected the chunk recoded at rank 1 to carry only original chunk 1")
+	}
+
+	// Full rank: recoded chunks fill a decoder like the source's.
+	for !relayNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := relayNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	for !decoder.IsFull() {
+		chunk, err := relayNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error recoding at full rank: %v", err)
+		}
+		if _, err := decoder.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving recoded chunk: %v", err)
+		}
+	}
+	got, err := decoder.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded from recoded chunks doesn't match")
+	}
+
+	// Back to rank 0 after a Reset.
+	if err := relayNode.Reset(); err != nil {
+		t.Fatalf("Error resetting node: %v", err)
+	}
+	if _, err := relayNode.ChunkToSend(); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld after a reset, got %v", err)
+	}
+}
+
+func TestReceiveChunkErrors(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	if _, err := destinationNode.ChunkToSend(); !errors.Is(err, ErrNoChunksHeld) {
+		t.Fatalf("Expected ErrNoChunksHeld from an empty node, got %v", err)
+	}
+	if _, err := destinationNode.Data(); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Expected ErrNotReady from an empty node, got %v", err)
+	}
+	if _, err := destinationNode.WriteTo(io.Discard); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Expected ErrNotReady writing an empty node, got %v", err)
+	}
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	// Flip a bit in the first data scalar, after the length prefix.
+	tampered := bytes.Clone(chunk)
+	tampered[8] ^= 1
+	if _, err := destinationNode.ReceiveChunk(tampered); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for a tampered chunk, got %v", err)
+	}
+	if _, err := destinationNode.ReceiveChunk(chunk[:len(chunk)/2]); !errors.Is(err, ErrMalformedChunk) {
+		t.Fatalf("Expected ErrMalformedChunk for a truncated chunk, got %v", err)
+	}
+
+	if ok, err := destinationNode.ReceiveChunk(chunk); !ok || err != nil {
+		t.Fatalf("Expected the first chunk to be innovative, got %v, %v", ok, err)
+	}
+	if ok, err := destinationNode.ReceiveChunk(chunk); ok || err != nil {
+		t.Fatalf("Expected a repeated chunk to be dropped without error, got %v, %v", ok, err)
+	}
+	if err := receiveError(-5); !errors.Is(err, ErrLinearlyDependent) {
+		t.Fatalf("Expected ErrLinearlyDependent, got %v", err)
+	}
+}
+
+func TestReceiveUndersizedChunk(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	smallest := chunkWireSize(1, numChunks)
+	if len(chunk) < smallest {
+		t.Fatalf("Expected a chunk of at least %d bytes, got %d", smallest, len(chunk))
+	}
+	for size := range smallest {
+		garbage := make([]byte, size)
+		rand.Read(garbage)
+		for _, b := range [][]byte{chunk[:size], garbage} {
+			if _, err := destinationNode.ReceiveChunk(b); !errors.Is(err, ErrMalformedChunk) {
+				t.Fatalf("Expected ErrMalformedChunk for %d bytes, got %v", size, err)
+			}
+			if _, errs := destinationNode.ReceiveChunks([][]byte{b}); !errors.Is(errs[0], ErrMalformedChunk) {
+				t.Fatalf("Expected ErrMalformedChunk for %d bytes in a batch, got %v", size, errs[0])
+			}
+		}
+	}
+	if destinationNode.Rank() != 0 {
+		t.Fatalf("Expected undersized chunks not to be received, got rank %d", destinationNode.Rank())
+	}
+	if ok, err := destinationNode.ReceiveChunk(chunk); !ok || err != nil {
+		t.Fatalf("Expected the whole chunk to be innovative, got %v, %v", ok, err)
+	}
+}
+
+func TestReceiveChunkFrame(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	raw, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	chunk, err := ParseChunk(raw)
+	if err != nil {
+		t.Fatalf("Error parsing chunk: %v", err)
+	}
+	maxScalars := len(chunk.Payload)
+
+	tests := []struct {
+		name  string
+		chunk []byte
+		want  string
+	}{
+		{"long payload", (&Chunk{Payload: append(chunk.Payload, Scalar{}), Coefficients: chunk.Coefficients, Commitments: chunk.Commitments}).Marshal(), fmt.Sprintf("%d values in the payload, want 1 to %d", maxScalars+1, maxScalars)},
+		{"missing commitment", (&Chunk{Payload: chunk.Payload, Coefficients: chunk.Coefficients, Commitments: chunk.Commitments[1:]}).Marshal(), fmt.Sprintf("%d values in the commitments, want %d", numChunks-1, numChunks)},
+		{"extra commitment", (&Chunk{Payload: chunk.Payload, Coefficients: chunk.Coefficients, Commitments: append(chunk.Commitments, Point{})}).Marshal(), fmt.Sprintf("%d values in the commitments, want %d", numChunks+1, numChunks)},
+		{"truncated payload", raw[:8+32*(maxScalars-1)], "shorter than"},
+		{"truncated commitments", raw[:len(raw)-1], "truncated in the commitments"},
+		{"trailing bytes", append(bytes.Clone(raw), make([]byte, 32)...), "32 trailing bytes"},
+	}
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := destinationNode.ReceiveChunk(tt.chunk)
+			if !errors.Is(err, ErrMalformedChunk) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected ErrMalformedChunk saying %q, got %v", tt.want, err)
+			}
+			if _, errs := destinationNode.ReceiveChunks([][]byte{tt.chunk}); !errors.Is(errs[0], ErrMalformedChunk) {
+				t.Fatalf("Expected ErrMalformedChunk in a batch, got %v", errs[0])
+			}
+		})
+	}
+	if destinationNode.Rank() != 0 {
+		t.Fatalf("Expected malformed chunks not to be received, got rank %d", destinationNode.Rank())
+	}
+	if ok, err := destinationNode.ReceiveChunk(raw); !ok || err != nil {
+		t.Fatalf("Expected the well-formed chunk to be innovative, got %v, %v", ok, err)
+	}
+}
+
+func FuzzReceiveChunk(f *testing.F) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		f.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		f.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		f.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks := make([][]byte, numChunks)
+	for i := range chunks {
+		if chunks[i], err = sourceNode.SystematicChunk(i); err != nil {
+			f.Fatalf("Error getting systematic chunk: %v", err)
+		}
+	}
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		f.Fatalf("Error getting chunk to send: %v", err)
+	}
+	otherData := make([]byte, len(data))
+	rand.Read(otherData)
+	otherNode, err := committer.NewSourceNode(otherData, numChunks)
+	if err != nil {
+		f.Fatalf("Error creating source node: %v", err)
+	}
+	otherChunk, err := otherNode.ChunkToSend()
+	otherNode.Close()
+	if err != nil {
+		f.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	f.Add(chunk)
+	f.Add(chunks[1])
+	f.Add(otherChunk)
+	f.Add(chunk[:len(chunk)-1])
+	f.Add(append(bytes.Clone(chunk), 0))
+	tampered := bytes.Clone(chunk)
+	tampered[8] ^= 1
+	f.Add(tampered)
+	f.Add([]byte{})
+	f.Add(bytes.Repeat([]byte{0xff}, len(chunk)))
+
+	// The typed errors a chunk from a peer may be rejected with.
+	rejections := []error{ErrMalformedChunk, ErrReceiveFailed, ErrCommitmentMismatch, ErrChunkMismatch, ErrInvalidMessage}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		node, err := committer.NewNode(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer node.Close()
+		// Receive a real chunk first, so b can't decide the block.
+		if _, err := node.ReceiveChunk(chunks[0]); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		rank := node.Rank()
+		if _, err := node.ReceiveChunk(b); err != nil {
+			if !slices.ContainsFunc(rejections, func(target error) bool { return errors.Is(err, target) }) {
+				t.Fatalf("Expected a typed error for %x, got %v", b, err)
+			}
+			if node.Rank() != rank {
+				t.Fatalf("Expected a rejected chunk not to change the rank, got %d from %d", node.Rank(), rank)
+			}
+		}
+		for _, c := range chunks[1:] {
+			if _, err := node.ReceiveChunk(c); err != nil {
+				t.Fatalf("Error receiving chunk after %x: %v", b, err)
+			}
+		}
+		got, err := node.Data()
+		if err != nil {
+			t.Fatalf("Error getting data after %x: %v", b, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Decoded data doesn't match after %x", b)
+		}
+	})
+}
+
+func TestNumChunksMismatch(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	chunkSize := 32 * 64
+	committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	for _, tc := range []struct{ source, node int }{{16, 8}, {8, 16}} {
+		data := make([]byte, chunkSize*tc.source)
+		rand.Read(data)
+		sourceNode, err := committer.NewSourceNode(data, tc.source)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer sourceNode.Close()
+		destinationNode, err := committer.NewNode(tc.node)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer destinationNode.Close()
+
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		_, err = destinationNode.ReceiveChunk(chunk)
+		var numChunksErr *NumChunksError
+		if !errors.As(err, &numChunksErr) || !errors.Is(err, ErrChunkMismatch) {
+			t.Fatalf("Expected a NumChunksError, got %v", err)
+		}
+		if numChunksErr.Node != tc.node || numChunksErr.Chunk != uint64(tc.source) {
+			t.Fatalf("Expected node %d, chunk %d, got %+v", tc.node, tc.source, numChunksErr)
+		}
+		want := fmt.Sprintf("node configured for %d chunks, chunk encodes %d", tc.node, tc.source)
+		if err.Error() != want {
+			t.Fatalf("Expected error %q, got %q", want, err)
+		}
+
+		_, errs := destinationNode.ReceiveChunks([][]byte{chunk})
+		if !errors.As(errs[0], &numChunksErr) {
+			t.Fatalf("Expected a NumChunksError from ReceiveChunks, got %v", errs[0])
+		}
+		if rank := destinationNode.Rank(); rank != 0 {
+			t.Fatalf("Expected rank 0 after mismatched chunks, got %d", rank)
+		}
+	}
+}
+
+func TestOnDecoded(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks := make([][]byte, numChunks+1)
+	for i := range chunks {
+		if chunks[i], err = sourceNode.ChunkToSend(); err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+	}
+
+	var fired [][]byte
+	record := func(data []byte) { fired = append(fired, data) }
+
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	if err := destinationNode.OnDecoded(record); err != nil {
+		t.Fatalf("Error registering callback: %v", err)
+	}
+	if _, err := destinationNode.ReceiveChunk(chunks[0]); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	// A completing chunk that fails to verify doesn't fire the callback.
+	tampered := bytes.Clone(chunks[1])
+	tampered[8] ^= 1
+	if _, err := destinationNode.ReceiveChunk(tampered); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for a tampered chunk, got %v", err)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("Expected no callback before the node is full")
+	}
+	if _, err := destinationNode.ReceiveChunk(chunks[1]); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	if len(fired) != 1 || !bytes.Equal(fired[0], data) {
+		t.Fatalf("Expected one callback with the decoded data, got %d", len(fired))
+	}
+	// Receiving on a full node doesn't fire it again.
+	if _, err := destinationNode.ReceiveChunk(chunks[2]); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	if len(fired) != 1 {
+		t.Fatalf("Expected the callback to fire once, got %d", len(fired))
+	}
+
+	// Registering on a full node fires right away.
+	fired = nil
+	if err := destinationNode.OnDecoded(record); err != nil {
+		t.Fatalf("Error registering callback: %v", err)
+	}
+	if len(fired) != 1 || !bytes.Equal(fired[0], data) {
+		t.Fatalf("Expected the callback to fire on registration")
+	}
+
+	// ReceiveChunks fires it once for the whole batch.
+	fired = nil
+	batchNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer batchNode.Close()
+	if err := batchNode.OnDecoded(record); err != nil {
+		t.Fatalf("Error registering callback: %v", err)
+	}
+	if _, errs := batchNode.ReceiveChunks(chunks); errors.Join(errs...) != nil {
+		t.Fatalf("Error receiving chunks: %v", errors.Join(errs...))
+	}
+	if len(fired) != 1 || !bytes.Equal(fired[0], data) {
+		t.Fatalf("Expected one callback from the batch, got %d", len(fired))
+	}
+
+	// Closing first drops the callback.
+	fired = nil
+	closedNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	if err := closedNode.OnDecoded(record); err != nil {
+		t.Fatalf("Error registering callback: %v", err)
+	}
+	if _, err := closedNode.ReceiveChunk(chunks[0]); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	closedNode.Close()
+	if _, err := closedNode.ReceiveChunk(chunks[1]); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed, got %v", err)
+	}
+	if err := closedNode.OnDecoded(record); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed registering on a closed node, got %v", err)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("Expected no callback from a closed node")
+	}
+}
+
+func TestDone(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 3
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	isClosed := func(done <-chan struct{}) bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	done := destinationNode.Done()
+	for i := range numChunks {
+		if isClosed(done) {
+			t.Fatalf("Expected Done to be open at rank %d", i)
+		}
+		chunk, err := sourceNode.SystematicChunk(i)
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	if !isClosed(done) {
+		t.Fatalf("Expected Done to be closed once the node is full")
+	}
+	if err := destinationNode.Err(); err != nil {
+		t.Fatalf("Expected no error for a full node, got %v", err)
+	}
+	if destinationNode.Done() != done {
+		t.Fatalf("Expected Done to keep returning the same channel")
+	}
+	destinationNode.Close()
+	if err := destinationNode.Err(); err != nil {
+		t.Fatalf("Expected closing a full node to keep its error nil, got %v", err)
+	}
+
+	// A full node that was never watched closes Done right away.
+	if !isClosed(sourceNode.Done()) || sourceNode.Err() != nil {
+		t.Fatalf("Expected Done of a full node to be closed")
+	}
+
+	abandoned, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	done = abandoned.Done()
+	if err := abandoned.Err(); err != nil {
+		t.Fatalf("Expected no error before Done is closed, got %v", err)
+	}
+	abandoned.Close()
+	if !isClosed(done) {
+		t.Fatalf("Expected Close to close Done")
+	}
+	if err := abandoned.Err(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed for a node closed before it was full, got %v", err)
+	}
+}
+
+func TestReceiveSourceChunk(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	chunkSize := len(data) / numChunks
+	original := func(i int) []byte { return data[i*chunkSize : (i+1)*chunkSize] }
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	parsed, err := ParseChunk(chunk)
+	if err != nil {
+		t.Fatalf("Error parsing chunk: %v", err)
+	}
+	commitments := parsed.Commitments
+
+	// Only source chunks.
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer destinationNode.Close()
+	if _, err := destinationNode.ReceiveSourceChunk(0, original(0)); !errors.Is(err, ErrNoCommitments) {
+		t.Fatalf("Expected ErrNoCommitments before any commitments, got %v", err)
+	}
+	if ok, err := destinationNode.ReceiveSourceChunkWithCommitments(0, original(0), commitments); !ok || err != nil {
+		t.Fatalf("Expected source chunk 0 to be innovative, got %v, %v", ok, err)
+	}
+	if _, err := destinationNode.ReceiveSourceChunk(1, original(2)); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for a chunk under the wrong index, got %v", err)
+	}
+	if _, err := destinationNode.ReceiveSourceChunk(numChunks, original(0)); err == nil {
+		t.Fatalf("Expected an error for an out of range index")
+	}
+	if ok, err := destinationNode.ReceiveSourceChunk(0, original(0)); ok || err != nil {
+		t.Fatalf("Expected a repeated source chunk to be dropped without error, got %v, %v", ok, err)
+	}
+	for i := 1; i < numChunks; i++ {
+		if ok, err := destinationNode.ReceiveSourceChunk(i, original(i)); !ok || err != nil {
+			t.Fatalf("Expected source chunk %d to be innovative, got %v, %v", i, ok, err)
+		}
+	}
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded from source chunks doesn't match")
+	}
+
+	// Source chunks mixed with coded repair chunks, which must carry the same
+	// commitments.
+	mixedNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer mixedNode.Close()
+	if _, err := mixedNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	if ok, err := mixedNode.ReceiveSourceChunk(2, original(2)); !ok || err != nil {
+		t.Fatalf("Expected source chunk 2 to be innovative, got %v, %v", ok, err)
+	}
+	otherCommitments := slices.Clone(commitments)
+	otherCommitments[0], otherCommitments[1] = otherCommitments[1], otherCommitments[0]
+	if _, err := mixedNode.ReceiveSourceChunkWithCommitments(3, original(3), otherCommitments); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Fatalf("Expected ErrCommitmentMismatch for other commitments, got %v", err)
+	}
+	for !mixedNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := mixedNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	if got, err = mixedNode.Data(); err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Data decoded from mixed chunks doesn't match")
+	}
+}
+
+func TestVerifyChunk(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 2
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	if err := committer.VerifyChunk(chunk); err != nil {
+		t.Fatalf("Error verifying chunk: %v", err)
+	}
+
+	tampered := bytes.Clone(chunk)
+	tampered[8] ^= 1
+	if err := committer.VerifyChunk(tampered); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("Expected ErrVerificationFailed for a tampered chunk, got %v", err)
+	}
+	if err := committer.VerifyChunk(chunk[:len(chunk)/2]); !errors.Is(err, ErrMalformedChunk) {
+		t.Fatalf("Expected ErrMalformedChunk for a truncated chunk, got %v", err)
+	}
+
+	// Drop a commitment, leaving more coefficients than commitments.
+	parsed, err := ParseChunk(chunk)
+	if err != nil {
+		t.Fatalf("Error parsing chunk: %v", err)
+	}
+	parsed.Commitments = parsed.Commitments[1:]
+	if err := committer.VerifyChunk(parsed.Marshal()); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage for a chunk missing a commitment, got %v", err)
+	}
+}
+
+func BenchmarkVerifyChunk(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 8
+	data := make([]byte, 32*256*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		b.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		b.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		b.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	b.Run("VerifyChunk", func(b *testing.B) {
+		for range b.N {
+			if err := committer.VerifyChunk(chunk); err != nil {
+				b.Fatalf("Error verifying chunk: %v", err)
+			}
+		}
+	})
+	b.Run("ReceiveChunk", func(b *testing.B) {
+		// After the first call the chunk is dependent, but it is still
+		// verified before the node finds that out.
+		node, err := committer.NewNode(numChunks)
+		if err != nil {
+			b.Fatalf("Error creating node: %v", err)
+		}
+		defer node.Close()
+		for range b.N {
+			if _, err := node.ReceiveChunk(chunk); err != nil {
+				b.Fatalf("Error receiving chunk: %v", err)
+			}
+		}
+	})
+}
+
+func TestReceiveChunks(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 3)
+	systematic := func(i int) []byte {
+		t.Helper()
+		chunk, err := sourceNode.SystematicChunk(i)
+		if err != nil {
+			t.Fatalf("Error getting systematic chunk: %v", err)
+		}
+		return chunk
+	}
+	tampered := systematic(1)
+	tampered[8] ^= 1
+
+	// The node is full after the fifth chunk, so the last two are skipped
+	// even though one is invalid.
+	batch := [][]byte{systematic(0), tampered, systematic(1), systematic(0), systematic(2), systematic(1), tampered}
+	innovative, errs := destinationNode.ReceiveChunks(batch)
+	if innovative != 3 {
+		t.Fatalf("Expected 3 innovative chunks, got %d", innovative)
+	}
+	for i, err := range errs {
+		if i == 1 {
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("Expected ErrInvalidMessage for the tampered chunk, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("Error receiving chunk %d: %v", i, err)
+		}
+	}
+	destData, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+
+	if innovative, errs := destinationNode.ReceiveChunks(nil); innovative != 0 || len(errs) != 0 {
+		t.Fatalf("Expected nothing from an empty batch, got %d, %v", innovative, errs)
+	}
+}
+
+func BenchmarkReceiveChunks(b *testing.B) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		b.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 64
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		b.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		b.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks := make([][]byte, numChunks)
+	for i := range chunks {
+		if chunks[i], err = sourceNode.SystematicChunk(i); err != nil {
+			b.Fatalf("Error getting systematic chunk: %v", err)
+		}
+	}
+
+	b.Run("Loop", func(b *testing.B) {
+		for range b.N {
+			node, err := committer.NewNode(numChunks)
+			if err != nil {
+				b.Fatalf("Error creating node: %v", err)
+			}
+			for _, chunk := range chunks {
+				if _, err := node.ReceiveChunk(chunk); err != nil {
+					b.Fatalf("Error receiving chunk: %v", err)
+				}
+			}
+			node.Close()
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for range b.N {
+			node, err := committer.NewNode(numChunks)
+			if err != nil {
+				b.Fatalf("Error creating node: %v", err)
+			}
+			if innovative, _ := node.ReceiveChunks(chunks); innovative != numChunks {
+				b.Fatalf("Expected %d innovative chunks, got %d", numChunks, innovative)
+			}
+			node.Close()
+		}
+	})
+}
+
+func TestChunks(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 4)
+
+	// Random coefficients are independent with overwhelming probability, so
+	// a few spare chunks are plenty.
+	chunks, err := sourceNode.Chunks(destinationNode.NumChunks() + 2)
+	if err != nil {
+		t.Fatalf("Error getting chunks: %v", err)
+	}
+	for i, chunk := range chunks {
+		if len(chunk) != sourceNode.ChunkLen() {
+			t.Fatalf("Expected chunk %d to be %d bytes, got %d", i, sourceNode.ChunkLen(), len(chunk))
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk %d: %v", i, err)
+		}
+	}
+	if !destinationNode.IsFull() {
+		t.Fatalf("Expected one batch of chunks to fill the node")
+	}
+	destData, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+
+	if _, err := sourceNode.Chunks(0); err == nil {
+		t.Fatalf("Expected error getting 0 chunks")
+	}
+}
+
+func TestSmallNumChunks(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	for _, numChunks := range []int{1, 2} {
+		t.Run(fmt.Sprintf("%dChunks", numChunks), func(t *testing.T) {
+			data := make([]byte, 32*64*numChunks)
+			rand.Read(data)
+			committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+			if err != nil {
+				t.Fatalf("Error creating committer: %v", err)
+			}
+			defer committer.Close()
+			sourceNode, err := committer.NewSourceNode(data, numChunks)
+			if err != nil {
+				t.Fatalf("Error creating source node: %v", err)
+			}
+			defer sourceNode.Close()
+
+			destinationNode, err := committer.NewNode(numChunks)
+			if err != nil {
+				t.Fatalf("Error creating node: %v", err)
+			}
+			defer destinationNode.Close()
+			innovative := 0
+			for !destinationNode.IsFull() {
+				chunk, err := sourceNode.ChunkToSend()
+				if err != nil {
+					t.Fatalf("Error getting chunk to send: %v", err)
+				}
+				ok, err := destinationNode.ReceiveChunk(chunk)
+				if err != nil {
+					t.Fatalf("Error receiving chunk: %v", err)
+				}
+				if ok {
+					innovative++
+				}
+				if full := destinationNode.IsFull(); full != (innovative == numChunks) {
+					t.Fatalf("Expected IsFull to be %v after %d innovative chunks", !full, innovative)
+				}
+			}
+			got, err := destinationNode.Data()
+			if err != nil {
+				t.Fatalf("Error getting data: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("Decoded data doesn't match")
+			}
+
+			// The first chunk a fresh node receives is always innovative, so
+			// with a single chunk every chunk fills a node.
+			for range 500 {
+				chunk, err := sourceNode.ChunkToSend()
+				if err != nil {
+					t.Fatalf("Error getting chunk to send: %v", err)
+				}
+				node, err := committer.NewNode(numChunks)
+				if err != nil {
+					t.Fatalf("Error creating node: %v", err)
+				}
+				ok, err := node.ReceiveChunk(chunk)
+				full := node.IsFull()
+				node.Close()
+				if !ok || err != nil {
+					t.Fatalf("Expected the first chunk to be innovative, got %v, %v", ok, err)
+				}
+				if full != (numChunks == 1) {
+					t.Fatalf("Expected IsFull to be %v after one chunk", numChunks == 1)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkWireSize(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	for _, chunkSize := range []int{32, 32 * 64, 32 * 100} {
+		committer, err := rlnc.GenCommitterForChunkSize(chunkSize)
+		if err != nil {
+			t.Fatalf("Error creating committer: %v", err)
+		}
+		defer committer.Close()
+		for _, numChunks := range []int{1, 8, 64} {
+			data := make([]byte, chunkSize*numChunks)
+			rand.Read(data)
+			sourceNode, err := committer.NewSourceNode(data, numChunks)
+			if err != nil {
+				t.Fatalf("Error creating source node: %v", err)
+			}
+			defer sourceNode.Close()
+			destinationNode, err := committer.NewNode(numChunks)
+			if err != nil {
+				t.Fatalf("Error creating node: %v", err)
+			}
+			defer destinationNode.Close()
+
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			want := committer.ChunkWireSize(numChunks)
+			for name, got := range map[string]int{
+				"chunk":            len(chunk),
+				"source ChunkLen":  sourceNode.ChunkLen(),
+				"decoder ChunkLen": destinationNode.ChunkLen(),
+			} {
+				if got != want {
+					t.Fatalf("Chunk size %d, %d chunks: predicted %d bytes, %s is %d", chunkSize, numChunks, want, name, got)
+				}
+			}
+		}
+	}
+}
+
+func TestDataContext(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 2)
+	for !destinationNode.IsFull() {
+		chunk, err := sourceNode.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+
+	destData, err := destinationNode.DataContext(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, destData) {
+		t.Fatalf("Source and destination nodes do not have the same data")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := destinationNode.DataContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, _, err := destinationNode.ReceiveChunksContext(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestReceiveReusedBuffers(t *testing.T) {
+	numChunks := 4
+	sourceNode, _, data := newStreamNodes(t, numChunks)
+	newNode := func() *Node {
+		n, err := sourceNode.committer.NewNode(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		t.Cleanup(n.Close)
+		return n
+	}
+	// Every batch reuses the same buffers, overwritten with garbage as
+	// soon as the call returns.
+	bufs := make([][]byte, 2)
+	fill := func() {
+		for i := range bufs {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send: %v", err)
+			}
+			if bufs[i] == nil {
+				bufs[i] = make([]byte, len(chunk))
+			}
+			copy(bufs[i], chunk)
+		}
+	}
+	garbage := func() {
+		for _, buf := range bufs {
+			rand.Read(buf)
+		}
+	}
+	checkData := func(n *Node) {
+		t.Helper()
+		got, err := n.Data()
+		if err != nil {
+			t.Fatalf("Error getting data: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Decoded data doesn't match")
+		}
+	}
+
+	node := newNode()
+	for !node.IsFull() {
+		fill()
+		if _, errs := node.ReceiveChunks(bufs); errs[0] != nil || errs[1] != nil {
+			t.Fatalf("Error receiving chunks: %v", errs)
+		}
+		garbage()
+	}
+	checkData(node)
+
+	// A batch ReceiveChunksContext gives up on is still received in the
+	// background, from a copy, while the caller already reuses the buffers.
+	node = newNode()
+	for !node.IsFull() {
+		fill()
+		rank := node.Rank()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		node.mu.Lock()
+		_, _, err := node.ReceiveChunksContext(ctx, bufs)
+		cancel()
+		garbage()
+		node.mu.Unlock()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded with the node held up, got %v", err)
+		}
+		node.inflight.Wait()
+		if got := node.Rank(); got == rank {
+			t.Fatalf("Expected the abandoned batch to be received, rank is still %d", got)
+		}
+	}
+	checkData(node)
+}
+
+func TestNewSourceNodeFailure(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	committer, err := rlnc.GenCommitterForChunkSize(4 * 31)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	numChunks, chunkSize := 3, 128
+	block := make([]byte, numChunks*chunkSize)
+	if _, err := committer.NewSourceNode(block, numChunks); err == nil {
+		t.Fatalf("Expected an error for chunks larger than the committer supports")
+	}
+	// Past the checks, the library refuses the geometry and says why.
+	_, err = committer.newSourceNode(block, numChunks, chunkSize)
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Expected the library's reason in the error, got %v", err)
+	}
+	if _, err := committer.RestoreNode([]byte{1, 32, 0, 0xff}); err == nil || !strings.Contains(err.Error(), "failed to restore node: ") {
+		t.Fatalf("Expected the library's reason in the error, got %v", err)
+	}
+
+	committer.Close()
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected failed constructors not to hold the library")
+	}
+}
+
+func TestConstructorInputs(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	committer, err := rlnc.GenCommitterForChunkSize(64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	commitments, err := committer.Commit(make([]byte, 4*32), 4)
+	if err != nil {
+		t.Fatalf("Error committing: %v", err)
+	}
+	block := make([]byte, 4*32)
+
+	badNumChunks := []int{0, -1, -4, MaxNumChunks + 1}
+	for _, numChunks := range badNumChunks {
+		for name, f := range map[string]func() error{
+			"GenCommitterForMessage": func() error {
+				_, err := rlnc.GenCommitterForMessage(len(block), numChunks)
+				return err
+			},
+			"NewSourceNode": func() error {
+				_, err := committer.NewSourceNode(block, numChunks)
+				return err
+			},
+			"NewSourceNodePadded": func() error {
+				_, err := committer.NewSourceNodePadded(block, numChunks)
+				return err
+			},
+			"NewSourceNodeFromReader": func() error {
+				_, err := committer.NewSourceNodeFromReader(bytes.NewReader(block), int64(len(block)), numChunks)
+				return err
+			},
+			"NewSourceNodeWithCommitments": func() error {
+				_, err := committer.NewSourceNodeWithCommitments(block, numChunks, commitments)
+				return err
+			},
+			"Commit": func() error {
+				_, err := committer.Commit(block, numChunks)
+				return err
+			},
+		} {
+			if err := f(); err == nil || !strings.Contains(err.Error(), "num chunks") {
+				t.Fatalf("Expected %s to reject %d chunks, got %v", name, numChunks, err)
+			}
+		}
+		for name, newNode := range map[string]func(int) (*Node, error){
+			"NewNode":       committer.NewNode,
+			"NewNodePadded": committer.NewNodePadded,
+		} {
+			if n, err := newNode(numChunks); n != nil || err == nil || !strings.Contains(err.Error(), "num chunks") {
+				t.Fatalf("Expected %s to reject %d chunks, got %v", name, numChunks, err)
+			}
+		}
+	}
+
+	for name, f := range map[string]func() error{
+		"GenCommitterForChunkSize zero": func() error {
+			_, err := rlnc.GenCommitterForChunkSize(0)
+			return err
+		},
+		"GenCommitterForChunkSize negative": func() error {
+			_, err := rlnc.GenCommitterForChunkSize(-32)
+			return err
+		},
+		"GenCommitterForMessage zero": func() error {
+			_, err := rlnc.GenCommitterForMessage(0, 4)
+			return err
+		},
+		"GenCommitterForMessage negative": func() error {
+			_, err := rlnc.GenCommitterForMessage(-128, 4)
+			return err
+		},
+		"NewSourceNode empty": func() error {
+			_, err := committer.NewSourceNode(nil, 4)
+			return err
+		},
+		"Commit empty": func() error {
+			_, err := committer.Commit([]byte{}, 4)
+			return err
+		},
+		"NewSourceNodeFromReader zero": func() error {
+			_, err := committer.NewSourceNodeFromReader(bytes.NewReader(nil), 0, 4)
+			return err
+		},
+		"NewSourceNodeFromReader negative": func() error {
+			_, err := committer.NewSourceNodeFromReader(bytes.NewReader(nil), -128, 4)
+			return err
+		},
+	} {
+		if err := f(); err == nil {
+			t.Fatalf("Expected an error from %s", name)
+		}
+	}
+
+	committer.Close()
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected rejected inputs not to hold the library")
+	}
+}
+
+// unreadable fails the test if a block is read from it.
+type unreadable struct{ t *testing.T }
+
+func (r unreadable) Read([]byte) (int, error) {
+	r.t.Fatalf("Expected the block to be rejected before it is read")
+	return 0, io.EOF
+}
+
+func TestLargeLengths(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("blocks past 2GB need 64 bit ints")
+	}
+	maxChunkSize := MaxChunkSize
+
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+	committer, err := rlnc.GenCommitterForChunkSize(64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	// Chunks whose scalars don't fit in 32 bits used to be truncated into a
+	// small committer.
+	tooLarge := "exceeds the maximum"
+	if _, err := rlnc.GenCommitterForChunkSize(int(maxChunkSize) + 1); err == nil || !strings.Contains(err.Error(), tooLarge) {
+		t.Fatalf("Expected an error for a chunk size past MaxChunkSize, got %v", err)
+	}
+	if _, err := rlnc.GenCommitterForMessage(2*(int(maxChunkSize)+1), 2); err == nil || !strings.Contains(err.Error(), tooLarge) {
+		t.Fatalf("Expected an error for a message of chunks past MaxChunkSize, got %v", err)
+	}
+	hugeChunk := (int(maxChunkSize)/32 + 1) * 32
+	if _, err := committer.checkBlockSize(int64(2*hugeChunk), 2); err == nil || !strings.Contains(err.Error(), tooLarge) {
+		t.Fatalf("Expected an error for a block of chunks past MaxChunkSize, got %v", err)
+	}
+	if _, err := committer.NewSourceNodeFromReader(unreadable{t}, int64(2*hugeChunk), 2); err == nil || !strings.Contains(err.Error(), tooLarge) {
+		t.Fatalf("Expected an error for a block of chunks past MaxChunkSize, got %v", err)
+	}
+	snapshot := binary.AppendUvarint(nil, 2)
+	snapshot = binary.AppendUvarint(snapshot, uint64(maxChunkSize)+1)
+	snapshot = append(snapshot, 0, 0)
+	if _, err := committer.RestoreNode(snapshot); err == nil {
+		t.Fatalf("Expected an error for a snapshot of chunks past MaxChunkSize")
+	}
+
+	// A 5GB block in a few chunks is rejected for the size of its chunks,
+	// not for a truncated one. Blocks that large are checked by their size
+	// alone, which is what NewSourceNode and Commit check too.
+	var size int64 = 5 << 30
+	want := fmt.Sprintf("block implies %d-scalar chunks", chunkSizeInScalars(size/4))
+	for name, f := range map[string]func() error{
+		"checkBlockSize": func() error {
+			_, err := committer.checkBlockSize(size, 4)
+			return err
+		},
+		"NewSourceNodeFromReader": func() error {
+			_, err := committer.NewSourceNodeFromReader(unreadable{t}, size, 4)
+			return err
+		},
+		"checkPaddedSize": func() error {
+			return committer.checkPaddedSize(int(size-padLenSize), 4)
+		},
+	} {
+		if err := f(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Expected %s to fail with %q, got %v", name, want, err)
+		}
+	}
+	if err := committer.checkPaddedSize(math.MaxInt-padLenSize, 4); err == nil || !strings.Contains(err.Error(), "too large to pad") {
+		t.Fatalf("Expected an error for a block too large to pad, got %v", err)
+	}
+}
+
+func TestDoubleClose(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	numChunks := 4
+	data := make([]byte, 32*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+
+	// Overlapping Closes, as from two defer paths on different goroutines.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(3)
+		go func() { defer wg.Done(); sourceNode.Close() }()
+		go func() { defer wg.Done(); destinationNode.Close() }()
+		go func() { defer wg.Done(); committer.Close() }()
+	}
+	wg.Wait()
+	sourceNode.Close()
+	destinationNode.Close()
+	committer.Close()
+
+	for _, n := range []*Node{sourceNode, destinationNode} {
+		if _, err := n.ChunkToSend(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed sending from a closed node, got %v", err)
+		}
+		if _, err := n.ReceiveChunk(chunk); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed receiving into a closed node, got %v", err)
+		}
+		if _, err := n.Data(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed decoding a closed node, got %v", err)
+		}
+		if _, err := n.Snapshot(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed snapshotting a closed node, got %v", err)
+		}
+		if _, err := n.Clone(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed cloning a closed node, got %v", err)
+		}
+		if _, err := n.CommitmentsHash(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed hashing a closed node's commitments, got %v", err)
+		}
+		if n.IsFull() || n.Rank() != 0 {
+			t.Fatalf("Expected a closed node to be empty")
+		}
+	}
+	for name, f := range map[string]func() error{
+		"Serialize": func() error {
+			_, err := committer.Serialize()
+			return err
+		},
+		"Fingerprint": func() error {
+			_, err := committer.Fingerprint()
+			return err
+		},
+		"NewNode": func() error {
+			_, err := committer.NewNode(numChunks)
+			return err
+		},
+		"NewNodePadded": func() error {
+			_, err := committer.NewNodePadded(numChunks)
+			return err
+		},
+		"NewSink": func() error {
+			_, err := committer.NewSink(numChunks)
+			return err
+		},
+		"NewSourceNode": func() error {
+			_, err := committer.NewSourceNode(data, numChunks)
+			return err
+		},
+		"NewSourceNodePadded": func() error {
+			_, err := committer.NewSourceNodePadded(data, numChunks)
+			return err
+		},
+		"NewSourceNodeFromReader": func() error {
+			_, err := committer.NewSourceNodeFromReader(bytes.NewReader(data), int64(len(data)), numChunks)
+			return err
+		},
+		"NewBlock": func() error {
+			_, err := committer.NewBlock(data, numChunks)
+			return err
+		},
+		"RestoreNode": func() error {
+			_, err := committer.RestoreNode([]byte{4, 64, 0, 0})
+			return err
+		},
+		"Commit": func() error {
+			_, err := committer.Commit(data, numChunks)
+			return err
+		},
+		"CommitmentsHashForBlock": func() error {
+			_, err := committer.CommitmentsHashForBlock(data, numChunks)
+			return err
+		},
+		"ChunkHash": func() error {
+			_, err := committer.ChunkHash(chunk)
+			return err
+		},
+		"VerifyChunk": func() error {
+			return committer.VerifyChunk(chunk)
+		},
+	} {
+		if err := f(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Expected ErrClosed from %s on a closed committer, got %v", name, err)
+		}
+	}
+	if got := committer.ChunkSizeScalars(); got != 0 {
+		t.Fatalf("Expected a closed committer to support no scalars, got %d", got)
+	}
+
+	rlnc.mu.Lock()
+	handles := rlnc.handles
+	rlnc.mu.Unlock()
+	if handles != 0 {
+		t.Fatalf("Expected every handle to be released once, got %d held", handles)
+	}
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected closed handles not to hold the library")
+	}
+}
+
+func TestConcurrentNode(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 16
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+
+	const workers = 8
+	chunks, err := sourceNode.Chunks(workers * numChunks)
+	if err != nil {
+		t.Fatalf("Error getting chunks: %v", err)
+	}
+	hammer := func(node *Node, chunks [][]byte) error {
+		for _, chunk := range chunks {
+			if _, err := node.ReceiveChunk(chunk); err != nil {
+				return fmt.Errorf("receiving chunk: %w", err)
+			}
+			if _, err := node.ChunkToSend(); err != nil && !errors.Is(err, ErrNoChunksHeld) {
+				return fmt.Errorf("getting chunk to send: %w", err)
+			}
+			full := node.IsFull()
+			got, err := node.Data()
+			switch {
+			case errors.Is(err, ErrNotReady) && !full:
+			case err == nil && bytes.Equal(got, data):
+			default:
+				return fmt.Errorf("getting data when full is %v: %w", full, err)
+			}
+			node.DebugState()
+		}
+		return nil
+	}
+
+	node, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	defer node.Close()
+	var fired atomic.Int32
+	if err := node.OnDecoded(func(got []byte) {
+		// The callback runs unlocked, so it can use the node.
+		if fired.Add(1) == 1 && (!node.IsFull() || !bytes.Equal(got, data)) {
+			t.Errorf("Expected the callback to get the data of a full node")
+		}
+	}); err != nil {
+		t.Fatalf("Error registering callback: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := hammer(node, chunks[i*numChunks:(i+1)*numChunks]); err != nil {
+				t.Errorf("Error in worker %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if !node.IsFull() {
+		t.Fatalf("Expected the node to be full after %d chunks", len(chunks))
+	}
+	if got := node.DebugState().Accepted; got != uint64(numChunks) {
+		t.Fatalf("Expected %d innovative chunks, got %d", numChunks, got)
+	}
+	if got := fired.Load(); got != 1 {
+		t.Fatalf("Expected the callback to fire once, fired %d times", got)
+	}
+	got, err := node.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+
+	// Closing midway leaves the workers with ErrClosed, not a freed handle.
+	node, err = committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := hammer(node, chunks[i*numChunks:(i+1)*numChunks]); err != nil && !errors.Is(err, ErrClosed) {
+				t.Errorf("Error in worker %d: %v", i, err)
+			}
+		}()
+	}
+	node.Close()
+	wg.Wait()
+	if _, err := node.ReceiveChunk(chunks[0]); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestMisroutedChunk(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	committer, err := rlnc.GenCommitterForChunkSize(64)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	// Two blocks go out at once, as they would on a busy link.
+	type block struct {
+		data   []byte
+		chunks [][]byte
+		hash   []byte
+		node   *Node
+	}
+	blocks := make([]*block, 2)
+	for i := range blocks {
+		b := &block{data: make([]byte, 64*numChunks)}
+		rand.Read(b.data)
+		sourceNode, err := committer.NewSourceNode(b.data, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source node: %v", err)
+		}
+		defer sourceNode.Close()
+		if b.chunks, err = sourceNode.Chunks(numChunks + 1); err != nil {
+			t.Fatalf("Error getting chunks: %v", err)
+		}
+		if b.hash, err = sourceNode.CommitmentsHash(); err != nil {
+			t.Fatalf("Error getting commitments hash: %v", err)
+		}
+		b.node, err = committer.NewNode(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating node: %v", err)
+		}
+		defer b.node.Close()
+		blocks[i] = b
+	}
+
+	serialized, err := committer.Serialize()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+	// checkMismatch checks err reports a chunk of block other at a receiver
+	// of block b, naming both by their hashes.
+	checkMismatch := func(what string, err error, b, other *block) bool {
+		if !errors.Is(err, ErrBlockMismatch) || !errors.Is(err, ErrCommitmentMismatch) {
+			t.Errorf("Expected ErrBlockMismatch from %s, got %v", what, err)
+			return false
+		}
+		var mismatch *BlockMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Errorf("Expected a *BlockMismatchError from %s, got %T", what, err)
+			return false
+		}
+		if len(mismatch.Node) == 0 || !bytes.HasPrefix(b.hash, mismatch.Node) || len(mismatch.Chunk) == 0 || !bytes.HasPrefix(other.hash, mismatch.Chunk) {
+			t.Errorf("Expected the hash prefixes %x and %x from %s, got %x and %x", b.hash, other.hash, what, mismatch.Node, mismatch.Chunk)
+		}
+		if msg := err.Error(); !strings.Contains(msg, hex.EncodeToString(mismatch.Node)) || !strings.Contains(msg, hex.EncodeToString(mismatch.Chunk)) {
+			t.Errorf("Expected both hash prefixes in %q", msg)
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for i, b := range blocks {
+		other := blocks[1-i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.node.ReceiveChunk(b.chunks[0]); err != nil {
+				t.Errorf("Error receiving chunk of block %d: %v", i, err)
+				return
+			}
+			// One chunk of the other block ends up at the wrong node.
+			ok, err := b.node.ReceiveChunk(other.chunks[0])
+			if ok || !checkMismatch("ReceiveChunk", err, b, other) {
+				return
+			}
+			if got := b.node.Rank(); got != 1 {
+				t.Errorf("Expected a misrouted chunk to leave rank 1, got %d", got)
+			}
+			_, errs := b.node.ReceiveChunks([][]byte{other.chunks[1]})
+			checkMismatch("ReceiveChunks", errs[0], b, other)
+
+			// Routed by block ID or through a Decoder, it is the same error.
+			var id, otherID BlockID
+			copy(id[:], b.hash)
+			copy(otherID[:], other.hash)
+			b.node.SetBlockID(id)
+			_, err = b.node.ReceiveWrappedChunk(WrapChunk(otherID, other.chunks[1]))
+			checkMismatch("ReceiveWrappedChunk", err, b, other)
+			decoder, err := NewDecoder(rlnc, serialized, numChunks)
+			if err != nil {
+				t.Errorf("Error creating decoder: %v", err)
+				return
+			}
+			defer decoder.Close()
+			if _, err := decoder.Add(b.chunks[0]); err != nil {
+				t.Errorf("Error adding chunk of block %d: %v", i, err)
+				return
+			}
+			_, err = decoder.Add(other.chunks[0])
+			checkMismatch("Decoder.Add", err, b, other)
+
+			for _, chunk := range b.chunks[1:] {
+				if _, err := b.node.ReceiveChunk(chunk); err != nil {
+					t.Errorf("Error receiving chunk of block %d: %v", i, err)
+					return
+				}
+			}
+			got, err := b.node.Data()
+			if err != nil {
+				t.Errorf("Error getting data of block %d: %v", i, err)
+			} else if !bytes.Equal(got, b.data) {
+				t.Errorf("Decoded data of block %d doesn't match", i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewSourceNodeFromReader(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+
+	sourceNode, err := committer.NewSourceNodeFromReader(bytes.NewReader(data), int64(len(data)), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	got, err := sourceNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Source node data doesn't match what was read")
+	}
+
+	for _, short := range [][]byte{nil, data[:1], data[:len(data)-1]} {
+		_, err := committer.NewSourceNodeFromReader(bytes.NewReader(short), int64(len(data)), numChunks)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("Expected io.ErrUnexpectedEOF reading %d bytes, got %v", len(short), err)
+		}
+	}
+
+	if _, err := committer.NewSourceNodeFromReader(bytes.NewReader(data), int64(len(data)-1), numChunks); err == nil {
+		t.Fatalf("Expected error for a size that isn't a multiple of numChunks")
+	}
+}
+
+func TestCloseWithLiveNode(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	numChunks := 8
+	data := make([]byte, 31*512*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+
+	rlnc.Close()
+	committer.Close()
+	if rlnc.unloaded {
+		t.Fatalf("Library unloaded while a node is still open")
+	}
+
+	if _, err := sourceNode.ChunkToSend(); err != nil {
+		t.Fatalf("Error getting chunk to send after Close: %v", err)
+	}
+
+	sourceNode.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Library not unloaded after the last handle was closed")
+	}
+}
+
+func TestCommitterCloseBeforeNodes(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	numChunks := 4
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating destination node: %v", err)
+	}
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+		t.Fatalf("Error receiving chunk: %v", err)
+	}
+	snapshot, err := destinationNode.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	restoredNode, err := committer.RestoreNode(snapshot)
+	if err != nil {
+		t.Fatalf("Error restoring node: %v", err)
+	}
+
+	// The nodes use the committer in the library, so closing it first only
+	// closes it to its own callers: it is freed with the last node.
+	rlnc.Close()
+	committer.Close()
+	if _, err := committer.NewNode(numChunks); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from NewNode, got %v", err)
+	}
+	if got := len(committer.deferred); got != 1 {
+		t.Fatalf("Expected the committer's free to wait for its nodes, got %d deferred", got)
+	}
+	clonedNode, err := restoredNode.Clone()
+	if err != nil {
+		t.Fatalf("Error cloning node after closing the committer: %v", err)
+	}
+	for _, node := range []*Node{destinationNode, restoredNode, clonedNode} {
+		for !node.IsFull() {
+			chunk, err := sourceNode.ChunkToSend()
+			if err != nil {
+				t.Fatalf("Error getting chunk to send after closing the committer: %v", err)
+			}
+			if _, err := node.ReceiveChunk(chunk); err != nil && !errors.Is(err, ErrLinearlyDependent) {
+				t.Fatalf("Error receiving chunk after closing the committer: %v", err)
+			}
+		}
+		got, err := node.Data()
+		if err != nil {
+			t.Fatalf("Error decoding after closing the committer: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Decoded data doesn't match after closing the committer")
+		}
+	}
+
+	for _, node := range []*Node{sourceNode, destinationNode, restoredNode} {
+		node.Close()
+		if committer.deferred == nil || rlnc.unloaded {
+			t.Fatalf("Committer freed while a node is still open")
+		}
+	}
+	clonedNode.Close()
+	if committer.deferred != nil || committer.nodes.Load() != 0 {
+		t.Fatalf("Committer not freed after its last node was closed")
+	}
+	if !rlnc.unloaded {
+		t.Fatalf("Library not unloaded after the last handle was closed")
+	}
+}
+
+func TestUseAfterClose(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+
+	numChunks := 2
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	serialized, err := committer.Serialize()
+	if err != nil {
+		t.Fatalf("Error serializing committer: %v", err)
+	}
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+
+	sourceNode.Close()
+	sourceNode.Close()
+	if _, err := sourceNode.ChunkToSend(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from ChunkToSend, got %v", err)
+	}
+	if _, err := sourceNode.SystematicChunk(0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from SystematicChunk, got %v", err)
+	}
+	if _, err := sourceNode.ReceiveChunk(chunk); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from ReceiveChunk, got %v", err)
+	}
+	if _, err := sourceNode.Data(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from Data, got %v", err)
+	}
+	if _, err := sourceNode.WriteTo(io.Discard); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from WriteTo, got %v", err)
+	}
+	if _, err := sourceNode.Snapshot(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from Snapshot, got %v", err)
+	}
+	if _, err := sourceNode.Coefficients(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from Coefficients, got %v", err)
+	}
+	if sourceNode.IsFull() || sourceNode.Rank() != 0 {
+		t.Fatalf("Expected a closed node to be empty")
+	}
+
+	committer.Close()
+	committer.Close()
+	if _, err := committer.Serialize(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from Serialize, got %v", err)
+	}
+	if err := committer.VerifyChunk(chunk); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from VerifyChunk, got %v", err)
+	}
+	if _, err := committer.NewSourceNode(data, numChunks); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from NewSourceNode, got %v", err)
+	}
+	if _, err := committer.RestoreNode([]byte{2, 0, 0, 0}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from RestoreNode, got %v", err)
+	}
+	if _, err := committer.NewNode(numChunks); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from NewNode, got %v", err)
+	}
+
+	rlnc.Close()
+	rlnc.Close()
+	if _, err := rlnc.GenCommitterForChunkSize(32); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from GenCommitterForChunkSize, got %v", err)
+	}
+	if _, err := DeserializeCommitter(rlnc, serialized); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from DeserializeCommitter, got %v", err)
+	}
+	if _, err := rlnc.CommitmentsHash(chunk); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed from CommitmentsHash, got %v", err)
+	}
+	if !rlnc.unloaded {
+		t.Fatalf("Library not unloaded after the last handle was closed")
+	}
+}
+
+func TestCommitterCloseWhileCreating(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+
+	// Nodes are created until Close lands, and then fail cleanly instead of
+	// borrowing a freed committer.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var n *Node
+				var err error
+				if i%2 == 0 {
+					n, err = committer.NewNode(numChunks)
+				} else {
+					n, err = committer.NewSourceNode(data, numChunks)
+				}
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Error creating node: %v", err)
+					return
+				}
+				n.Close()
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	committer.Close()
+	wg.Wait()
+
+	rlnc.mu.Lock()
+	handles := rlnc.handles
+	rlnc.mu.Unlock()
+	if handles != 0 {
+		t.Fatalf("Expected every handle to be released, got %d held", handles)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	if rlnc.Version() != abiVersion {
+		t.Fatalf("Expected ABI version %d, got %d", abiVersion, rlnc.Version())
+	}
+
+	rlnc.getVersion = func() uint32 { return abiVersion + 1 }
+	if err := rlnc.checkVersion(); err == nil {
+		t.Fatalf("Expected an error for a mismatched ABI version")
+	}
+}
+
+func TestConcurrentNewRLNC(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rlnc, err := NewRLNC()
+			if err != nil {
+				errs <- err
+				return
+			}
+			rlnc.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Error creating RLNC: %v", err)
+	}
+}
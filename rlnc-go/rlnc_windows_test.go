@@ -0,0 +1,19 @@
+//go:build windows && amd64
+
+package rlnc
+
+import "testing"
+
+func TestWindowsLoad(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	committer, err := rlnc.GenCommitterForMessage(31*512*8, 8)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+}
@@ -0,0 +1,212 @@
+package rlnctest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/marcopolo/rlnc_poc/rlnc-go"
+)
+
+// TestCoder checks that c codes and decodes blocks of numChunks chunks of
+// chunkSize bytes with the semantics of the real Committer and Node: which
+// chunks are innovative, dependent or rejected, and with which errors. It
+// runs against the real types in the rlnc tests, so fakes passing it can
+// stand in for them.
+func TestCoder(t *testing.T, c rlnc.CommitterAPI, numChunks, chunkSize int) {
+	newBlock := func(t *testing.T) []byte {
+		block := make([]byte, numChunks*chunkSize)
+		rand.Read(block)
+		return block
+	}
+	newSource := func(t *testing.T, block []byte) rlnc.ChunkSource {
+		source, err := c.NewSource(block, numChunks)
+		if err != nil {
+			t.Fatalf("Error creating source: %v", err)
+		}
+		t.Cleanup(source.Close)
+		return source
+	}
+	newSink := func(t *testing.T, numChunks int) rlnc.ChunkSink {
+		sink, err := c.NewSink(numChunks)
+		if err != nil {
+			t.Fatalf("Error creating sink: %v", err)
+		}
+		t.Cleanup(sink.Close)
+		return sink
+	}
+	send := func(t *testing.T, source rlnc.ChunkSource) []byte {
+		chunk, err := source.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error sending chunk: %v", err)
+		}
+		return chunk
+	}
+
+	t.Run("Decode", func(t *testing.T) {
+		block := newBlock(t)
+		source, sink := newSource(t, block), newSink(t, numChunks)
+		if _, err := sink.Data(); !errors.Is(err, rlnc.ErrNotReady) {
+			t.Fatalf("Expected ErrNotReady decoding before the sink is full, got %v", err)
+		}
+		for !sink.IsFull() {
+			rank := sink.Rank()
+			innovative, err := sink.ReceiveChunk(send(t, source))
+			if err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+			want := rank
+			if innovative {
+				want++
+			}
+			if sink.Rank() != want {
+				t.Fatalf("Expected rank %d after an innovative=%v chunk at rank %d, got %d", want, innovative, rank, sink.Rank())
+			}
+		}
+		if sink.Rank() != numChunks {
+			t.Fatalf("Expected a full sink to have rank %d, got %d", numChunks, sink.Rank())
+		}
+		if innovative, err := sink.ReceiveChunk(send(t, source)); innovative || err != nil {
+			t.Fatalf("Expected a chunk received when full to be dependent, got %v, %v", innovative, err)
+		}
+		data, err := sink.Data()
+		if err != nil {
+			t.Fatalf("Error decoding: %v", err)
+		}
+		if !bytes.Equal(data, block) {
+			t.Fatalf("Decoded data doesn't match the block")
+		}
+	})
+
+	t.Run("Dependent", func(t *testing.T) {
+		source, sink := newSource(t, newBlock(t)), newSink(t, numChunks)
+		chunk := send(t, source)
+		if innovative, err := sink.ReceiveChunk(chunk); !innovative || err != nil {
+			t.Fatalf("Expected the first chunk to be innovative, got %v, %v", innovative, err)
+		}
+		if innovative, err := sink.ReceiveChunk(chunk); innovative || err != nil {
+			t.Fatalf("Expected a repeated chunk to be dependent, got %v, %v", innovative, err)
+		}
+		if sink.Rank() != 1 {
+			t.Fatalf("Expected rank 1, got %d", sink.Rank())
+		}
+	})
+
+	t.Run("ReusedBuffer", func(t *testing.T) {
+		block := newBlock(t)
+		source, other := newSource(t, block), newSource(t, newBlock(t))
+		sink := newSink(t, numChunks)
+		// A single read buffer, overwritten with garbage after each receive
+		// like the next datagram would.
+		buf := send(t, source)
+		garbage := func() {
+			for i := range buf {
+				buf[i] = byte(i*7 + 0x5a)
+			}
+		}
+		for !sink.IsFull() {
+			copy(buf, send(t, source))
+			if _, err := sink.ReceiveChunk(buf); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+			garbage()
+		}
+		if data, err := sink.Data(); err != nil || !bytes.Equal(data, block) {
+			t.Fatalf("Expected the block decoded from a reused buffer, got %v", err)
+		}
+
+		// The error of a rejected chunk doesn't point into it either.
+		otherChunk := send(t, other)
+		otherHash, err := c.ChunkHash(otherChunk)
+		if err != nil {
+			t.Fatalf("Error hashing chunk: %v", err)
+		}
+		sink = newSink(t, numChunks)
+		copy(buf, send(t, source))
+		if _, err := sink.ReceiveChunk(buf); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		copy(buf, otherChunk)
+		_, mismatchErr := sink.ReceiveChunk(buf)
+		garbage()
+		var mismatch *rlnc.BlockMismatchError
+		if !errors.As(mismatchErr, &mismatch) || !bytes.HasPrefix(otherHash, mismatch.Chunk) {
+			t.Fatalf("Expected the error to keep the chunk's hash prefix once the buffer is reused, got %v", mismatchErr)
+		}
+		for !sink.IsFull() {
+			copy(buf, send(t, source))
+			if _, err := sink.ReceiveChunk(buf); err != nil {
+				t.Fatalf("Error receiving chunk: %v", err)
+			}
+			garbage()
+		}
+		data, err := sink.Data()
+		if err != nil {
+			t.Fatalf("Error decoding: %v", err)
+		}
+		if !bytes.Equal(data, block) {
+			t.Fatalf("Decoded data doesn't match the block")
+		}
+	})
+
+	t.Run("OtherBlock", func(t *testing.T) {
+		source, other := newSource(t, newBlock(t)), newSource(t, newBlock(t))
+		sink := newSink(t, numChunks)
+		chunk, otherChunk := send(t, source), send(t, other)
+		if _, err := sink.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		_, mismatchErr := sink.ReceiveChunk(otherChunk)
+		if !errors.Is(mismatchErr, rlnc.ErrBlockMismatch) || !errors.Is(mismatchErr, rlnc.ErrCommitmentMismatch) {
+			t.Fatalf("Expected ErrBlockMismatch for a chunk of another block, got %v", mismatchErr)
+		}
+		if sink.Rank() != 1 {
+			t.Fatalf("Expected a rejected chunk to leave rank 1, got %d", sink.Rank())
+		}
+
+		hash, err := c.ChunkHash(chunk)
+		if err != nil {
+			t.Fatalf("Error hashing chunk: %v", err)
+		}
+		if again, _ := c.ChunkHash(send(t, source)); !bytes.Equal(again, hash) {
+			t.Fatalf("Expected chunks of a block to have the same hash")
+		}
+		otherHash, _ := c.ChunkHash(otherChunk)
+		if bytes.Equal(otherHash, hash) {
+			t.Fatalf("Expected chunks of another block to have another hash")
+		}
+		// The error names both blocks by the prefix of their hash.
+		var mismatch *rlnc.BlockMismatchError
+		if !errors.As(mismatchErr, &mismatch) || len(mismatch.Node) == 0 || !bytes.HasPrefix(hash, mismatch.Node) || len(mismatch.Chunk) == 0 || !bytes.HasPrefix(otherHash, mismatch.Chunk) {
+			t.Fatalf("Expected the error to carry the hash prefixes of both blocks, got %v", mismatchErr)
+		}
+	})
+
+	t.Run("NumChunks", func(t *testing.T) {
+		source, sink := newSource(t, newBlock(t)), newSink(t, numChunks+1)
+		if _, err := sink.ReceiveChunk(send(t, source)); !errors.Is(err, rlnc.ErrChunkMismatch) {
+			t.Fatalf("Expected ErrChunkMismatch for a chunk of another number of chunks, got %v", err)
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		source, sink := newSource(t, newBlock(t)), newSink(t, numChunks)
+		chunk := send(t, source)
+		if _, err := sink.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		sink.Close()
+		sink.Close()
+		if _, err := sink.ReceiveChunk(chunk); !errors.Is(err, rlnc.ErrClosed) {
+			t.Fatalf("Expected ErrClosed receiving on a closed sink, got %v", err)
+		}
+		if sink.Rank() != 0 || sink.IsFull() {
+			t.Fatalf("Expected a closed sink to have rank 0 and not be full")
+		}
+		source.Close()
+		if _, err := source.ChunkToSend(); !errors.Is(err, rlnc.ErrClosed) {
+			t.Fatalf("Expected ErrClosed sending from a closed source, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+// Package rlnctest provides a fake coder for testing code built on the rlnc
+// interfaces without the native library, and a conformance suite checking
+// that a coder behaves like the real one.
+package rlnctest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/marcopolo/rlnc_poc/rlnc-go"
+)
+
+// headerSize is the size of a fake chunk before its coefficients: the block
+// hash and the number of chunks as a little-endian uint32.
+const headerSize = sha256.Size + 4
+
+// Committer is an rlnc.CommitterAPI doing random linear coding over GF(256)
+// in Go. Its chunks carry the SHA-256 hash of their block in place of
+// commitments, so nothing is verified: it is for tests only. Blocks are split
+// like by the real Committer, into chunks of a multiple of 32 bytes, and
+// chunks are innovative, dependent or rejected like by a real Node.
+type Committer struct{}
+
+// NewCommitter returns a fake Committer.
+func NewCommitter() *Committer {
+	return &Committer{}
+}
+
+// NewSource returns a Source coding block split into numChunks chunks.
+func (c *Committer) NewSource(block []byte, numChunks int) (rlnc.ChunkSource, error) {
+	return NewSource(block, numChunks)
+}
+
+// NewSink returns a Sink decoding blocks of numChunks chunks.
+func (c *Committer) NewSink(numChunks int) (rlnc.ChunkSink, error) {
+	return NewSink(numChunks), nil
+}
+
+// ChunkHash returns the hash of the block chunk belongs to.
+func (c *Committer) ChunkHash(chunk []byte) ([]byte, error) {
+	if len(chunk) < headerSize {
+		return nil, fmt.Errorf("%w: chunk is too short", rlnc.ErrMalformedChunk)
+	}
+	return bytes.Clone(chunk[:sha256.Size]), nil
+}
+
+// Source produces random combinations of the chunks of a block.
+type Source struct {
+	hash   [sha256.Size]byte
+	chunks [][]byte
+	closed bool
+}
+
+// NewSource splits block into numChunks chunks, which must be a multiple of
+// 32 bytes like for rlnc.Committer.NewSourceNode.
+func NewSource(block []byte, numChunks int) (*Source, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	if len(block) == 0 || len(block)%numChunks != 0 {
+		return nil, fmt.Errorf("block size must be a multiple of chunk size")
+	}
+	chunkSize := len(block) / numChunks
+	if chunkSize%32 != 0 {
+		return nil, fmt.Errorf("block implies %d byte chunks, which aren't a multiple of 32 bytes", chunkSize)
+	}
+	s := &Source{hash: sha256.Sum256(block)}
+	for i := range numChunks {
+		s.chunks = append(s.chunks, bytes.Clone(block[i*chunkSize:(i+1)*chunkSize]))
+	}
+	return s, nil
+}
+
+// NewSourcePadded pads data with rlnc.PadBlock so any size fits, like
+// rlnc.Committer.NewSourceNodePadded. Its chunks decode with a Sink from
+// NewSinkPadded.
+func NewSourcePadded(data []byte, numChunks int) (*Source, error) {
+	if numChunks <= 0 {
+		return nil, fmt.Errorf("num chunks must be positive, got %d", numChunks)
+	}
+	return NewSource(rlnc.PadBlock(data, numChunks), numChunks)
+}
+
+// ChunkToSend returns a random combination of the chunks, with at least one
+// non-zero coefficient.
+func (s *Source) ChunkToSend() ([]byte, error) {
+	if s.closed {
+		return nil, rlnc.ErrClosed
+	}
+	n := len(s.chunks)
+	chunk := make([]byte, headerSize+n+len(s.chunks[0]))
+	copy(chunk, s.hash[:])
+	binary.LittleEndian.PutUint32(chunk[sha256.Size:], uint32(n))
+	coefficients, payload := chunk[headerSize:headerSize+n], chunk[headerSize+n:]
+	for allZero := true; allZero; {
+		for i := range coefficients {
+			coefficients[i] = byte(rand.UintN(256))
+			allZero = allZero && coefficients[i] == 0
+		}
+	}
+	for i, c := range coefficients {
+		gfAddMul(payload, s.chunks[i], c)
+	}
+	return chunk, nil
+}
+
+// Close marks s closed. Calling it again does nothing.
+func (s *Source) Close() {
+	s.closed = true
+}
+
+// Sink decodes a block from the chunks of a Source.
+type Sink struct {
+	numChunks int
+	padded    bool
+	// hash and rowLen are the block hash and coefficients and payload length
+	// of the first chunk received, which later ones must match.
+	hash   []byte
+	rowLen int
+	// rows holds the chunks received in reduced echelon form, indexed by the
+	// column of their leading coefficient, which is 1. Each row is the
+	// coefficients followed by the payload.
+	rows   [][]byte
+	rank   int
+	closed bool
+}
+
+// NewSink returns a Sink decoding a block of numChunks chunks.
+func NewSink(numChunks int) *Sink {
+	return &Sink{numChunks: numChunks, rows: make([][]byte, numChunks)}
+}
+
+// NewSinkPadded is NewSink for the chunks of a Source from NewSourcePadded,
+// stripping the padding from Data.
+func NewSinkPadded(numChunks int) *Sink {
+	s := NewSink(numChunks)
+	s.padded = true
+	return s
+}
+
+// ReceiveChunk adds chunk to s and reports whether it was innovative. Like
+// rlnc.Node.ReceiveChunk, a dependent chunk is dropped without an error, and
+// chunks of another block or shape are rejected with the same errors.
+func (s *Sink) ReceiveChunk(chunk []byte) (bool, error) {
+	if s.closed {
+		return false, rlnc.ErrClosed
+	}
+	if len(chunk) < headerSize {
+		return false, fmt.Errorf("%w: chunk is too short", rlnc.ErrMalformedChunk)
+	}
+	if count := binary.LittleEndian.Uint32(chunk[sha256.Size:]); count != uint32(s.numChunks) {
+		return false, &rlnc.NumChunksError{Node: s.numChunks, Chunk: uint64(count)}
+	}
+	row := chunk[headerSize:]
+	if len(row) <= s.numChunks {
+		return false, fmt.Errorf("%w: chunk has no payload", rlnc.ErrMalformedChunk)
+	}
+	if s.hash == nil {
+		s.hash = bytes.Clone(chunk[:sha256.Size])
+		s.rowLen = len(row)
+	} else if !bytes.Equal(chunk[:sha256.Size], s.hash) {
+		return false, &rlnc.BlockMismatchError{Node: s.hash[:4], Chunk: bytes.Clone(chunk[:4]), Err: rlnc.ErrCommitmentMismatch}
+	} else if len(row) != s.rowLen {
+		return false, &rlnc.BlockMismatchError{Node: s.hash[:4], Chunk: bytes.Clone(chunk[:4]), Err: rlnc.ErrChunkMismatch}
+	}
+
+	row = bytes.Clone(row)
+	for j, r := range s.rows {
+		if r != nil {
+			gfAddMul(row, r, row[j])
+		}
+	}
+	pivot := firstNonZero(row[:s.numChunks])
+	if pivot < 0 {
+		return false, nil
+	}
+	gfScale(row, gfInv(row[pivot]))
+	for _, r := range s.rows {
+		if r != nil {
+			gfAddMul(r, row, r[pivot])
+		}
+	}
+	s.rows[pivot] = row
+	s.rank++
+	return true, nil
+}
+
+func firstNonZero(b []byte) int {
+	for i, x := range b {
+		if x != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsFull reports whether s holds enough chunks to decode.
+func (s *Sink) IsFull() bool {
+	return !s.closed && s.rank == s.numChunks
+}
+
+// Rank returns the number of linearly independent chunks s holds, 0 once it
+// is closed.
+func (s *Sink) Rank() int {
+	if s.closed {
+		return 0
+	}
+	return s.rank
+}
+
+// Data returns the decoded block once s is full.
+func (s *Sink) Data() ([]byte, error) {
+	if s.closed {
+		return nil, rlnc.ErrClosed
+	}
+	if !s.IsFull() {
+		return nil, &rlnc.NotReadyError{Rank: s.rank, NumChunks: s.numChunks}
+	}
+	var block []byte
+	for _, r := range s.rows {
+		block = append(block, r[s.numChunks:]...)
+	}
+	if s.padded {
+		return rlnc.UnpadBlock(block)
+	}
+	return block, nil
+}
+
+// Close marks s closed. Calling it again does nothing.
+func (s *Sink) Close() {
+	s.closed = true
+}
@@ -0,0 +1,54 @@
+package rlnctest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func TestFakeCommitter(t *testing.T) {
+	for _, numChunks := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("%dChunks", numChunks), func(t *testing.T) {
+			TestCoder(t, NewCommitter(), numChunks, 64)
+		})
+	}
+}
+
+func TestFakePadded(t *testing.T) {
+	numChunks := 3
+	data := make([]byte, 1000)
+	rand.Read(data)
+	source, err := NewSourcePadded(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source: %v", err)
+	}
+	sink := NewSinkPadded(numChunks)
+	for !sink.IsFull() {
+		chunk, err := source.ChunkToSend()
+		if err != nil {
+			t.Fatalf("Error sending chunk: %v", err)
+		}
+		if _, err := sink.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+	}
+	decoded, err := sink.Data()
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+}
+
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := gfMul(byte(a), gfInv(byte(a))); got != 1 {
+			t.Fatalf("Expected %d times its inverse to be 1, got %d", a, got)
+		}
+	}
+	if gfMul(0x53, 0xca) != gfMul(0xca, 0x53) {
+		t.Fatalf("Expected multiplication to commute")
+	}
+}
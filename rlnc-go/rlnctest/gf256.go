@@ -0,0 +1,47 @@
+package rlnctest
+
+// GF(256) arithmetic with the polynomial x^8+x^4+x^3+x^2+1, whose element 2
+// generates the multiplicative group.
+var gfExp, gfLog = gfTables()
+
+func gfTables() (exp [510]byte, log [256]byte) {
+	x := 1
+	for i := range 255 {
+		exp[i] = byte(x)
+		exp[i+255] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfAddMul sets dst to dst + c*src.
+func gfAddMul(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	for i, x := range src {
+		dst[i] ^= gfMul(c, x)
+	}
+}
+
+// gfScale sets v to c*v.
+func gfScale(v []byte, c byte) {
+	for i, x := range v {
+		v[i] = gfMul(c, x)
+	}
+}
@@ -0,0 +1,139 @@
+package rlnc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+)
+
+// ErrNodeFull is returned by ChunkWriter.Write once its node has received
+// enough chunks to decode, ending an io.Copy into it.
+var ErrNodeFull = errors.New("node is full")
+
+// ChunkReader reads a stream of coded chunks from a Node. Each chunk is framed
+// as its length, encoded as a uvarint, followed by the chunk itself.
+type ChunkReader struct {
+	n     *Node
+	limit int
+	sent  int
+	frame []byte
+}
+
+// NewChunkReader returns a ChunkReader producing chunks from n. Since there is
+// no end to the chunks a node can produce, the stream only ends with io.EOF
+// after limit chunks, or never if limit is 0.
+func NewChunkReader(n *Node, limit int) *ChunkReader {
+	return &ChunkReader{n: n, limit: limit}
+}
+
+// Read reads at most one frame. A frame that doesn't fit in p is returned
+// over several calls.
+func (cr *ChunkReader) Read(p []byte) (int, error) {
+	if len(cr.frame) == 0 {
+		if cr.limit > 0 && cr.sent == cr.limit {
+			return 0, io.EOF
+		}
+		chunk, err := cr.n.ChunkToSend()
+		if err != nil {
+			return 0, err
+		}
+		cr.frame = binary.AppendUvarint(cr.frame[:0], uint64(len(chunk)))
+		cr.frame = append(cr.frame, chunk...)
+		cr.sent++
+	}
+	n := copy(p, cr.frame)
+	cr.frame = cr.frame[n:]
+	return n, nil
+}
+
+// ChunkWriter feeds a stream of chunks framed like a ChunkReader's into a
+// Node.
+type ChunkWriter struct {
+	n   *Node
+	buf []byte
+}
+
+// NewChunkWriter returns a ChunkWriter passing chunks to n.
+func NewChunkWriter(n *Node) *ChunkWriter {
+	return &ChunkWriter{n: n}
+}
+
+// Write buffers p and receives every complete frame. Chunks that aren't
+// innovative are dropped, any other receive error is returned. Once the node
+// is full Write returns ErrNodeFull, with the bytes after the last frame it
+// needed left unconsumed.
+func (cw *ChunkWriter) Write(p []byte) (int, error) {
+	if cw.Done() {
+		return 0, ErrNodeFull
+	}
+	buffered := len(cw.buf)
+	cw.buf = append(cw.buf, p...)
+	consumed := 0
+	for {
+		size, n := binary.Uvarint(cw.buf[consumed:])
+		if n < 0 {
+			return 0, errors.New("invalid chunk frame length")
+		}
+		if n == 0 || uint64(len(cw.buf)-consumed-n) < size {
+			break
+		}
+		chunk := cw.buf[consumed+n : consumed+n+int(size)]
+		consumed += n + int(size)
+		if _, err := cw.n.ReceiveChunk(chunk); err != nil {
+			cw.buf = append(cw.buf[:0], cw.buf[consumed:]...)
+			return max(consumed-buffered, 0), err
+		}
+		if cw.Done() {
+			cw.buf = nil
+			return max(consumed-buffered, 0), ErrNodeFull
+		}
+	}
+	cw.buf = append(cw.buf[:0], cw.buf[consumed:]...)
+	return len(p), nil
+}
+
+// Done reports whether the node has received enough chunks to decode.
+func (cw *ChunkWriter) Done() bool {
+	return cw.n.IsFull()
+}
+
+// ChunkSeq returns a sequence of coded chunks from n that ends when ctx is
+// done. An error getting a chunk is yielded as the second value and ends the
+// sequence. Each chunk is copied out of native memory before it is yielded,
+// so breaking out of the loop early leaves nothing to free.
+func (n *Node) ChunkSeq(ctx context.Context) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for ctx.Err() == nil {
+			chunk, err := n.ChunkToSend()
+			if !yield(chunk, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ReceiveSeq receives chunks from seq until n is full or seq ends, and
+// returns how many were innovative. Chunks that aren't innovative are
+// dropped, any other receive error stops it and is returned. Whether n is
+// full when seq ends early is up to the caller to check with IsFull.
+func (n *Node) ReceiveSeq(seq iter.Seq[[]byte]) (int, error) {
+	if n.IsFull() {
+		return 0, nil
+	}
+	innovative := 0
+	for chunk := range seq {
+		ok, err := n.ReceiveChunk(chunk)
+		if err != nil {
+			return innovative, err
+		}
+		if ok {
+			innovative++
+		}
+		if n.IsFull() {
+			break
+		}
+	}
+	return innovative, nil
+}
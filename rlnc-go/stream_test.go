@@ -0,0 +1,249 @@
+package rlnc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func newStreamNodes(t *testing.T, numChunks int) (*Node, *Node, []byte) {
+	t.Helper()
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	t.Cleanup(rlnc.Close)
+
+	data := make([]byte, 32*64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	t.Cleanup(committer.Close)
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	t.Cleanup(sourceNode.Close)
+	destinationNode, err := committer.NewNode(numChunks)
+	if err != nil {
+		t.Fatalf("Error creating node: %v", err)
+	}
+	t.Cleanup(destinationNode.Close)
+	return sourceNode, destinationNode, data
+}
+
+func TestChunkReader(t *testing.T) {
+	sourceNode, destinationNode, _ := newStreamNodes(t, 4)
+
+	// Reading a byte at a time has every frame split over many reads.
+	r := bufio.NewReader(iotest.OneByteReader(NewChunkReader(sourceNode, 6)))
+	frames := 0
+	for {
+		size, err := binary.ReadUvarint(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading frame length: %v", err)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			t.Fatalf("Error reading frame: %v", err)
+		}
+		if _, err := destinationNode.ReceiveChunk(chunk); err != nil {
+			t.Fatalf("Error receiving chunk: %v", err)
+		}
+		frames++
+	}
+	if frames != 6 {
+		t.Fatalf("Expected 6 frames, got %d", frames)
+	}
+	if !destinationNode.IsFull() {
+		t.Fatalf("Destination node is not full")
+	}
+}
+
+func TestChunkWriterThroughPipe(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 4)
+
+	// The copy runs until the pipe is closed, which must happen before the
+	// source node is freed.
+	pr, pw := io.Pipe()
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		_, err := io.Copy(pw, NewChunkReader(sourceNode, 0))
+		pw.CloseWithError(err)
+	}()
+	defer func() {
+		pr.Close()
+		<-copied
+	}()
+
+	w := NewChunkWriter(destinationNode)
+	buf := make([]byte, 4096)
+	for !w.Done() {
+		var size [1]byte
+		rand.Read(size[:])
+		n, err := pr.Read(buf[:1+int(size[0])%len(buf)])
+		if err != nil {
+			t.Fatalf("Error reading from pipe: %v", err)
+		}
+		if _, err := w.Write(buf[:n]); err != nil && !errors.Is(err, ErrNodeFull) {
+			t.Fatalf("Error writing chunks: %v", err)
+		}
+	}
+	if _, err := w.Write([]byte{1}); !errors.Is(err, ErrNodeFull) {
+		t.Fatalf("Expected ErrNodeFull writing to a full node, got %v", err)
+	}
+
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+}
+
+func TestChunkWriterTampered(t *testing.T) {
+	sourceNode, destinationNode, _ := newStreamNodes(t, 4)
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	chunk[8] ^= 1
+	frame := binary.AppendUvarint(nil, uint64(len(chunk)))
+	if _, err := NewChunkWriter(destinationNode).Write(append(frame, chunk...)); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected ErrInvalidMessage, got %v", err)
+	}
+}
+
+func TestChunkSeq(t *testing.T) {
+	sourceNode, destinationNode, data := newStreamNodes(t, 4)
+
+	// Chunks from a range loop fill the destination through ReceiveSeq, which
+	// stops pulling from ChunkSeq once it is full.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pulled := 0
+	chunks := func(yield func([]byte) bool) {
+		for chunk, err := range sourceNode.ChunkSeq(ctx) {
+			if err != nil {
+				t.Errorf("Error getting chunk to send: %v", err)
+				return
+			}
+			pulled++
+			if !yield(chunk) {
+				return
+			}
+		}
+	}
+	innovative, err := destinationNode.ReceiveSeq(chunks)
+	if err != nil {
+		t.Fatalf("Error receiving chunks: %v", err)
+	}
+	if innovative != 4 || !destinationNode.IsFull() {
+		t.Fatalf("Expected 4 innovative chunks filling the node, got %d", innovative)
+	}
+	if pulled != innovative {
+		t.Fatalf("Expected ReceiveSeq to stop pulling once full, pulled %d", pulled)
+	}
+	got, err := destinationNode.Data()
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Fatalf("Decoded data doesn't match")
+	}
+
+	// The sequence ends once ctx is done.
+	count := 0
+	for _, err := range sourceNode.ChunkSeq(ctx) {
+		if err != nil {
+			t.Fatalf("Error getting chunk to send: %v", err)
+		}
+		if count++; count == 3 {
+			cancel()
+		}
+	}
+	if count != 3 {
+		t.Fatalf("Expected 3 chunks before cancelling, got %d", count)
+	}
+}
+
+func TestChunkSeqErrors(t *testing.T) {
+	sourceNode, destinationNode, _ := newStreamNodes(t, 2)
+
+	// An empty node yields ErrNoChunksHeld once and stops.
+	yielded := 0
+	for chunk, err := range destinationNode.ChunkSeq(context.Background()) {
+		yielded++
+		if chunk != nil || !errors.Is(err, ErrNoChunksHeld) {
+			t.Fatalf("Expected ErrNoChunksHeld from an empty node, got %v", err)
+		}
+	}
+	if yielded != 1 {
+		t.Fatalf("Expected a single error, got %d values", yielded)
+	}
+
+	chunk, err := sourceNode.ChunkToSend()
+	if err != nil {
+		t.Fatalf("Error getting chunk to send: %v", err)
+	}
+	tampered := bytes.Clone(chunk)
+	tampered[8] ^= 1
+	seq := func(yield func([]byte) bool) {
+		for _, c := range [][]byte{chunk, tampered, chunk} {
+			if !yield(c) {
+				return
+			}
+		}
+	}
+	innovative, err := destinationNode.ReceiveSeq(seq)
+	if innovative != 1 || !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected 1 innovative chunk then ErrInvalidMessage, got %d, %v", innovative, err)
+	}
+}
+
+func TestChunkSeqBreak(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	data := make([]byte, 32*64*2)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), 2)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	sourceNode, err := committer.NewSourceNode(data, 2)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+
+	for range sourceNode.ChunkSeq(context.Background()) {
+		break
+	}
+	if _, err := sourceNode.ChunkToSend(); err != nil {
+		t.Fatalf("Error getting chunk to send after breaking: %v", err)
+	}
+
+	// Nothing the sequence created outlives the node, so the library unloads
+	// as soon as everything is closed.
+	sourceNode.Close()
+	committer.Close()
+	rlnc.Close()
+	if !rlnc.unloaded {
+		t.Fatalf("Expected the library to be unloaded")
+	}
+}
@@ -0,0 +1,177 @@
+package rlnc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// tamperSegments returns the offset of the first scalar or point of the
+// payload, coefficients and commitments of a chunk of numChunks chunks of
+// payloadScalars scalars each.
+func tamperSegments(payloadScalars, numChunks int) map[string][]int {
+	segments := map[string][]int{}
+	off := 0
+	for _, segment := range []struct {
+		name  string
+		count int
+	}{
+		{"payload", payloadScalars},
+		{"coefficients", numChunks},
+		{"commitments", numChunks},
+	} {
+		off += 8
+		for range segment.count {
+			segments[segment.name] = append(segments[segment.name], off)
+			off += 32
+		}
+	}
+	return segments
+}
+
+func TestTamperedChunks(t *testing.T) {
+	rlnc, err := NewRLNC()
+	if err != nil {
+		t.Fatalf("Error creating RLNC: %v", err)
+	}
+	defer rlnc.Close()
+
+	numChunks := 4
+	data := make([]byte, 64*numChunks)
+	rand.Read(data)
+	committer, err := rlnc.GenCommitterForMessage(len(data), numChunks)
+	if err != nil {
+		t.Fatalf("Error creating committer: %v", err)
+	}
+	defer committer.Close()
+	sourceNode, err := committer.NewSourceNode(data, numChunks)
+	if err != nil {
+		t.Fatalf("Error creating source node: %v", err)
+	}
+	defer sourceNode.Close()
+	chunks, err := sourceNode.Chunks(2 * numChunks)
+	if err != nil {
+		t.Fatalf("Error getting chunks: %v", err)
+	}
+	segments := tamperSegments(int(chunkSizeInScalars(64)), numChunks)
+	// A commitment whose coefficient is zero takes no part in verifying the
+	// chunk, so tampering with it would go unnoticed. Draw a chunk whose
+	// coefficients are all nonzero.
+	for slices.ContainsFunc(segments["coefficients"], func(off int) bool {
+		return bytes.Equal(chunks[0][off:off+32], make([]byte, 32))
+	}) {
+		if chunks[0], err = sourceNode.ChunkToSend(); err != nil {
+			t.Fatalf("Error getting chunk: %v", err)
+		}
+	}
+	chunk, valid := chunks[0], chunks[1:]
+
+	// A fresh node takes the commitments of the first chunk that verifies,
+	// while one holding a chunk compares them with its own first.
+	for _, state := range []struct {
+		name    string
+		holding bool
+	}{{"Fresh", false}, {"Holding", true}} {
+		holding := state.holding
+		t.Run(state.name, func(t *testing.T) {
+			node, err := committer.NewNode(numChunks)
+			if err != nil {
+				t.Fatalf("Error creating node: %v", err)
+			}
+			defer node.Close()
+			if holding {
+				if ok, err := node.ReceiveChunk(valid[0]); !ok || err != nil {
+					t.Fatalf("Expected the first chunk to be innovative, got %v, %v", ok, err)
+				}
+			}
+			rank := node.Rank()
+			receive := func(tampered []byte) error {
+				t.Helper()
+				ok, err := node.ReceiveChunk(tampered)
+				if ok || err == nil {
+					t.Fatalf("Expected a tampered chunk to be rejected, got %v, %v", ok, err)
+				}
+				if got := node.Rank(); got != rank {
+					t.Fatalf("Expected a tampered chunk to leave rank %d, got %d", rank, got)
+				}
+				return err
+			}
+
+			// The lowest bit of a scalar keeps it canonical, so the chunk
+			// still parses and it is the commitments that catch it.
+			for _, name := range []string{"payload", "coefficients"} {
+				for i, off := range segments[name] {
+					tampered := bytes.Clone(chunk)
+					tampered[off] ^= 1
+					if err := receive(tampered); !errors.Is(err, ErrVerificationFailed) {
+						t.Fatalf("Expected ErrVerificationFailed for scalar %d of the %s, got %v", i, name, err)
+					}
+					if err := committer.VerifyChunk(tampered); !errors.Is(err, ErrVerificationFailed) {
+						t.Fatalf("Expected VerifyChunk to fail with ErrVerificationFailed for scalar %d of the %s, got %v", i, name, err)
+					}
+				}
+			}
+
+			// Most bits of a point give bytes that aren't a point at all,
+			// which the library fails to parse. The others are caught by the
+			// commitments, or by the commitments already held.
+			decoded := 0
+			for i, off := range segments["commitments"] {
+				for bit := 8; bit < 40; bit++ {
+					tampered := bytes.Clone(chunk)
+					tampered[off+bit/8] ^= 1 << (bit % 8)
+					err := receive(tampered)
+					if errors.Is(err, ErrReceiveFailed) {
+						continue
+					}
+					decoded++
+					want := ErrVerificationFailed
+					if holding {
+						want = ErrCommitmentMismatch
+					}
+					if !errors.Is(err, want) {
+						t.Fatalf("Expected %v for bit %d of commitment %d, got %v", want, bit, i, err)
+					}
+				}
+			}
+			if decoded == 0 {
+				t.Fatalf("Expected some tampered commitments to still be points")
+			}
+
+			// However the bit is chosen, no flip gets through.
+			for bit := range 8 * len(chunk) {
+				tampered := bytes.Clone(chunk)
+				tampered[bit/8] ^= 1 << (bit % 8)
+				receive(tampered)
+			}
+
+			innovative, errs := node.ReceiveChunks([][]byte{func() []byte {
+				tampered := bytes.Clone(chunk)
+				tampered[segments["payload"][0]] ^= 1
+				return tampered
+			}()})
+			if innovative != 0 || !errors.Is(errs[0], ErrVerificationFailed) {
+				t.Fatalf("Expected ReceiveChunks to fail with ErrVerificationFailed, got %d, %v", innovative, errs[0])
+			}
+
+			// The node is none the worse for it.
+			for _, chunk := range valid {
+				if node.IsFull() {
+					break
+				}
+				if _, err := node.ReceiveChunk(chunk); err != nil {
+					t.Fatalf("Error receiving chunk: %v", err)
+				}
+			}
+			got, err := node.Data()
+			if err != nil {
+				t.Fatalf("Error getting data: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("Decoded data doesn't match")
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
-use curve25519_dalek::constants::RISTRETTO_BASEPOINT_POINT;
 use curve25519_dalek::ristretto::RistrettoPoint;
 use curve25519_dalek::scalar::Scalar;
 use curve25519_dalek::traits::MultiscalarMul;
 use rand::Rng;
 use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha512};
 
 #[derive(Serialize, Deserialize)]
 pub struct Committer {
@@ -37,11 +37,20 @@ impl Committer {
     }
 }
 
+// generators derives the points by hashing their index, so a committer only
+// depends on its size and nobody knows the discrete logs between its points.
 // TODO: read the points from file instead of computing them at runtime
 fn generators(n: usize) -> Vec<RistrettoPoint> {
-    let mut rng = rand::thread_rng();
     (0..n)
-        .map(|_| RISTRETTO_BASEPOINT_POINT * Scalar::from(rng.gen::<u128>()))
+        .map(|i| {
+            let hash = Sha512::new()
+                .chain_update(b"rlnc_poc generator")
+                .chain_update((i as u64).to_le_bytes())
+                .finalize();
+            let mut bytes = [0u8; 64];
+            bytes.copy_from_slice(&hash);
+            RistrettoPoint::from_uniform_bytes(&bytes)
+        })
         .collect()
 }
 
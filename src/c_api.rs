@@ -1,14 +1,84 @@
+use std::cell::RefCell;
 use std::ptr;
+use std::sync::atomic::{AtomicU64, Ordering};
 
 use crate::blocks::Committer;
-use crate::node::{Message, Node, ReceiveError};
+use crate::node::{
+    commit_block as commit_block_fn, Message, Node, ReceiveError,
+};
+
+// ABI_VERSION must be bumped whenever an exported function changes, so the
+// bindings can refuse to load a library they don't match. Version 2 has
+// send_chunk return -2 for a node holding no chunks, decode and
+// decode_verified return -2 when decoding fails, and decode_verified return
+// -3 for data that doesn't match the commitments.
+pub const ABI_VERSION: u32 = 2;
+
+#[no_mangle]
+pub extern "C" fn get_version() -> u32 {
+    ABI_VERSION
+}
+
+thread_local! {
+    // LAST_ERROR holds why the last constructor that returned null on this
+    // thread failed, until last_error takes it.
+    static LAST_ERROR: RefCell<Option<String>> = RefCell::new(None);
+}
+
+// LIVE_HANDLES counts the committers and nodes handed out and not yet freed.
+static LIVE_HANDLES: AtomicU64 = AtomicU64::new(0);
+
+// live_handles returns the number of committers and nodes not yet freed, so
+// the bindings can check for leaks.
+#[no_mangle]
+pub extern "C" fn live_handles() -> u64 {
+    LIVE_HANDLES.load(Ordering::Relaxed)
+}
+
+// box_handle boxes value as a handle, counting it in LIVE_HANDLES.
+fn box_handle<T>(value: T) -> *const std::ffi::c_void {
+    LIVE_HANDLES.fetch_add(1, Ordering::Relaxed);
+    Box::into_raw(Box::new(value)) as *const std::ffi::c_void
+}
+
+// into_ptr boxes value, or records the error for last_error and returns
+// null.
+fn into_ptr<T>(value: Result<T, String>) -> *const std::ffi::c_void {
+    match value {
+        Ok(value) => box_handle(value),
+        Err(e) => {
+            LAST_ERROR.with(|last| *last.borrow_mut() = Some(e));
+            ptr::null()
+        }
+    }
+}
+
+// last_error writes the message of the last error recorded on this thread
+// and clears it. It returns -1 if there is none.
+#[no_mangle]
+pub extern "C" fn last_error(
+    out_ptr: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    match LAST_ERROR.with(|last| last.borrow_mut().take()) {
+        Some(e) => {
+            let bytes = e.into_bytes().into_boxed_slice();
+            unsafe {
+                *out_len = bytes.len();
+                *out_ptr = Box::into_raw(bytes) as *mut u8;
+            }
+            0
+        }
+        None => -1,
+    }
+}
 
 #[no_mangle]
 pub extern "C" fn gen_committer(
     chunk_size_in_scalars: u32,
 ) -> *const std::ffi::c_void {
     let committer = Committer::new(chunk_size_in_scalars as usize);
-    return Box::into_raw(Box::new(committer)) as *const std::ffi::c_void;
+    box_handle(committer)
 }
 
 #[no_mangle]
@@ -33,14 +103,23 @@ pub extern "C" fn deserialize_committer(
     let serialized =
         unsafe { std::slice::from_raw_parts(serialized_ptr, serialized_len) };
 
-    bincode::deserialize::<Committer>(&serialized)
-        .and_then(|c| Ok(Box::into_raw(Box::new(c)) as *const std::ffi::c_void))
-        .unwrap_or(ptr::null())
+    into_ptr(
+        bincode::deserialize::<Committer>(&serialized)
+            .map_err(|e| e.to_string()),
+    )
 }
 
 #[no_mangle]
 pub extern "C" fn free_committer(committer_ptr: *const std::ffi::c_void) {
     unsafe { drop(Box::from_raw(committer_ptr as *mut Committer)) }
+    LIVE_HANDLES.fetch_sub(1, Ordering::Relaxed);
+}
+
+// committer_len returns the number of scalars per chunk the committer supports.
+#[no_mangle]
+pub extern "C" fn committer_len(committer_ptr: *const std::ffi::c_void) -> u32 {
+    let committer = unsafe { &*(committer_ptr as *const Committer) };
+    committer.len() as u32
 }
 
 #[no_mangle]
@@ -50,7 +129,7 @@ pub extern "C" fn new_node(
 ) -> *const std::ffi::c_void {
     let commiter = unsafe { &*(commiter as *const Committer) };
     let node = Node::new(commiter, num_chunks as usize);
-    return Box::into_raw(Box::new(node)) as *const std::ffi::c_void;
+    box_handle(node)
 }
 
 #[no_mangle]
@@ -62,15 +141,130 @@ pub extern "C" fn new_source_node(
 ) -> *const std::ffi::c_void {
     let commiter = unsafe { &*(commiter as *const Committer) };
     let block = unsafe { std::slice::from_raw_parts(block, block_len) };
-    if let Ok(node) = Node::new_source(commiter, block, num_chunks as usize) {
-        return Box::into_raw(Box::new(node)) as *const std::ffi::c_void;
+    into_ptr(Node::new_source(commiter, block, num_chunks as usize))
+}
+
+// commit_block writes the serialized commitments to the chunks of a block,
+// the same as a chunk of its source node carries, without creating the node.
+#[no_mangle]
+pub extern "C" fn commit_block(
+    commiter: *const std::ffi::c_void,
+    block: *const u8,
+    block_len: usize,
+    num_chunks: u32,
+    out_ptr: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let commiter = unsafe { &*(commiter as *const Committer) };
+    let block = unsafe { std::slice::from_raw_parts(block, block_len) };
+    match commit_block_fn(commiter, block, num_chunks as usize)
+        .and_then(|c| bincode::serialize(&c).map_err(|e| e.to_string()))
+    {
+        Ok(serialized) => {
+            unsafe {
+                *out_len = serialized.len();
+                *out_ptr =
+                    Box::into_raw(serialized.into_boxed_slice()) as *mut u8;
+            }
+            0
+        }
+        Err(_) => -1,
+    }
+}
+
+// new_source_node_with_commitments is new_source_node, reusing commitments
+// serialized by commit_block instead of computing them.
+#[no_mangle]
+pub extern "C" fn new_source_node_with_commitments(
+    commiter: *const std::ffi::c_void,
+    block: *const u8,
+    block_len: usize,
+    num_chunks: u32,
+    commitments_ptr: *const u8,
+    commitments_len: usize,
+) -> *const std::ffi::c_void {
+    let commiter = unsafe { &*(commiter as *const Committer) };
+    let block = unsafe { std::slice::from_raw_parts(block, block_len) };
+    let serialized =
+        unsafe { std::slice::from_raw_parts(commitments_ptr, commitments_len) };
+    into_ptr(
+        bincode::deserialize(serialized)
+            .map_err(|e| e.to_string())
+            .and_then(|commitments| {
+                Node::new_source_with_commitments(
+                    commiter,
+                    block,
+                    num_chunks as usize,
+                    commitments,
+                )
+            }),
+    )
+}
+
+// expect_commitments sets the commitments of the block a node will receive,
+// serialized like in a chunk. It returns -1 if they can't be deserialized, -2
+// if the node already holds others and -4 if there isn't one per chunk.
+#[no_mangle]
+pub extern "C" fn expect_commitments(
+    node_ptr: *const std::ffi::c_void,
+    commitments_ptr: *const u8,
+    commitments_len: usize,
+) -> i32 {
+    let node = unsafe { &mut *(node_ptr as *mut Node) };
+    let serialized =
+        unsafe { std::slice::from_raw_parts(commitments_ptr, commitments_len) };
+    match bincode::deserialize(serialized) {
+        Ok(commitments) => match node.expect_commitments(commitments) {
+            Ok(_) => 0,
+            Err(e) => error_code(e),
+        },
+        Err(_) => -1,
     }
-    ptr::null()
+}
+
+#[no_mangle]
+pub extern "C" fn serialize_node(
+    node_ptr: *const std::ffi::c_void,
+    out_ptr: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if let Ok(serialized) = node.snapshot() {
+        unsafe {
+            *out_len = serialized.len();
+            *out_ptr = Box::into_raw(serialized.into_boxed_slice()) as *mut u8;
+        }
+        return 0;
+    }
+    -1
+}
+
+#[no_mangle]
+pub extern "C" fn deserialize_node(
+    commiter: *const std::ffi::c_void,
+    num_chunks: u32,
+    serialized_ptr: *const u8,
+    serialized_len: usize,
+) -> *const std::ffi::c_void {
+    let commiter = unsafe { &*(commiter as *const Committer) };
+    let serialized =
+        unsafe { std::slice::from_raw_parts(serialized_ptr, serialized_len) };
+    into_ptr(Node::restore(commiter, num_chunks as usize, serialized))
+}
+
+// clone_node returns a deep copy of a node, sharing only its committer.
+#[no_mangle]
+pub extern "C" fn clone_node(
+    node_ptr: *const std::ffi::c_void,
+) -> *const std::ffi::c_void {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    box_handle(node.clone())
 }
 
 #[no_mangle]
 pub extern "C" fn free_node(node_ptr: *const std::ffi::c_void) {
     unsafe { drop(Box::from_raw(node_ptr as *mut Node)) }
+    LIVE_HANDLES.fetch_sub(1, Ordering::Relaxed);
 }
 
 #[no_mangle]
@@ -80,6 +274,9 @@ pub extern "C" fn send_chunk(
     out_len: *mut usize,
 ) -> i32 {
     let node = unsafe { &*(node_ptr as *const Node) };
+    if node.chunks().is_empty() {
+        return -2;
+    }
     if let Ok(serialized) = node.send().and_then(|message| {
         bincode::serialize(&message).map_err(|e| e.to_string())
     }) {
@@ -93,6 +290,121 @@ pub extern "C" fn send_chunk(
     -1
 }
 
+// send_chunks writes count new chunks one after the other. They all have the
+// same length. It returns -2 if the node holds no chunks, like send_chunk.
+#[no_mangle]
+pub extern "C" fn send_chunks(
+    node_ptr: *const std::ffi::c_void,
+    count: u32,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if node.chunks().is_empty() {
+        return -2;
+    }
+    let mut data = Vec::new();
+    for _ in 0..count {
+        match node.send().and_then(|message| {
+            bincode::serialize(&message).map_err(|e| e.to_string())
+        }) {
+            Ok(serialized) => data.extend_from_slice(&serialized),
+            Err(_) => return -1,
+        }
+    }
+    unsafe {
+        *out_len = data.len();
+        *out_data = Box::into_raw(data.into_boxed_slice()) as *mut u8;
+    }
+    0
+}
+
+#[no_mangle]
+pub extern "C" fn send_systematic_chunk(
+    node_ptr: *const std::ffi::c_void,
+    index: u32,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if let Ok(serialized) =
+        node.send_systematic(index as usize).and_then(|message| {
+            bincode::serialize(&message).map_err(|e| e.to_string())
+        })
+    {
+        unsafe {
+            *out_len = serialized.len();
+            let boxed = serialized.into_boxed_slice();
+            *out_data = Box::into_raw(boxed) as *mut u8;
+        }
+        return 0;
+    }
+    -1
+}
+
+// pivot_columns writes a byte per column of the node's echelon form, 1 if
+// it is a pivot and 0 if not, for a sender's send_chunk_for_needs.
+#[no_mangle]
+pub extern "C" fn pivot_columns(
+    node_ptr: *const std::ffi::c_void,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    let flags: Vec<u8> = node.pivots().into_iter().map(u8::from).collect();
+    unsafe {
+        *out_len = flags.len();
+        *out_data = Box::into_raw(flags.into_boxed_slice()) as *mut u8;
+    }
+    0
+}
+
+// send_chunk_for_needs is send_chunk for a receiver with the pivots written
+// by pivot_columns, returning a chunk innovative for it. It returns -1 unless
+// the node is a source node and the receiver still needs chunks.
+#[no_mangle]
+pub extern "C" fn send_chunk_for_needs(
+    node_ptr: *const std::ffi::c_void,
+    pivots_ptr: *const u8,
+    pivots_len: usize,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    let flags = unsafe { std::slice::from_raw_parts(pivots_ptr, pivots_len) };
+    let pivots: Vec<bool> = flags.iter().map(|&x| x != 0).collect();
+    if let Ok(serialized) = node.send_for_needs(&pivots).and_then(|message| {
+        bincode::serialize(&message).map_err(|e| e.to_string())
+    }) {
+        unsafe {
+            *out_len = serialized.len();
+            let boxed = serialized.into_boxed_slice();
+            *out_data = Box::into_raw(boxed) as *mut u8;
+        }
+        return 0;
+    }
+    -1
+}
+
+// reset_node empties a node so it can receive another block under the same
+// committer.
+#[no_mangle]
+pub extern "C" fn reset_node(node_ptr: *const std::ffi::c_void) {
+    let node = unsafe { &mut *(node_ptr as *mut Node) };
+    node.reset();
+}
+
+// set_seed seeds the RNG drawing the coefficients of the chunks the node
+// sends, making them reproducible. It is meant for tests.
+#[no_mangle]
+pub extern "C" fn set_seed(node_ptr: *const std::ffi::c_void, seed: u64) {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    node.set_seed(seed);
+}
+
+// receive_chunk receives the chunk at chunk_start. The chunk is only read
+// during the call, into values the node owns, so the caller may reuse it as
+// soon as the call returns.
 #[no_mangle]
 pub extern "C" fn receive_chunk(
     node_ptr: *const std::ffi::c_void,
@@ -101,20 +413,123 @@ pub extern "C" fn receive_chunk(
 ) -> i32 {
     let node = unsafe { &mut *(node_ptr as *mut Node) };
     let chunk = unsafe { std::slice::from_raw_parts(chunk_start, chunk_len) };
+    receive_code(node, chunk)
+}
 
-    match bincode::deserialize(chunk).or(Err(-1)).and_then(|message| {
-        node.receive(message).map_err(|e| match e {
-            ReceiveError::ExistingCommitmentsMismatch(_e) => -2,
-            ReceiveError::ExistingChunksMismatch(_e) => -3,
-            ReceiveError::InvalidMessage(_e) => -4,
-            ReceiveError::LinearlyDependentChunk => -5,
-        })
-    }) {
+fn receive_code(node: &mut Node, chunk: &[u8]) -> i32 {
+    match bincode::deserialize(chunk)
+        .or(Err(-1))
+        .and_then(|message| node.receive(message).map_err(error_code))
+    {
         Ok(_) => 0,
         Err(e) => e,
     }
 }
 
+fn error_code(e: ReceiveError) -> i32 {
+    match e {
+        ReceiveError::ExistingCommitmentsMismatch(_e) => -2,
+        ReceiveError::ExistingChunksMismatch(_e) => -3,
+        ReceiveError::InvalidMessage(_e) => -4,
+        ReceiveError::LinearlyDependentChunk => -5,
+        ReceiveError::MissingCommitments => -6,
+        ReceiveError::VerificationFailed => -7,
+    }
+}
+
+// receive_source_chunk receives the original chunk at index as raw bytes. The
+// commitments are serialized like in a chunk and may be left empty if the
+// node already holds some. It returns the receive_chunk codes, -1 if the
+// commitments can't be deserialized, or -6 if neither has commitments.
+#[no_mangle]
+pub extern "C" fn receive_source_chunk(
+    node_ptr: *const std::ffi::c_void,
+    index: u32,
+    data_start: *const u8,
+    data_len: usize,
+    commitments_start: *const u8,
+    commitments_len: usize,
+) -> i32 {
+    let node = unsafe { &mut *(node_ptr as *mut Node) };
+    let data = if data_len == 0 {
+        &[]
+    } else {
+        unsafe { std::slice::from_raw_parts(data_start, data_len) }
+    };
+    let commitments = if commitments_len == 0 {
+        None
+    } else {
+        let serialized = unsafe {
+            std::slice::from_raw_parts(commitments_start, commitments_len)
+        };
+        match bincode::deserialize(serialized) {
+            Ok(commitments) => Some(commitments),
+            Err(_) => return -1,
+        }
+    };
+    match node.receive_source(index as usize, data, commitments) {
+        Ok(_) => 0,
+        Err(e) => error_code(e),
+    }
+}
+
+// receive_chunks receives count chunks laid out one after the other, with
+// their lengths in lens, writing the receive_chunk code of each to codes.
+// Once the node is full the remaining chunks are skipped without being
+// verified and get -5, like dependent chunks. It returns the number of
+// innovative chunks.
+#[no_mangle]
+pub extern "C" fn receive_chunks(
+    node_ptr: *const std::ffi::c_void,
+    chunks_start: *const u8,
+    lens_start: *const u64,
+    count: usize,
+    codes_start: *mut i32,
+) -> i32 {
+    let node = unsafe { &mut *(node_ptr as *mut Node) };
+    let lens = unsafe { std::slice::from_raw_parts(lens_start, count) };
+    let codes = unsafe { std::slice::from_raw_parts_mut(codes_start, count) };
+    let total = lens.iter().sum::<u64>() as usize;
+    let mut chunks = unsafe { std::slice::from_raw_parts(chunks_start, total) };
+
+    let mut innovative = 0;
+    for (len, code) in lens.iter().zip(codes.iter_mut()) {
+        let (chunk, rest) = chunks.split_at(*len as usize);
+        chunks = rest;
+        *code = if node.is_full() {
+            -5
+        } else {
+            receive_code(node, chunk)
+        };
+        if *code == 0 {
+            innovative += 1;
+        }
+    }
+    innovative
+}
+
+// verify_chunk checks a chunk's payload against its commitments without a
+// node. It returns -1 if the chunk can't be deserialized, -7 if it doesn't
+// verify and -4 if it isn't a chunk the committer can verify, like
+// receive_chunk.
+#[no_mangle]
+pub extern "C" fn verify_chunk(
+    committer_ptr: *const std::ffi::c_void,
+    chunk_start: *const u8,
+    chunk_len: usize,
+) -> i32 {
+    let committer = unsafe { &*(committer_ptr as *const Committer) };
+    let chunk = unsafe { std::slice::from_raw_parts(chunk_start, chunk_len) };
+
+    match bincode::deserialize::<Message>(chunk) {
+        Ok(message) => match message.verify(committer) {
+            Ok(_) => 0,
+            Err(e) => error_code(e),
+        },
+        Err(_) => -1,
+    }
+}
+
 #[no_mangle]
 pub extern "C" fn is_full(node_ptr: *const std::ffi::c_void) -> i32 {
     let node = unsafe { &*(node_ptr as *const Node) };
@@ -125,24 +540,123 @@ pub extern "C" fn is_full(node_ptr: *const std::ffi::c_void) -> i32 {
 }
 
 #[no_mangle]
-pub extern "C" fn decode(
+pub extern "C" fn rank(node_ptr: *const std::ffi::c_void) -> u32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    node.rank() as u32
+}
+
+// memory_usage returns the bytes of heap memory the node holds.
+#[no_mangle]
+pub extern "C" fn memory_usage(node_ptr: *const std::ffi::c_void) -> u64 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    node.memory_usage() as u64
+}
+
+// coefficients returns the coefficient vectors of the chunks the node holds,
+// one row after the other, as 32 byte scalars.
+#[no_mangle]
+pub extern "C" fn coefficients(
     node_ptr: *const std::ffi::c_void,
     out_data: *mut *mut u8,
     out_len: *mut usize,
 ) -> i32 {
     let node = unsafe { &*(node_ptr as *const Node) };
-    if !node.is_full() {
-        return -1;
+    let data: Vec<u8> = node
+        .coefficients()
+        .iter()
+        .flatten()
+        .flat_map(|x| x.to_bytes())
+        .collect();
+    unsafe {
+        *out_len = data.len();
+        *out_data = Box::into_raw(data.into_boxed_slice()) as *mut u8;
     }
+    0
+}
 
-    if let Ok(data) = node.decode() {
+// decoded_chunks returns the original chunks the node can already recover,
+// before it is full, as a u32 count, that many u32 indices and then the
+// chunks themselves, which are all the same size. Integers are little-endian.
+#[no_mangle]
+pub extern "C" fn decoded_chunks(
+    node_ptr: *const std::ffi::c_void,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if let Ok(chunks) = node.decoded_chunks() {
+        let mut data = (chunks.len() as u32).to_le_bytes().to_vec();
+        for (i, _) in &chunks {
+            data.extend_from_slice(&(*i as u32).to_le_bytes());
+        }
+        for (_, chunk) in &chunks {
+            data.extend_from_slice(chunk);
+        }
         unsafe {
             *out_len = data.len();
             *out_data = Box::into_raw(data.into_boxed_slice()) as *mut u8;
         }
         return 0;
     }
-    return 0;
+    -1
+}
+
+// decode writes the decoded block to out_data. It returns -1 if the node
+// isn't full and -2 if decoding fails, leaving out_data and out_len unset.
+#[no_mangle]
+pub extern "C" fn decode(
+    node_ptr: *const std::ffi::c_void,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if !node.is_full() {
+        return -1;
+    }
+    let data = match node.decode() {
+        Ok(data) => data,
+        Err(_) => return -2,
+    };
+    unsafe {
+        *out_len = data.len();
+        *out_data = Box::into_raw(data.into_boxed_slice()) as *mut u8;
+    }
+    0
+}
+
+// decode_verified is decode, but checks the decoded data against the node's
+// commitments first. It returns decode's codes, and -3 if the data doesn't
+// match the commitments.
+#[no_mangle]
+pub extern "C" fn decode_verified(
+    node_ptr: *const std::ffi::c_void,
+    out_data: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    if !node.is_full() {
+        return -1;
+    }
+    let data = match node.decode() {
+        Ok(data) => data,
+        Err(_) => return -2,
+    };
+    if node.verify_decoded(&data).is_err() {
+        return -3;
+    }
+    unsafe {
+        *out_len = data.len();
+        *out_data = Box::into_raw(data.into_boxed_slice()) as *mut u8;
+    }
+    0
+}
+
+// alloc_buffer hands out a zeroed buffer for the caller to fill and release
+// with free_buffer. Hosts that can't pass their own memory in, like a WASM
+// runtime, use it to copy input buffers into the library's memory.
+#[no_mangle]
+pub extern "C" fn alloc_buffer(len: usize) -> *mut u8 {
+    Box::into_raw(vec![0u8; len].into_boxed_slice()) as *mut u8
 }
 
 #[no_mangle]
@@ -153,6 +667,28 @@ pub extern "C" fn free_buffer(ptr: *mut u8, len: usize) {
     }
 }
 
+// node_commitments_hash returns the hash of the commitments of the block the
+// node holds, or -1 if it hasn't received a chunk yet.
+#[no_mangle]
+pub extern "C" fn node_commitments_hash(
+    node_ptr: *const std::ffi::c_void,
+    out_ptr: *mut *mut u8,
+    out_len: *mut usize,
+) -> i32 {
+    let node = unsafe { &*(node_ptr as *const Node) };
+    match node.commitments_hash() {
+        Some(hash) => {
+            unsafe {
+                *out_len = hash.len();
+                *out_ptr =
+                    Box::into_raw(hash.to_vec().into_boxed_slice()) as *mut u8;
+            }
+            0
+        }
+        None => -1,
+    }
+}
+
 #[no_mangle]
 pub extern "C" fn commitments_hash(
     message_data: *const u8,
@@ -160,6 +696,9 @@ pub extern "C" fn commitments_hash(
     out_ptr: *mut *mut u8,
     out_len: *mut usize,
 ) -> i32 {
+    if message_data.is_null() {
+        return -1;
+    }
     let message_bytes =
         unsafe { std::slice::from_raw_parts(message_data, message_len) };
     match bincode::deserialize::<Message>(&message_bytes) {
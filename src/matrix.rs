@@ -1,4 +1,5 @@
 use curve25519_dalek::Scalar;
+use serde::{Deserialize, Serialize};
 /*
 Echelon is a structure that keeps both the echelon form of a matrix and the transoformations
 necessary to obtain these form. Self consistency
@@ -15,10 +16,15 @@ We could in principle work with smaller integer matrices like u64 instead of Sca
 needs to be taken to prevent the integers to grow with the number of rows. Implementing something
 like Bareiss' seems overkill at this stage.
 */
+#[derive(Clone, Serialize, Deserialize)]
 pub struct Echelon {
     coefficients: Vec<Vec<Scalar>>,
     echelon: Vec<Vec<Scalar>>,
     transform: Vec<Vec<Scalar>>,
+    // spare holds rows the matrices no longer use, which add_row fills in
+    // place rather than allocating new ones.
+    #[serde(skip)]
+    spare: Vec<Vec<Scalar>>,
 }
 
 impl Echelon {
@@ -30,6 +36,7 @@ impl Echelon {
             coefficients: Vec::new(),
             echelon: Vec::new(),
             transform,
+            spare: Vec::new(),
         }
     }
 
@@ -43,9 +50,45 @@ impl Echelon {
             coefficients,
             echelon,
             transform,
+            spare: Vec::new(),
         }
     }
 
+    // reset empties the matrices as if the echelon form was new, keeping
+    // the echelon rows as spares for add_row to fill. The coefficient rows
+    // are dropped, as add_row takes them over from the chunks received.
+    pub fn reset(&mut self) {
+        self.coefficients.clear();
+        self.spare.extend(self.echelon.drain(..));
+        for (i, row) in self.transform.iter_mut().enumerate() {
+            row.fill(Scalar::ZERO);
+            row[i] = Scalar::ONE;
+        }
+    }
+
+    // size returns the number of columns, that is the number of chunks.
+    pub fn size(&self) -> usize {
+        self.transform.len()
+    }
+
+    // pivots returns the column of the leading entry of each row of the
+    // echelon form, in increasing order. A vector that is zero in all of them
+    // is not in the row space.
+    pub fn pivots(&self) -> Vec<usize> {
+        self.echelon
+            .iter()
+            .filter_map(|row| first_entry(row))
+            .collect()
+    }
+
+    // memory_usage returns the bytes of heap memory held by the matrices.
+    pub fn memory_usage(&self) -> usize {
+        matrix_memory_usage(&self.coefficients)
+            + matrix_memory_usage(&self.echelon)
+            + matrix_memory_usage(&self.transform)
+            + matrix_memory_usage(&self.spare)
+    }
+
     // is_full returns if the echelon form is square.
     pub fn is_full(&self) -> bool {
         if self.coefficients.len() == 0 {
@@ -54,6 +97,36 @@ impl Echelon {
         self.coefficients.len() == self.coefficients[0].len()
     }
 
+    pub fn coefficients(&self) -> &Vec<Vec<Scalar>> {
+        &self.coefficients
+    }
+
+    // check_shape returns an error unless the matrices have the dimensions
+    // they would have for size columns, like after deserializing.
+    pub fn check_shape(&self, size: usize) -> Result<(), String> {
+        let rows = self.coefficients.len();
+        if rows > size
+            || self.echelon.len() != rows
+            || self.transform.len() != size
+        {
+            return Err(
+                "The echelon form has the wrong number of rows".to_string()
+            );
+        }
+        let square = self
+            .coefficients
+            .iter()
+            .chain(&self.echelon)
+            .chain(&self.transform)
+            .all(|row| row.len() == size);
+        if !square {
+            return Err(
+                "The echelon form has the wrong number of columns".to_string()
+            );
+        }
+        Ok(())
+    }
+
     // add_row adds a row to the coefficients matrix and updates the echelon form and the transform.
     // It returns false if the row is linearly dependent with the previous ones.
     pub fn add_row(&mut self, row: Vec<Scalar>) -> bool {
@@ -65,21 +138,23 @@ impl Echelon {
             return false;
         }
         if current_size == 0 {
-            self.echelon
-                .push(row.iter().map(|x| Scalar::from(*x)).collect());
+            let new_echelon_row = spare_row(&mut self.spare, &row);
+            self.echelon.push(new_echelon_row);
             self.coefficients.push(row);
             return true;
         }
-        let mut tr = self.transform[current_size].clone();
+        let mut tr = spare_row(&mut self.spare, &self.transform[current_size]);
         let mut i = 0;
         let mut j: usize;
-        let mut new_echelon_row: Vec<Scalar> =
-            row.iter().map(|x| Scalar::from(*x)).collect();
+        let mut new_echelon_row = spare_row(&mut self.spare, &row);
         while i < current_size {
             j = first_entry(&self.echelon[i]).unwrap();
             let k = match first_entry(&new_echelon_row) {
                 Some(val) => val,
-                None => return false,
+                None => {
+                    self.spare.extend([new_echelon_row, tr]);
+                    return false;
+                }
             };
             if j < k {
                 i += 1;
@@ -100,16 +175,19 @@ impl Echelon {
             i += 1;
         }
         if new_echelon_row.iter().all(|x| *x == Scalar::ZERO) {
+            self.spare.extend([new_echelon_row, tr]);
             return false;
         }
         self.echelon.insert(i, new_echelon_row);
         self.coefficients.push(row);
         if i < current_size {
-            self.transform.remove(current_size);
+            let old = self.transform.remove(current_size);
+            self.spare.push(old);
             self.transform.insert(i, tr);
             return true;
         }
-        self.transform[i] = tr;
+        let old = std::mem::replace(&mut self.transform[i], tr);
+        self.spare.push(old);
         return true;
     }
 
@@ -151,6 +229,29 @@ impl Echelon {
     }
 }
 
+// matrix_memory_usage returns the bytes of heap memory held by the rows of a
+// matrix and the vector holding them.
+pub fn matrix_memory_usage(rows: &Vec<Vec<Scalar>>) -> usize {
+    rows.capacity() * std::mem::size_of::<Vec<Scalar>>()
+        + rows
+            .iter()
+            .map(|row| row.capacity() * std::mem::size_of::<Scalar>())
+            .sum::<usize>()
+}
+
+// spare_row returns a copy of from in one of the spare rows, or in a new row
+// if there are none left.
+pub fn spare_row(spare: &mut Vec<Vec<Scalar>>, from: &[Scalar]) -> Vec<Scalar> {
+    match spare.pop() {
+        Some(mut row) => {
+            row.clear();
+            row.extend_from_slice(from);
+            row
+        }
+        None => from.to_vec(),
+    }
+}
+
 fn first_entry<T: PartialEq + Default>(slice: &[T]) -> Option<usize> {
     let zero = T::default();
     slice.iter().position(|x| x != &zero)
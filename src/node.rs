@@ -1,13 +1,15 @@
 use crate::blocks::{
     block_to_chunks, chunk_to_scalars, scalars_to_chunk, Committer,
 };
-use crate::matrix::Echelon;
+use crate::matrix::{matrix_memory_usage, Echelon};
 use curve25519_dalek::ristretto::RistrettoPoint;
 use curve25519_dalek::traits::MultiscalarMul;
 use curve25519_dalek::Scalar;
-use rand::Rng;
+use rand::rngs::StdRng;
+use rand::{Rng, SeedableRng};
 use serde::{Deserialize, Serialize};
 use sha2::{Digest, Sha256};
+use std::cell::RefCell;
 
 /*
 A Message represents a single chunk that is received by the node.
@@ -29,11 +31,14 @@ pub struct Chunk {
 A Node keeps chunks and the full commitments from the source. The Echelon object is used to keep
 track of the linear independence of the chunks.
 */
+#[derive(Clone)]
 pub struct Node<'a> {
     chunks: Vec<Vec<Scalar>>,
     commitments: Vec<RistrettoPoint>,
     echelon: Echelon,
     committer: &'a Committer,
+    // rng draws the coefficients of sent chunks once set_seed is called.
+    rng: RefCell<Option<StdRng>>,
 }
 
 #[derive(Debug)]
@@ -42,6 +47,10 @@ pub enum ReceiveError {
     ExistingChunksMismatch(String),
     InvalidMessage(String),
     LinearlyDependentChunk,
+    MissingCommitments,
+    // VerificationFailed is a chunk whose payload doesn't match the
+    // combination of its commitments, so it was modified on the way.
+    VerificationFailed,
 }
 
 impl Message {
@@ -53,14 +62,21 @@ impl Message {
         self.chunk.coefficients.to_vec()
     }
 
-    pub fn verify(&self, committer: &Committer) -> Result<(), String> {
+    pub fn verify(&self, committer: &Committer) -> Result<(), ReceiveError> {
+        if self.chunk.coefficients.len() != self.commitments.len() {
+            return Err(ReceiveError::InvalidMessage(
+                "The number of coefficients and commitments differ".to_string(),
+            ));
+        }
         let msm = RistrettoPoint::multiscalar_mul(
             self.coefficients_to_scalars(),
             &self.commitments,
         );
-        let commitment = committer.commit(&self.chunk.data)?;
+        let commitment = committer
+            .commit(&self.chunk.data)
+            .map_err(ReceiveError::InvalidMessage)?;
         if msm != commitment {
-            return Err("The commitment does not match".to_string());
+            return Err(ReceiveError::VerificationFailed);
         }
         Ok(())
     }
@@ -70,13 +86,30 @@ impl Message {
     }
 
     pub fn commitments_hash(&self) -> [u8; 32] {
-        let mut hasher = Sha256::new();
-        let serialized = bincode::serialize(&self.commitments).unwrap();
-        hasher.update(&serialized);
-        hasher.finalize().into()
+        hash_commitments(&self.commitments)
     }
 }
 
+// commit_block returns the commitments to the chunks of block split into
+// num_chunks chunks, the ones its source node sends.
+pub fn commit_block(
+    committer: &Committer,
+    block: &[u8],
+    num_chunks: usize,
+) -> Result<Vec<RistrettoPoint>, String> {
+    block_to_chunks(block, num_chunks)?
+        .into_iter()
+        .map(|data| committer.commit(&chunk_to_scalars(data)?))
+        .collect()
+}
+
+fn hash_commitments(commitments: &[RistrettoPoint]) -> [u8; 32] {
+    let mut hasher = Sha256::new();
+    let serialized = bincode::serialize(commitments).unwrap();
+    hasher.update(&serialized);
+    hasher.finalize().into()
+}
+
 impl<'a> Node<'a> {
     pub fn new(committer: &'a Committer, num_chunks: usize) -> Self {
         Node {
@@ -84,29 +117,67 @@ impl<'a> Node<'a> {
             commitments: Vec::new(),
             echelon: Echelon::new(num_chunks),
             committer,
+            rng: RefCell::new(None),
         }
     }
+
     pub fn new_source(
         committer: &'a Committer,
         block: &[u8],
         num_chunks: usize,
     ) -> Result<Self, String> {
-        let chunks: Vec<_> = block_to_chunks(block, num_chunks)?
+        let commitments = commit_block(committer, block, num_chunks)?;
+        Node::new_source_with_commitments(
+            committer,
+            block,
+            num_chunks,
+            commitments,
+        )
+    }
+
+    // new_source_with_commitments is new_source for a block whose commitments
+    // were already computed by commit_block. They aren't checked: chunks sent
+    // under the wrong commitments just fail to verify.
+    pub fn new_source_with_commitments(
+        committer: &'a Committer,
+        block: &[u8],
+        num_chunks: usize,
+        commitments: Vec<RistrettoPoint>,
+    ) -> Result<Self, String> {
+        if commitments.len() != num_chunks {
+            return Err("The number of commitments is different".to_string());
+        }
+        let chunks = block_to_chunks(block, num_chunks)?
             .into_iter()
-            .map(|data| chunk_to_scalars(data).unwrap())
-            .collect();
-        let commitments = chunks
-            .iter()
-            .map(|chunk| committer.commit(&chunk).unwrap())
-            .collect();
+            .map(chunk_to_scalars)
+            .collect::<Result<Vec<_>, _>>()?;
         Ok(Node {
             chunks,
             commitments,
             echelon: Echelon::new_identity(num_chunks),
             committer,
+            rng: RefCell::new(None),
         })
     }
 
+    // expect_commitments sets the commitments of the block the node will
+    // receive before it receives any chunk, so chunks of other blocks are
+    // rejected from the first one.
+    pub fn expect_commitments(
+        &mut self,
+        commitments: Vec<RistrettoPoint>,
+    ) -> Result<(), ReceiveError> {
+        if commitments.len() != self.echelon.size() {
+            return Err(ReceiveError::InvalidMessage(
+                "The number of commitments is different".to_string(),
+            ));
+        }
+        self.check_existing_commitments(&commitments)
+            .map_err(ReceiveError::ExistingCommitmentsMismatch)?;
+        self.commitments = commitments;
+        Ok(())
+    }
+
     fn check_existing_commitments(
         &self,
         commitments: &[RistrettoPoint],
@@ -141,9 +212,7 @@ impl<'a> Node<'a> {
         self.check_existing_chunks(&message.chunk)
             .map_err(ReceiveError::ExistingChunksMismatch)?;
 
-        message
-            .verify(&self.committer)
-            .map_err(ReceiveError::InvalidMessage)?;
+        message.verify(&self.committer)?;
 
         // Verify linear independence
         if !self.echelon.add_row(message.chunk.coefficients) {
@@ -157,11 +226,50 @@ impl<'a> Node<'a> {
         Ok(())
     }
 
+    // receive_source receives the original chunk i, as the node would receive
+    // it from send_systematic. It is checked against the node's commitments,
+    // or the given ones if it doesn't have any yet.
+    pub fn receive_source(
+        &mut self,
+        i: usize,
+        data: &[u8],
+        commitments: Option<Vec<RistrettoPoint>>,
+    ) -> Result<(), ReceiveError> {
+        let num_chunks = self.echelon.size();
+        if i >= num_chunks {
+            return Err(ReceiveError::InvalidMessage(
+                "Chunk index out of range".to_string(),
+            ));
+        }
+        let commitments = match commitments {
+            Some(commitments) => commitments,
+            None if !self.commitments.is_empty() => self.commitments.clone(),
+            None => return Err(ReceiveError::MissingCommitments),
+        };
+        let data =
+            chunk_to_scalars(data).map_err(ReceiveError::InvalidMessage)?;
+        let mut coefficients = vec![Scalar::ZERO; num_chunks];
+        coefficients[i] = Scalar::ONE;
+        self.receive(Message::new(Chunk { data, coefficients }, commitments))
+    }
+
     pub fn send(&self) -> Result<Message, String> {
         if self.chunks.is_empty() {
             return Err("There are no chunks to send".to_string());
         }
-        let scalars = generate_random_coeffs(self.chunks.len());
+        // All zero coefficients give the zero chunk, which carries nothing.
+        // With a single chunk that is one chunk in 256, so draw again.
+        let scalars = loop {
+            let scalars: Vec<u8> = match self.rng.borrow_mut().as_mut() {
+                Some(rng) => {
+                    (0..self.chunks.len()).map(|_| rng.gen()).collect()
+                }
+                None => generate_random_coeffs(self.chunks.len()),
+            };
+            if scalars.iter().any(|&x| x != 0) {
+                break scalars;
+            }
+        };
         let chunk = self.linear_comb_chunk(&scalars);
 
         let message = Message::new(chunk, self.commitments.clone());
@@ -169,6 +277,98 @@ impl<'a> Node<'a> {
         Ok(message)
     }
 
+    // send_systematic returns the i-th original chunk, with the i-th unit
+    // vector as its coefficients. Only a source node holds the original chunks.
+    pub fn send_systematic(&self, i: usize) -> Result<Message, String> {
+        if i >= self.chunks.len() {
+            return Err("Chunk index out of range".to_string());
+        }
+        let mut scalars = vec![0u8; self.chunks.len()];
+        scalars[i] = 1;
+        let chunk = self.linear_comb_chunk(&scalars);
+        let is_unit = chunk.coefficients.iter().enumerate().all(|(j, x)| {
+            *x == if i == j { Scalar::ONE } else { Scalar::ZERO }
+        });
+        if !is_unit {
+            return Err(
+                "The node does not hold the original chunks".to_string()
+            );
+        }
+
+        let message = Message::new(chunk, self.commitments.clone());
+        debug_assert!(message.verify(&self.committer).is_ok());
+        Ok(message)
+    }
+
+    // send_for_needs returns a random combination of the original chunks at
+    // the columns that are not pivots of a receiver's echelon form. It is
+    // zero on every pivot, so it is innovative for that receiver. Only a
+    // source node holds the original chunks.
+    pub fn send_for_needs(&self, pivots: &[bool]) -> Result<Message, String> {
+        let num_chunks = self.commitments.len();
+        if pivots.len() != num_chunks {
+            return Err("The pivots are for another number of chunks".into());
+        }
+        if !self.holds_original_chunks() {
+            return Err(
+                "The node does not hold the original chunks".to_string()
+            );
+        }
+        if pivots.iter().all(|&p| p) {
+            return Err("The receiver needs no more chunks".to_string());
+        }
+        let mut rng = self.rng.borrow_mut();
+        let mut draw = || -> Scalar {
+            let x = match rng.as_mut() {
+                Some(rng) => rng.gen_range(1..=u64::MAX),
+                None => rand::thread_rng().gen_range(1..=u64::MAX),
+            };
+            Scalar::from(x)
+        };
+        let coefficients: Vec<Scalar> = pivots
+            .iter()
+            .map(|&p| if p { Scalar::ZERO } else { draw() })
+            .collect();
+        let data = (0..self.chunks[0].len())
+            .map(|k| {
+                coefficients
+                    .iter()
+                    .zip(&self.chunks)
+                    .map(|(x, chunk)| x * chunk[k])
+                    .sum()
+            })
+            .collect();
+
+        let message = Message::new(
+            Chunk { data, coefficients },
+            self.commitments.clone(),
+        );
+        debug_assert!(message.verify(&self.committer).is_ok());
+        Ok(message)
+    }
+
+    // holds_original_chunks returns if the node's chunks are the original
+    // ones, in order, as in a source node.
+    fn holds_original_chunks(&self) -> bool {
+        self.echelon.is_full()
+            && self.coefficients().iter().enumerate().all(|(i, row)| {
+                row.iter().enumerate().all(|(j, x)| {
+                    *x == if i == j { Scalar::ONE } else { Scalar::ZERO }
+                })
+            })
+    }
+
+    // pivots returns, for each column of the node's echelon form, whether it
+    // is a pivot.
+    pub fn pivots(&self) -> Vec<bool> {
+        let mut pivots = vec![false; self.echelon.size()];
+        self.echelon
+            .pivots()
+            .into_iter()
+            .for_each(|j| pivots[j] = true);
+        pivots
+    }
+
     fn linear_comb_chunk(&self, scalars: &[u8]) -> Chunk {
         let coefficients = self.echelon.compound_scalars(scalars);
         let data = self.linear_comb_data(scalars);
@@ -211,6 +411,110 @@ impl<'a> Node<'a> {
         Ok(ret)
     }
 
+    // verify_decoded checks that data, as returned by decode, matches the
+    // commitments the node holds, chunk by chunk.
+    pub fn verify_decoded(&self, data: &[u8]) -> Result<(), String> {
+        let chunks = block_to_chunks(data, self.commitments.len())?;
+        for (chunk, commitment) in chunks.into_iter().zip(&self.commitments) {
+            let scalars = chunk_to_scalars(chunk)?;
+            if self.committer.commit(&scalars)? != *commitment {
+                return Err("The decoded data does not match the commitments"
+                    .to_string());
+            }
+        }
+        Ok(())
+    }
+
+    // decoded_chunks returns the original chunks, with their indices, that
+    // the chunks received already determine, even before the node is full.
+    // Chunk i is determined when the i-th unit vector is in the span of the
+    // coefficients, that is when it is a row of their reduced echelon form.
+    pub fn decoded_chunks(&self) -> Result<Vec<(usize, Vec<u8>)>, String> {
+        let mut coefficients = self.echelon.coefficients().clone();
+        let mut data = self.chunks.clone();
+        let mut pivots = Vec::new();
+        let num_chunks = coefficients.first().map_or(0, |row| row.len());
+        for col in 0..num_chunks {
+            let row = pivots.len();
+            let Some(p) = (row..coefficients.len())
+                .find(|&i| coefficients[i][col] != Scalar::ZERO)
+            else {
+                continue;
+            };
+            coefficients.swap(row, p);
+            data.swap(row, p);
+            let inv = coefficients[row][col].invert();
+            coefficients[row].iter_mut().for_each(|x| *x *= inv);
+            data[row].iter_mut().for_each(|x| *x *= inv);
+            let (pivot_coefficients, pivot_data) =
+                (coefficients[row].clone(), data[row].clone());
+            for i in 0..coefficients.len() {
+                let f = coefficients[i][col];
+                if i == row || f == Scalar::ZERO {
+                    continue;
+                }
+                coefficients[i]
+                    .iter_mut()
+                    .zip(&pivot_coefficients)
+                    .for_each(|(x, y)| *x -= f * y);
+                data[i]
+                    .iter_mut()
+                    .zip(&pivot_data)
+                    .for_each(|(x, y)| *x -= f * y);
+            }
+            pivots.push(col);
+            if pivots.len() == coefficients.len() {
+                break;
+            }
+        }
+        pivots
+            .into_iter()
+            .enumerate()
+            .filter(|&(row, col)| {
+                coefficients[row]
+                    .iter()
+                    .enumerate()
+                    .all(|(j, x)| j == col || *x == Scalar::ZERO)
+            })
+            .map(|(row, col)| Ok((col, scalars_to_chunk(&data[row])?)))
+            .collect()
+    }
+
+    // snapshot serializes everything the node has received, so it can be
+    // restored with the same committer.
+    pub fn snapshot(&self) -> Result<Vec<u8>, String> {
+        bincode::serialize(&(&self.chunks, &self.commitments, &self.echelon))
+            .map_err(|e| e.to_string())
+    }
+
+    pub fn restore(
+        committer: &'a Committer,
+        num_chunks: usize,
+        snapshot: &[u8],
+    ) -> Result<Self, String> {
+        let (chunks, commitments, echelon): (
+            Vec<Vec<Scalar>>,
+            Vec<RistrettoPoint>,
+            Echelon,
+        ) = bincode::deserialize(snapshot).map_err(|e| e.to_string())?;
+        echelon.check_shape(num_chunks)?;
+        if chunks.len() != echelon.coefficients().len()
+            || chunks.iter().any(|chunk| chunk.len() != chunks[0].len())
+        {
+            return Err("The chunks do not match the coefficients".to_string());
+        }
+        if !commitments.is_empty() && commitments.len() != num_chunks {
+            return Err("The number of commitments is different".to_string());
+        }
+        Ok(Node {
+            chunks,
+            commitments,
+            echelon,
+            committer,
+            rng: RefCell::new(None),
+        })
+    }
+
     pub fn chunks(&self) -> &Vec<Vec<Scalar>> {
         &self.chunks
     }
@@ -219,9 +523,53 @@ impl<'a> Node<'a> {
         &self.commitments
     }
 
+    pub fn coefficients(&self) -> &Vec<Vec<Scalar>> {
+        self.echelon.coefficients()
+    }
+
     pub fn is_full(&self) -> bool {
         self.echelon.is_full()
     }
+
+    // reset drops everything the node received, leaving it like a new node
+    // for the same number of chunks, but keeping its allocations. The chunk
+    // rows are dropped, as receive takes them over from the messages.
+    pub fn reset(&mut self) {
+        self.chunks.clear();
+        self.commitments.clear();
+        self.echelon.reset();
+        *self.rng.borrow_mut() = None;
+    }
+
+    // set_seed makes the coefficients of the chunks sent from now on a
+    // function of seed, for reproducible tests.
+    pub fn set_seed(&self, seed: u64) {
+        *self.rng.borrow_mut() = Some(StdRng::seed_from_u64(seed));
+    }
+
+    // commitments_hash returns the hash of the commitments of the block the
+    // node holds, the same as the commitments_hash of its messages, or None
+    // before it has received any.
+    pub fn commitments_hash(&self) -> Option<[u8; 32]> {
+        if self.commitments.is_empty() {
+            return None;
+        }
+        Some(hash_commitments(&self.commitments))
+    }
+
+    // rank returns the number of linearly independent chunks held.
+    pub fn rank(&self) -> usize {
+        self.chunks.len()
+    }
+
+    // memory_usage returns the bytes of heap memory the node holds, counting
+    // the capacity of its vectors.
+    pub fn memory_usage(&self) -> usize {
+        matrix_memory_usage(&self.chunks)
+            + self.commitments.capacity()
+                * std::mem::size_of::<RistrettoPoint>()
+            + self.echelon.memory_usage()
+    }
 }
 
 fn generate_random_coeffs(length: usize) -> Vec<u8> {
@@ -248,6 +596,275 @@ mod tests {
         assert_eq!(source_node.commitments().len(), num_chunks);
     }
 
+    #[test]
+    fn test_send_systematic() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let mut destination_node = Node::new(&committer, num_chunks);
+        for i in 0..num_chunks {
+            let message = source_node.send_systematic(i).unwrap();
+            destination_node.receive(message).unwrap();
+        }
+        assert!(destination_node.is_full());
+        assert_eq!(destination_node.decode().unwrap(), block);
+        assert!(source_node.send_systematic(num_chunks).is_err());
+
+        let mut relay_node = Node::new(&committer, num_chunks);
+        relay_node.receive(source_node.send().unwrap()).unwrap();
+        assert!(relay_node.send_systematic(0).is_err());
+    }
+
+    #[test]
+    fn test_snapshot_restore() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let mut node = Node::new(&committer, num_chunks);
+        node.receive(source_node.send().unwrap()).unwrap();
+
+        let snapshot = node.snapshot().unwrap();
+        let mut restored =
+            Node::restore(&committer, num_chunks, &snapshot).unwrap();
+        while !restored.is_full() {
+            let _ = restored.receive(source_node.send().unwrap());
+        }
+        assert_eq!(restored.decode().unwrap(), block);
+
+        assert!(Node::restore(&committer, num_chunks + 1, &snapshot).is_err());
+        assert!(Node::restore(&committer, num_chunks, &snapshot[1..]).is_err());
+    }
+
+    #[test]
+    fn test_set_seed() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let a = Node::new_source(&committer, &block, num_chunks).unwrap();
+        let b = Node::new_source(&committer, &block, num_chunks).unwrap();
+        a.set_seed(42);
+        b.set_seed(42);
+        for _ in 0..4 {
+            assert_eq!(
+                a.send().unwrap().coefficients(),
+                b.send().unwrap().coefficients()
+            );
+        }
+    }
+
+    #[test]
+    fn test_decoded_chunks() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let chunk = |i: usize| block[i * 96..(i + 1) * 96].to_vec();
+        assert_eq!(
+            source_node.decoded_chunks().unwrap(),
+            (0..num_chunks).map(|i| (i, chunk(i))).collect::<Vec<_>>()
+        );
+
+        let mut node = Node::new(&committer, num_chunks);
+        assert!(node.decoded_chunks().unwrap().is_empty());
+        node.receive(source_node.send_systematic(1).unwrap())
+            .unwrap();
+        assert_eq!(node.decoded_chunks().unwrap(), vec![(1, chunk(1))]);
+        while node.rank() < 2 {
+            let _ = node.receive(source_node.send().unwrap());
+        }
+        let decoded = node.decoded_chunks().unwrap();
+        assert!(decoded.contains(&(1, chunk(1))));
+        assert!(decoded.iter().all(|(i, data)| *data == chunk(*i)));
+    }
+
+    #[test]
+    fn test_commitments_hash() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let message = source_node.send().unwrap();
+        let hash = message.commitments_hash();
+        assert_eq!(source_node.commitments_hash(), Some(hash));
+
+        let mut node = Node::new(&committer, num_chunks);
+        assert_eq!(node.commitments_hash(), None);
+        node.receive(message).unwrap();
+        assert_eq!(node.commitments_hash(), Some(hash));
+    }
+
+    #[test]
+    fn test_clone() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let mut node = Node::new(&committer, num_chunks);
+        node.receive(source_node.send().unwrap()).unwrap();
+
+        let clone = node.clone();
+        while !node.is_full() {
+            let _ = node.receive(source_node.send().unwrap());
+        }
+        assert_eq!(clone.rank(), 1);
+        assert_eq!(node.decode().unwrap(), block);
+    }
+
+    #[test]
+    fn test_receive_source() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let chunk = |i: usize| &block[i * 3 * 32..(i + 1) * 3 * 32];
+
+        let mut node = Node::new(&committer, num_chunks);
+        assert!(matches!(
+            node.receive_source(0, chunk(0), None),
+            Err(ReceiveError::MissingCommitments)
+        ));
+        let commitments = source_node.commitments().clone();
+        node.receive_source(0, chunk(0), Some(commitments)).unwrap();
+        assert!(matches!(
+            node.receive_source(1, chunk(2), None),
+            Err(ReceiveError::VerificationFailed)
+        ));
+        assert!(node.receive_source(num_chunks, chunk(0), None).is_err());
+        node.receive_source(1, chunk(1), None).unwrap();
+        while !node.is_full() {
+            let _ = node.receive(source_node.send().unwrap());
+        }
+        assert_eq!(node.decode().unwrap(), block);
+    }
+
+    #[test]
+    fn test_reset() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let mut node = Node::new(&committer, num_chunks);
+        let mut full_usage = 0;
+        for i in 0..3 {
+            let block = random_u8_slice(num_chunks * 3 * 32);
+            let source_node =
+                Node::new_source(&committer, &block, num_chunks).unwrap();
+            while !node.is_full() {
+                let _ = node.receive(source_node.send().unwrap());
+            }
+            assert_eq!(node.decode().unwrap(), block);
+            // The rows kept by reset are reused rather than piling up.
+            if i > 0 {
+                assert_eq!(node.memory_usage(), full_usage);
+            }
+            full_usage = node.memory_usage();
+            node.reset();
+            assert!(!node.is_full());
+            assert_eq!(node.rank(), 0);
+            assert!(node.commitments_hash().is_none());
+        }
+    }
+
+    #[test]
+    fn test_verify_decoded() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let mut node = Node::new(&committer, num_chunks);
+        while !node.is_full() {
+            let _ = node.receive(source_node.send().unwrap());
+        }
+        let data = node.decode().unwrap();
+        assert!(node.verify_decoded(&data).is_ok());
+
+        node.chunks[1][0] += curve25519_dalek::Scalar::ONE;
+        let data = node.decode().unwrap();
+        assert_ne!(data, block);
+        assert!(node.verify_decoded(&data).is_err());
+    }
+
+    #[test]
+    fn test_expect_commitments() {
+        let num_chunks = 3;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let commitments =
+            super::commit_block(&committer, &block, num_chunks).unwrap();
+        let source_node = Node::new_source_with_commitments(
+            &committer,
+            &block,
+            num_chunks,
+            commitments.clone(),
+        )
+        .unwrap();
+        assert_eq!(source_node.commitments(), &commitments);
+
+        let impostor_block = random_u8_slice(num_chunks * 3 * 32);
+        let impostor =
+            Node::new_source(&committer, &impostor_block, num_chunks).unwrap();
+        let mut node = Node::new(&committer, num_chunks);
+        node.expect_commitments(commitments.clone()).unwrap();
+        assert!(matches!(
+            node.receive(impostor.send().unwrap()),
+            Err(ReceiveError::ExistingCommitmentsMismatch(_))
+        ));
+        assert!(node
+            .expect_commitments(impostor.commitments().clone())
+            .is_err());
+        while !node.is_full() {
+            let _ = node.receive(source_node.send().unwrap());
+        }
+        assert_eq!(node.decode().unwrap(), block);
+    }
+
+    #[test]
+    fn test_single_chunk() {
+        let committer = Committer::new(4);
+        let block = random_u8_slice(3 * 32);
+        let source_node = Node::new_source(&committer, &block, 1).unwrap();
+        source_node.set_seed(1);
+        // Every chunk of a single chunk block fills a node, so none may be
+        // the zero chunk.
+        for _ in 0..1000 {
+            let mut node = Node::new(&committer, 1);
+            node.receive(source_node.send().unwrap()).unwrap();
+            assert!(node.is_full());
+            assert_eq!(node.decode().unwrap(), block);
+        }
+    }
+
+    #[test]
+    fn test_send_for_needs() {
+        let num_chunks = 6;
+        let committer = Committer::new(4);
+        let block = random_u8_slice(num_chunks * 3 * 32);
+        let source_node =
+            Node::new_source(&committer, &block, num_chunks).unwrap();
+        let mut node = Node::new(&committer, num_chunks);
+        for _ in 0..2 {
+            node.receive(source_node.send().unwrap()).unwrap();
+        }
+        assert_eq!(node.pivots().iter().filter(|&&p| p).count(), 2);
+        assert!(node.send_for_needs(&node.pivots()).is_err());
+
+        let pivots = node.pivots();
+        for _ in 2..num_chunks {
+            node.receive(source_node.send_for_needs(&pivots).unwrap())
+                .unwrap();
+        }
+        assert!(node.is_full());
+        assert!(source_node.send_for_needs(&node.pivots()).is_err());
+        assert_eq!(node.decode().unwrap(), block);
+    }
+
     #[macro_export]
     macro_rules! measure_time {
         ($prefix:expr, $expr:expr) => {{